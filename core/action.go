@@ -22,6 +22,8 @@ type Action struct {
 	// EventTypes triggers events only from specific event type
 	// Use either trigger_entities OR trigger_events
 	EventTypes []string `mapstructure:"trigger_events"`
+	// DispatchOptions debounce, throttle or delay events before calling Trigger
+	DispatchOptions `mapstructure:",squash"`
 }
 
 // GetEntitiesForTrigger godoc
@@ -34,6 +36,11 @@ func (a *Action) GetEventTypesForTrigger() []string {
 	return a.EventTypes
 }
 
+// GetDispatchOptions godoc
+func (a *Action) GetDispatchOptions() DispatchOptions {
+	return a.DispatchOptions
+}
+
 // Trigger godoc
 func (a *Action) Trigger(e *model.HassEvent) {
 	l := logging.NewLogger("Action.Trigger")
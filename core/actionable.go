@@ -10,9 +10,17 @@ type Actionable interface {
 	Automate
 	GetEntitiesForTrigger() []model.HassEntity
 	GetEventTypesForTrigger() []string
+	// GetDispatchOptions returns how events are dispatched to Trigger
+	GetDispatchOptions() DispatchOptions
 	Trigger(e *model.HassEvent)
 	GinHandler(c *gin.Context)
 	// NeedsInitialization specifies if this Actionable needs to be triggered with a dummy event
 	// when program starts (or conf is reloaded)
 	NeedsInitialization() bool
 }
+
+// Releasable is implemented by Actionable objects holding resources
+// which have to be released when they are removed from the configuration
+type Releasable interface {
+	Release()
+}
@@ -12,12 +12,18 @@ type Automate interface {
 	Enable()
 	Disable()
 	GetName() string
+	// Bind gives the dependencies to use, it has to be called before starting
+	// and can be called again when the configuration is reloaded
+	Bind(runtime *Runtime)
+	GetRuntime() *Runtime
 }
 
 type automate struct {
 	Name          string `mapstructure:"name"`
 	Disabled      bool   `mapstructure:"disabled"`
 	mutexDisabled sync.Mutex
+	runtime       *Runtime
+	mutexRuntime  sync.RWMutex
 }
 
 // IsDisabled godoc
@@ -48,3 +54,20 @@ func (a *automate) Enable() {
 func (a *automate) GetName() string {
 	return a.Name
 }
+
+// Bind godoc
+func (a *automate) Bind(runtime *Runtime) {
+	a.mutexRuntime.Lock()
+	defer a.mutexRuntime.Unlock()
+	a.runtime = runtime
+}
+
+// GetRuntime returns the bound Runtime, an empty one if Bind has not been called
+func (a *automate) GetRuntime() *Runtime {
+	a.mutexRuntime.RLock()
+	defer a.mutexRuntime.RUnlock()
+	if a.runtime == nil {
+		return &Runtime{}
+	}
+	return a.runtime
+}
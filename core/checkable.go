@@ -7,4 +7,6 @@ type Checkable interface {
 	Configurable
 	routines.Runnable
 	GetModular() Modular
+	// CheckNow checks right away without waiting for the next interval
+	CheckNow()
 }
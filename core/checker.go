@@ -22,7 +22,9 @@ var (
 
 // Checker checks a Modular at a regular interval
 type Checker struct {
-	stop   chan bool
+	stop chan bool
+	// now asks for a check before the next interval
+	now    chan struct{}
 	Module Modular
 
 	started        bool
@@ -42,6 +44,7 @@ func (c *Checker) Start() error {
 	}
 
 	c.stop = make(chan bool, 1)
+	c.now = make(chan struct{}, 1)
 
 	app.RoutinesWG.Add(1)
 	go func() {
@@ -69,6 +72,11 @@ func (c *Checker) Start() error {
 			select {
 			case <-c.stop:
 				return
+			case <-c.now:
+				if c.Module.IsEnabled() {
+					l.Trace().Msgf("Checker %s is asked to check now", c.Module.GetName())
+					c.Module.Check()
+				}
 			case <-ticker.C:
 				if c.Module.IsEnabled() {
 					l.Trace().Msgf("Checker %s is enabled, calling Check()", c.Module.GetName())
@@ -96,6 +104,20 @@ func (c *Checker) Stop() {
 	c.started = false
 }
 
+// CheckNow asks for a check right away, nothing is done if a check is already pending or if not started
+func (c *Checker) CheckNow() {
+	c.mutexStopStart.Lock()
+	defer c.mutexStopStart.Unlock()
+	if !c.started {
+		return
+	}
+
+	select {
+	case c.now <- struct{}{}:
+	default:
+	}
+}
+
 // IsStarted checks whether or not the routine is already started
 func (c *Checker) IsStarted() bool {
 	c.mutexStopStart.Lock()
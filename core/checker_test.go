@@ -0,0 +1,52 @@
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingModule counts its checks
+type countingModule struct {
+	Module
+	checks atomic.Int32
+}
+
+func (m *countingModule) Check() {
+	m.checks.Add(1)
+}
+
+func TestChecker_CheckNow(t *testing.T) {
+	module := &countingModule{Module: Module{Interval: time.Hour}}
+	c := &Checker{Module: module}
+
+	// Not started, nothing is checked
+	c.CheckNow()
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(c.Stop)
+
+	waitChecks := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for module.checks.Load() < want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if got := module.checks.Load(); got != want {
+			t.Fatalf("checks = %d, want %d", got, want)
+		}
+	}
+
+	// Checked right away when started
+	waitChecks(1)
+
+	c.CheckNow()
+	waitChecks(2)
+
+	module.Disable()
+	c.CheckNow()
+	waitChecks(2)
+}
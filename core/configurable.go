@@ -4,3 +4,8 @@ package core
 type Configurable interface {
 	Configure(config interface{}, obj interface{}) error
 }
+
+// Validatable is implemented by objects able to check their configuration further than decoding it
+type Validatable interface {
+	Validate() []error
+}
@@ -2,11 +2,9 @@ package core
 
 import (
 	"fmt"
-	"github.com/nmaupu/gotomation/model"
 	"sync"
 	"time"
 
-	"github.com/kelvins/sunrisesunset"
 	"github.com/nmaupu/gotomation/app"
 	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
@@ -15,26 +13,37 @@ import (
 )
 
 var (
-	coords  coordinates
-	once    sync.Once
-	onceErr error
+	// coords are the home's coordinates, nil if not known
+	coords      Coordinates
+	coordsMutex sync.RWMutex
 )
 
 // Coordinates represents GPS coordinates using latitude and longitude
 type Coordinates interface {
 	routines.Runnable
 	GetSunriseSunset() (time.Time, time.Time, error)
-	IsDarkNow(offsetDawn, offsetDusk time.Duration) bool
+	// GetSunTimes returns when the sun crosses elevation (in degrees) in the morning and in the evening of date's day
+	GetSunTimes(date time.Time, elevation float64) (time.Time, time.Time, error)
+	// GetSunInfo returns solar noon, sunrise, sunset and twilights of date's day
+	GetSunInfo(date time.Time) SunInfo
+	// GetSunPosition returns the sun's elevation and azimuth in degrees at t
+	GetSunPosition(t time.Time) (float64, float64)
+	// IsDark returns true if the sun is below elevation (in degrees) now
+	IsDark(elevation float64) bool
 	GetLatitude() float64
 	GetLongitude() float64
+	// GetLocation returns the time zone days are computed in
+	GetLocation() *time.Location
 }
 
 // Coordinates represents GPS coordinates using latitude and longitude
 type coordinates struct {
 	Latitude  float64
 	Longitude float64
+	Timezone  string
+	location  *time.Location
 
-	// Store previous sunrise/sunset values because it takes 10s on raspberry to compute...
+	// Store previous sunrise/sunset values, they are logged when refreshed
 	sunrise    time.Time
 	sunset     time.Time
 	lastUpdate time.Time
@@ -46,30 +55,60 @@ type coordinates struct {
 	mutexStopStart sync.Mutex
 }
 
-// InitCoordinates gets the latitude and longitude of a Home Assistant zone entity
-func InitCoordinates(zoneName string) error {
-	once.Do(
-		func() {
-			var entity model.HassEntity
-			entity, onceErr = httpclient.GetSimpleClient().GetEntity("zone", zoneName)
-			if onceErr != nil {
-				onceErr = errors.Wrapf(onceErr, "Unable to get latitude and longitude")
-			} else {
-				coords = coordinates{
-					Latitude:          entity.State.Attributes["latitude"].(float64),
-					Longitude:         entity.State.Attributes["longitude"].(float64),
-					mutex:             &sync.Mutex{},
-					sunriseSunsetDone: make(chan bool, 1),
-				}
-			}
-		})
+// ZoneCoordinates gets the latitude and longitude of a Home Assistant zone entity using client
+func ZoneCoordinates(client httpclient.SimpleClient, zoneName string) (Coordinates, error) {
+	if client == nil {
+		return nil, fmt.Errorf("Home Assistant client is not available")
+	}
+	entity, err := client.GetEntity("zone", zoneName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get latitude and longitude")
+	}
 
-	return onceErr
+	latitude, ok := entity.State.Attributes["latitude"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("zone %s has no latitude", zoneName)
+	}
+	longitude, ok := entity.State.Attributes["longitude"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("zone %s has no longitude", zoneName)
+	}
+	return newCoordinates(latitude, longitude, time.Local), nil
+}
+
+// NewCoordinates returns Coordinates for the given latitude and longitude in the local time zone
+func NewCoordinates(latitude, longitude float64) Coordinates {
+	return newCoordinates(latitude, longitude, time.Local)
+}
+
+// NewCoordinatesInLocation returns Coordinates for the given latitude and longitude, days being computed in loc
+func NewCoordinatesInLocation(latitude, longitude float64, loc *time.Location) Coordinates {
+	return newCoordinates(latitude, longitude, loc)
+}
+
+func newCoordinates(latitude, longitude float64, loc *time.Location) *coordinates {
+	return &coordinates{
+		Latitude:          latitude,
+		Longitude:         longitude,
+		Timezone:          loc.String(),
+		location:          loc,
+		mutex:             &sync.Mutex{},
+		sunriseSunsetDone: make(chan bool, 1),
+	}
 }
 
-// Coords returns the Coordinates singleton
+// Coords returns the home's Coordinates, nil if they are not known
 func Coords() Coordinates {
-	return &coords
+	coordsMutex.RLock()
+	defer coordsMutex.RUnlock()
+	return coords
+}
+
+// SetCoords sets the home's Coordinates, c can be nil if they are not known
+func SetCoords(c Coordinates) {
+	coordsMutex.Lock()
+	defer coordsMutex.Unlock()
+	coords = c
 }
 
 // Stop stops the sunrise/sunset refresh goroutine
@@ -137,10 +176,30 @@ func (c *coordinates) GetLongitude() float64 {
 	return c.Longitude
 }
 
+// GetLocation godoc
+func (c *coordinates) GetLocation() *time.Location {
+	return c.location
+}
+
 func (c *coordinates) GetSunriseSunset() (time.Time, time.Time, error) {
 	return c.getSunriseSunset(true)
 }
 
+// GetSunTimes godoc
+func (c *coordinates) GetSunTimes(date time.Time, elevation float64) (time.Time, time.Time, error) {
+	return SunTimes(c.Latitude, c.Longitude, date, elevation)
+}
+
+// GetSunInfo godoc
+func (c *coordinates) GetSunInfo(date time.Time) SunInfo {
+	return GetSunInfo(c.Latitude, c.Longitude, date)
+}
+
+// GetSunPosition godoc
+func (c *coordinates) GetSunPosition(t time.Time) (float64, float64) {
+	return SunPosition(c.Latitude, c.Longitude, t)
+}
+
 // GetSunriseSunset gets sunrise and sunset times
 func (c *coordinates) getSunriseSunset(cache bool) (time.Time, time.Time, error) {
 	if c.mutex == nil {
@@ -149,7 +208,7 @@ func (c *coordinates) getSunriseSunset(cache bool) (time.Time, time.Time, error)
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	now := time.Now().Local()
+	now := time.Now().In(c.location)
 
 	l := logging.NewLogger("GetSunriseSunset")
 
@@ -167,24 +226,13 @@ func (c *coordinates) getSunriseSunset(cache bool) (time.Time, time.Time, error)
 		}
 	}
 
-	name, offset := now.Zone()
-	l.Trace().Str("zone_name", name).Int("offset", offset).Msg("Timezone information")
-
-	p := sunrisesunset.Parameters{
-		Latitude:  c.Latitude,
-		Longitude: c.Longitude,
-		UtcOffset: float64(offset) / 3600,
-		Date:      now,
-	}
-
-	sunrise, sunset, err := p.GetSunriseSunset()
+	sunrise, sunset, err := SunTimes(c.Latitude, c.Longitude, now, SunriseElevation)
 	if err != nil {
 		return time.Time{}, time.Time{}, err
 	}
 
-	// Using now.Location() to get correct timezone (sunrise and sunset doesn't have a Location set to return UTC time.UTC)
-	c.sunrise = time.Date(now.Year(), now.Month(), now.Day(), sunrise.Hour(), sunrise.Minute(), sunrise.Second(), sunrise.Nanosecond(), now.Location())
-	c.sunset = time.Date(now.Year(), now.Month(), now.Day(), sunset.Hour(), sunset.Minute(), sunset.Second(), sunset.Nanosecond(), now.Location())
+	c.sunrise = sunrise
+	c.sunset = sunset
 	c.lastUpdate = now
 
 	l.Info().
@@ -195,18 +243,15 @@ func (c *coordinates) getSunriseSunset(cache bool) (time.Time, time.Time, error)
 	return c.sunrise, c.sunset, nil
 }
 
-// IsDarkNow returns true if it's dark outside
-func (c *coordinates) IsDarkNow(offsetDawn, offsetDusk time.Duration) bool {
-	l := logging.NewLogger("IsDarkNow")
+// IsDark returns true if the sun is below elevation now
+func (c *coordinates) IsDark(elevation float64) bool {
+	l := logging.NewLogger("IsDark")
 	if c.GetLatitude() == 0 || c.GetLongitude() == 0 {
 		l.Warn().Msg("Latitude or longitude is not set, cannot determine if it's dark")
 		return false
 	}
-	now := time.Now().Local()
-	sunrise, sunset, _ := c.GetSunriseSunset()
-	sunrise = sunrise.Add(offsetDawn)
-	sunset = sunset.Add(offsetDusk)
-	return now.Before(sunrise) || now.After(sunset)
+	sunElevation, _ := c.GetSunPosition(time.Now())
+	return sunElevation < elevation
 }
 
 // GetName returns the name of this runnable object
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/nmaupu/gotomation/httpclient"
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+)
+
+func TestZoneCoordinates(t *testing.T) {
+	srv := hasstest.NewServer(
+		model.HassState{EntityID: "zone.home", State: "0", Attributes: map[string]any{"latitude": 48.8566, "longitude": 2.3522}},
+		model.HassState{EntityID: "zone.work", State: "0", Attributes: map[string]any{"radius": 100}},
+	)
+	t.Cleanup(srv.Close)
+	client := httpclient.NewSimpleClient(model.HassConfig{
+		URL:   url.URL{Scheme: "http", Host: srv.Host(), Path: "api"},
+		Token: srv.Token,
+	})
+
+	tests := []struct {
+		name          string
+		client        httpclient.SimpleClient
+		zone          string
+		wantLatitude  float64
+		wantLongitude float64
+		wantErr       bool
+	}{
+		{
+			name:          "zone",
+			client:        client,
+			zone:          "home",
+			wantLatitude:  48.8566,
+			wantLongitude: 2.3522,
+		},
+		{
+			name:    "zone_without_coordinates",
+			client:  client,
+			zone:    "work",
+			wantErr: true,
+		},
+		{
+			name:    "unknown_zone",
+			client:  client,
+			zone:    "unknown",
+			wantErr: true,
+		},
+		{
+			name:    "no_client",
+			zone:    "home",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ZoneCoordinates(tt.client, tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ZoneCoordinates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.GetLatitude() != tt.wantLatitude || got.GetLongitude() != tt.wantLongitude {
+				t.Errorf("ZoneCoordinates() = %v,%v, want %v,%v",
+					got.GetLatitude(), got.GetLongitude(), tt.wantLatitude, tt.wantLongitude)
+			}
+		})
+	}
+}
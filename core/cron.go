@@ -1,9 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"sync"
 
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/model/config"
@@ -13,12 +13,16 @@ import (
 
 var (
 	_ Configurable = (*CronEntry)(nil)
+	_ Releasable   = (*CronEntry)(nil)
 )
 
 // Crontab is a Cron object
 type Crontab interface {
 	routines.Runnable
-	AddFunc(spec string, cmd func()) error
+	AddFunc(spec string, cmd func()) (cron.EntryID, error)
+	// AddSchedule adds cmd to run following schedule, such as a SunSchedule
+	AddSchedule(schedule cron.Schedule, cmd func()) cron.EntryID
+	Remove(id cron.EntryID)
 }
 
 type crontab struct {
@@ -68,9 +72,12 @@ func (c *crontab) IsStarted() bool {
 	return c.started
 }
 
-func (c *crontab) AddFunc(spec string, cmd func()) error {
-	_, err := c.Cron.AddFunc(spec, cmd)
-	return err
+func (c *crontab) AddFunc(spec string, cmd func()) (cron.EntryID, error) {
+	return c.Cron.AddFunc(spec, cmd)
+}
+
+func (c *crontab) AddSchedule(schedule cron.Schedule, cmd func()) cron.EntryID {
+	return c.Cron.Schedule(schedule, cron.FuncJob(cmd))
 }
 
 // GetName returns the name of this runnable object
@@ -79,10 +86,26 @@ func (c *crontab) GetName() string {
 }
 
 // CronEntry is a struct to configure a crontab's entry
+// Action is called on Entities with ServiceData, then Steps are run one after the other
 type CronEntry struct {
-	Expr     string             `mapstructure:"expr"`
+	// Expr is a cron expression or a sun expression such as @sunset -30m, see ParseSchedule
+	Expr string `mapstructure:"expr"`
+	// Action is the service to call on Entities, such as turn_off
 	Action   string             `mapstructure:"action"`
 	Entities []model.HassEntity `mapstructure:"entities"`
+	// ServiceData are Action's extra parameters, such as brightness or temperature
+	ServiceData map[string]any `mapstructure:"service_data"`
+	// Steps are run once Action has been called
+	Steps []Step `mapstructure:"steps"`
+	// EntryID identifies the entry once added to a Crontab
+	EntryID cron.EntryID `mapstructure:"-"`
+
+	// cancel is closed to stop the running steps when the entry is released
+	cancel      chan struct{}
+	mutexCancel sync.Mutex
+	// runtime gives the clients and senders to use, it is read each time the entry runs
+	runtime      *Runtime
+	mutexRuntime sync.RWMutex
 }
 
 // Configure reads the configuration and returns a new Checkable object
@@ -100,16 +123,69 @@ func (c *CronEntry) Configure(data interface{}, i interface{}) error {
 	return nil
 }
 
-// GetActionFunc returns a func to execute when cron time is triggered
+// Validate checks that Action and Steps are usable
+func (c *CronEntry) Validate(runtime *Runtime) []error {
+	var errs []error
+	if c.Action == "" && len(c.Steps) == 0 {
+		errs = append(errs, fmt.Errorf("action or steps has to be set"))
+	}
+	if c.Action != "" && len(c.Entities) == 0 {
+		errs = append(errs, fmt.Errorf("entities are not set"))
+	}
+	return append(errs, ValidateSteps(runtime, "steps", c.Steps)...)
+}
+
+// Bind gives the Runtime to use, it can be called again when the configuration is reloaded
+// Entries already added to a Crontab use the new Runtime from their next run.
+func (c *CronEntry) Bind(runtime *Runtime) {
+	c.mutexRuntime.Lock()
+	defer c.mutexRuntime.Unlock()
+	c.runtime = runtime
+}
+
+// GetRuntime returns the bound Runtime, an empty one if Bind has not been called
+func (c *CronEntry) GetRuntime() *Runtime {
+	c.mutexRuntime.RLock()
+	defer c.mutexRuntime.RUnlock()
+	if c.runtime == nil {
+		return &Runtime{}
+	}
+	return c.runtime
+}
+
+// GetActionFunc returns a func to execute when cron time is triggered, the bound Runtime gives the client to use
 func (c *CronEntry) GetActionFunc() func() {
 	return func() {
-		l := logging.NewLogger("CronEntry.GetActionFunc")
-		for _, entity := range c.Entities {
-			l.Debug().
-				Str("action", c.Action).
-				Object("entity", entity).
-				Msg("Executing cron action for entity")
-			httpclient.GetSimpleClient().CallService(entity, c.Action, nil)
+		l := logging.NewLogger("CronEntry.GetActionFunc").With().Str("expr", c.Expr).Logger()
+		runtime := c.GetRuntime()
+
+		steps := c.Steps
+		if c.Action != "" {
+			steps = append([]Step{{Entities: c.Entities, Service: c.Action, Data: c.ServiceData}}, steps...)
+		}
+		l.Debug().Msg("Executing cron steps")
+		if !RunSteps(runtime, steps, nil, c.getCancel()) {
+			l.Debug().Msg("Cron released, remaining steps are cancelled")
 		}
 	}
 }
+
+// getCancel returns the channel closed when the entry is released
+func (c *CronEntry) getCancel() chan struct{} {
+	c.mutexCancel.Lock()
+	defer c.mutexCancel.Unlock()
+	if c.cancel == nil {
+		c.cancel = make(chan struct{})
+	}
+	return c.cancel
+}
+
+// Release cancels the steps still running
+func (c *CronEntry) Release() {
+	c.mutexCancel.Lock()
+	defer c.mutexCancel.Unlock()
+	if c.cancel != nil {
+		close(c.cancel)
+		c.cancel = nil
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+// DispatchOptions delay or drop events before they trigger an Actionable
+// Options apply independently to each entity (or event type for events without entity).
+type DispatchOptions struct {
+	// Debounce triggers only once no other event has been received during this duration, with the last event
+	Debounce time.Duration `mapstructure:"debounce"`
+	// Throttle drops events received less than this duration after the last triggered one
+	Throttle time.Duration `mapstructure:"throttle"`
+	// For triggers only if the entity's state has not changed during this duration
+	For time.Duration `mapstructure:"for"`
+}
+
+// IsZero returns true if no option is set, events trigger right away
+func (o DispatchOptions) IsZero() bool {
+	return o.Debounce <= 0 && o.Throttle <= 0 && o.For <= 0
+}
+
+// Validate checks options are consistent
+func (o DispatchOptions) Validate() error {
+	if o.Debounce > 0 && o.For > 0 {
+		return fmt.Errorf("debounce and for cannot be used together")
+	}
+	if o.Debounce < 0 || o.Throttle < 0 || o.For < 0 {
+		return fmt.Errorf("debounce, throttle and for cannot be negative")
+	}
+	return nil
+}
+
+// pendingEvent is an event waiting for its delay to elapse
+type pendingEvent struct {
+	timer *time.Timer
+	event model.HassEvent
+}
+
+// dispatcher applies DispatchOptions to events
+// Its zero value is ready to use.
+type dispatcher struct {
+	mutex         sync.Mutex
+	pending       map[string]*pendingEvent
+	lastTriggered map[string]time.Time
+	released      bool
+}
+
+// dispatch calls trigger with e according to opts, right away or never
+// A delayed event is given to requeue once its delay has elapsed, fire has to be called with it afterwards.
+func (d *dispatcher) dispatch(opts DispatchOptions, e *model.HassEvent, trigger func(e *model.HassEvent), requeue func(e *model.HassEvent)) {
+	key := dispatchKey(e)
+	evt := *e // events outlive the callback, making a copy
+
+	d.mutex.Lock()
+	if d.released {
+		d.mutex.Unlock()
+		return
+	}
+
+	delay := opts.Debounce
+	if opts.For > 0 {
+		delay = opts.For
+	}
+	if delay <= 0 {
+		allowed := d.allow(opts, key)
+		d.mutex.Unlock()
+		if allowed {
+			trigger(&evt)
+		}
+		return
+	}
+	defer d.mutex.Unlock()
+
+	if p, ok := d.pending[key]; ok {
+		if opts.For > 0 && strings.EqualFold(p.event.Event.Data.NewState.State, evt.Event.Data.NewState.State) {
+			// State did not change (attributes only), keeping the initial delay
+			p.event = evt
+			return
+		}
+		p.timer.Stop()
+		delete(d.pending, key)
+	}
+
+	if d.pending == nil {
+		d.pending = make(map[string]*pendingEvent)
+	}
+	p := &pendingEvent{event: evt}
+	p.timer = time.AfterFunc(delay, func() {
+		d.expire(key, p, requeue)
+	})
+	d.pending[key] = p
+}
+
+// expire gives a pending event to requeue once its delay has elapsed
+func (d *dispatcher) expire(key string, p *pendingEvent, requeue func(e *model.HassEvent)) {
+	d.mutex.Lock()
+	if d.released || d.pending[key] != p {
+		// Cancelled meanwhile
+		d.mutex.Unlock()
+		return
+	}
+	delete(d.pending, key)
+	evt := p.event
+	d.mutex.Unlock()
+
+	requeue(&evt)
+}
+
+// fire calls trigger with e whose delay has elapsed unless it is throttled
+func (d *dispatcher) fire(opts DispatchOptions, e *model.HassEvent, trigger func(e *model.HassEvent)) {
+	d.mutex.Lock()
+	if d.released {
+		d.mutex.Unlock()
+		return
+	}
+	allowed := d.allow(opts, dispatchKey(e))
+	d.mutex.Unlock()
+
+	if allowed {
+		trigger(e)
+	}
+}
+
+// allow returns true if the event for key is not throttled and records it as triggered
+// d.mutex has to be locked by the caller
+func (d *dispatcher) allow(opts DispatchOptions, key string) bool {
+	if opts.Throttle <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if last, ok := d.lastTriggered[key]; ok && now.Sub(last) < opts.Throttle {
+		return false
+	}
+	if d.lastTriggered == nil {
+		d.lastTriggered = make(map[string]time.Time)
+	}
+	d.lastTriggered[key] = now
+	return true
+}
+
+// release cancels all pending events, events dispatched afterwards are dropped
+func (d *dispatcher) release() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.released = true
+	for key, p := range d.pending {
+		p.timer.Stop()
+		delete(d.pending, key)
+	}
+}
+
+// dispatchKey returns the key options are applied to for e
+func dispatchKey(e *model.HassEvent) string {
+	if entity := e.GetEntity(); entity.Domain != "" {
+		return entity.GetFullName()
+	}
+	return e.Event.EventType
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+func newStateEvent(entityID, state string) model.HassEvent {
+	evt := model.HassEvent{}
+	evt.Event.EventType = "state_changed"
+	evt.Event.Data.EntityID = entityID
+	evt.Event.Data.NewState.State = state
+	return evt
+}
+
+func Test_dispatcher_dispatch(t *testing.T) {
+	type sent struct {
+		event model.HassEvent
+		after time.Duration // wait before sending the event
+	}
+
+	tests := []struct {
+		name    string
+		opts    DispatchOptions
+		events  []sent
+		release bool
+		want    []string
+	}{
+		{
+			name: "no_option",
+			events: []sent{
+				{event: newStateEvent("binary_sensor.door", "on")},
+				{event: newStateEvent("binary_sensor.door", "off")},
+			},
+			want: []string{"on", "off"},
+		},
+		{
+			name: "throttle",
+			opts: DispatchOptions{Throttle: time.Hour},
+			events: []sent{
+				{event: newStateEvent("binary_sensor.door", "on")},
+				{event: newStateEvent("binary_sensor.door", "off")},
+				{event: newStateEvent("binary_sensor.window", "off")},
+			},
+			want: []string{"on", "off"},
+		},
+		{
+			name: "debounce_keeps_last",
+			opts: DispatchOptions{Debounce: 50 * time.Millisecond},
+			events: []sent{
+				{event: newStateEvent("sensor.humidity", "60")},
+				{event: newStateEvent("sensor.humidity", "61"), after: 10 * time.Millisecond},
+				{event: newStateEvent("sensor.humidity", "62"), after: 10 * time.Millisecond},
+			},
+			want: []string{"62"},
+		},
+		{
+			name: "for_state_held",
+			opts: DispatchOptions{For: 50 * time.Millisecond},
+			events: []sent{
+				{event: newStateEvent("binary_sensor.door", "on")},
+				{event: newStateEvent("binary_sensor.door", "on"), after: 30 * time.Millisecond},
+			},
+			want: []string{"on"},
+		},
+		{
+			name: "for_state_bounced",
+			opts: DispatchOptions{For: 50 * time.Millisecond},
+			events: []sent{
+				{event: newStateEvent("binary_sensor.door", "on")},
+				{event: newStateEvent("binary_sensor.door", "off"), after: 30 * time.Millisecond},
+				{event: newStateEvent("binary_sensor.door", "on"), after: 30 * time.Millisecond},
+			},
+			want: []string{"on"},
+		},
+		{
+			name:    "released",
+			opts:    DispatchOptions{For: 50 * time.Millisecond},
+			events:  []sent{{event: newStateEvent("binary_sensor.door", "on")}},
+			release: true,
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			got := make([]string, 0)
+			trigger := func(e *model.HassEvent) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				got = append(got, e.Event.Data.NewState.State)
+			}
+
+			d := dispatcher{}
+			// Firing right away like a worker would once the event is requeued
+			requeue := func(e *model.HassEvent) {
+				d.fire(tt.opts, e, trigger)
+			}
+			start := time.Now()
+			for _, s := range tt.events {
+				time.Sleep(s.after)
+				evt := s.event
+				d.dispatch(tt.opts, &evt, trigger, requeue)
+			}
+			if tt.release {
+				d.release()
+			}
+			// Pending events last at most For or Debounce
+			time.Sleep(tt.opts.For + tt.opts.Debounce + 50*time.Millisecond)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("triggered = %v, want %v (after %s)", got, tt.want, time.Since(start))
+			}
+		})
+	}
+}
+
+func TestDispatchOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    DispatchOptions
+		wantErr bool
+	}{
+		{name: "zero", opts: DispatchOptions{}},
+		{name: "debounce_throttle", opts: DispatchOptions{Debounce: time.Second, Throttle: time.Minute}},
+		{name: "debounce_for", opts: DispatchOptions{Debounce: time.Second, For: time.Minute}, wantErr: true},
+		{name: "negative", opts: DispatchOptions{Throttle: -time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -51,9 +51,41 @@ type HeaterSchedules struct {
 	Thermostat model.HassEntity   `mapstructure:"thermostat"`
 	DateBegin  model.DayMonthDate `mapstructure:"date_begin"`
 	DateEnd    model.DayMonthDate `mapstructure:"date_end"`
+	// Exceptions replace Scheds on some days, they take precedence over Holidays
+	Exceptions []HeaterException `mapstructure:"exceptions"`
+	// Holidays are exceptions shared by several heaters, read from a holidays file
+	Holidays []HeaterException `mapstructure:"-"`
+
+	// WindowSensors suspend schedules while one of them is on, meaning a window is open
+	WindowSensors []model.HassEntity `mapstructure:"window_sensors"`
+	// TemperatureDrop suspends schedules when the temperature drops sharply as when a window is open
+	TemperatureDrop TemperatureDrop `mapstructure:"temperature_drop"`
+	// WindowClosedFor is how long windows have to be closed before resuming schedules, DefaultWindowClosedFor if not set
+	WindowClosedFor time.Duration `mapstructure:"window_closed_for"`
+	// WindowOpenTemperature is set while schedules are suspended, such as a frost protection temperature
+	// The thermostat is turned off if not set.
+	WindowOpenTemperature *float64 `mapstructure:"window_open_temperature"`
+}
+
+// HeaterHolidays are the exceptions of a holidays file
+type HeaterHolidays struct {
+	Exceptions []HeaterException `mapstructure:"exceptions"`
+}
+
+// HeaterException replaces a heater's schedules on some days, such as holidays or when away
+type HeaterException struct {
+	// Name describes the exception, such as christmas
+	Name string `mapstructure:"name"`
+	// Dates are the days the exception applies to, such as 2026-12-20..2026-12-27
+	Dates model.DateRange `mapstructure:"dates"`
+	// Temperature is set all day long if set
+	Temperature *float64 `mapstructure:"temperature"`
+	// Schedules are used instead of the regular ones if Temperature is not set
+	Schedules []HeaterSchedule `mapstructure:"schedules"`
 }
 
 // HeaterSchedule represents a heater's schedule
+// The schedule spans over midnight if End is before Beg, it then ends the day after it begins.
 type HeaterSchedule struct {
 	Beg     time.Time `mapstructure:"beg"`
 	End     time.Time `mapstructure:"end"`
@@ -75,10 +107,22 @@ func (c HeaterSchedule) TodayEnd(now time.Time, loc *time.Location) time.Time {
 	return getTodayTime(now, c.End, loc)
 }
 
+// IsOvernight returns true if c spans over midnight
+func (c HeaterSchedule) IsOvernight() bool {
+	// Comparing times of day only
+	return c.TodayEnd(c.Beg, time.UTC).Before(c.TodayBeg(c.Beg, time.UTC))
+}
+
 // IsActive returns true if given 't' is between c.Beg and c.End
+// An overnight schedule is active from c.Beg until midnight and from midnight until c.End.
 func (c HeaterSchedule) IsActive(t time.Time) bool {
 	l := logging.NewLogger("HeaterSchedule.IsActive")
-	ret := t.After(c.TodayBeg(t, t.Location())) && t.Before(c.TodayEnd(t, t.Location()))
+	afterBeg := t.After(c.TodayBeg(t, t.Location()))
+	beforeEnd := t.Before(c.TodayEnd(t, t.Location()))
+	ret := afterBeg && beforeEnd
+	if c.IsOvernight() {
+		ret = afterBeg || beforeEnd
+	}
 	l.Debug().
 		EmbedObject(c).
 		Bool("ret", ret).
@@ -95,6 +139,11 @@ func (c HeaterSchedule) MarshalZerologObject(event *zerolog.Event) {
 		Float64("eco", c.Eco)
 }
 
+// Days returns the days names usable in SchedulesDays
+func Days() []string {
+	return append([]string(nil), days...)
+}
+
 // AsFlag returns an int from a SchedulesDays
 func (s SchedulesDays) AsFlag() int {
 	result := 0
@@ -147,27 +196,40 @@ func (c *HeaterSchedules) Sort() {
 }
 
 // GetTemperatureToSet returns the temperature to set corresponding to the time given in parameter
+// The eco temperature of the last schedule ended today is set out of schedules, DefaultEco if none has ended yet.
+// Exceptions and holidays replace the schedules of their days.
 func (c *HeaterSchedules) GetTemperatureToSet(t time.Time) float64 {
 	if t.Location() == nil {
 		t = t.Local()
 	}
 
-	finalTemp := c.DefaultEco
-	// Sorting schedules to get stuff in order (eco temp of the previous time range
-	// is the temperature to set if we are not currently in a "comfort" time range)
+	if exception := c.GetException(t); exception != nil && exception.Temperature != nil {
+		return *exception.Temperature
+	}
+
+	// Sorting schedules to get stuff in order, the first active schedule wins
 	c.Sort()
-	for schedulesDays, schedules := range c.Scheds {
-		if !schedulesDays.IsScheduled(t) { // not schedules for today
-			continue
-		}
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	yesterday := today.AddDate(0, 0, -1)
 
-		// Configuration applies today
-		for _, sched := range schedules {
-			if sched.IsActive(t) { // in between
-				return sched.Comfort
+	finalTemp := c.DefaultEco
+	var lastEnd time.Time
+	for _, day := range []time.Time{yesterday, today} {
+		for _, sched := range c.schedulesOn(day) {
+			beg := sched.TodayBeg(day, t.Location())
+			end := sched.TodayEnd(day, t.Location())
+			if sched.IsOvernight() {
+				end = sched.TodayEnd(day.AddDate(0, 0, 1), t.Location())
+			}
+			if end.Before(today) { // ended yesterday
+				continue
 			}
 
-			if t.After(sched.TodayEnd(t, t.Location())) {
+			if t.After(beg) && t.Before(end) { // in between
+				return sched.Comfort
+			}
+			if t.After(end) && end.After(lastEnd) {
+				lastEnd = end
 				finalTemp = sched.Eco
 			}
 		}
@@ -176,10 +238,73 @@ func (c *HeaterSchedules) GetTemperatureToSet(t time.Time) float64 {
 	return finalTemp
 }
 
+// NextComfort returns the beginning and the comfort temperature of the first schedule beginning after t and before t+within
+// ok is false if there is none.
+func (c *HeaterSchedules) NextComfort(t time.Time, within time.Duration) (beg time.Time, comfort float64, ok bool) {
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for day := today; !day.After(t.Add(within)); day = day.AddDate(0, 0, 1) {
+		for _, sched := range c.schedulesOn(day) {
+			schedBeg := sched.TodayBeg(day, t.Location())
+			if !schedBeg.After(t) || schedBeg.After(t.Add(within)) {
+				continue
+			}
+			if !ok || schedBeg.Before(beg) {
+				beg, comfort, ok = schedBeg, sched.Comfort, true
+			}
+		}
+	}
+	return beg, comfort, ok
+}
+
+// HasWindowDetection returns true if schedules are suspended when a window is open
+func (c *HeaterSchedules) HasWindowDetection() bool {
+	return len(c.WindowSensors) > 0 || c.TemperatureDrop.Degrees > 0
+}
+
+func (c *HeaterSchedules) getWindowClosedFor() time.Duration {
+	if c.WindowClosedFor > 0 {
+		return c.WindowClosedFor
+	}
+	return DefaultWindowClosedFor
+}
+
+// GetException returns the exception applying on t's day, nil if none
+func (c *HeaterSchedules) GetException(t time.Time) *HeaterException {
+	for _, exceptions := range [][]HeaterException{c.Exceptions, c.Holidays} {
+		for i := range exceptions {
+			if exceptions[i].Dates.Contains(t) {
+				return &exceptions[i]
+			}
+		}
+	}
+	return nil
+}
+
+// schedulesOn returns the schedules beginning on day's day
+func (c *HeaterSchedules) schedulesOn(day time.Time) []HeaterSchedule {
+	if exception := c.GetException(day); exception != nil {
+		return exception.Schedules
+	}
+
+	scheds := make([]HeaterSchedule, 0)
+	for schedulesDays, schedules := range c.Scheds {
+		if schedulesDays.IsScheduled(day) {
+			scheds = append(scheds, schedules...)
+		}
+	}
+	return scheds
+}
+
 // MarshalZerologObject godoc
 func (c *HeaterSchedules) MarshalZerologObject(event *zerolog.Event) {
 	event.Time("date_begin", time.Time(c.DateBegin))
 	event.Time("date_end", time.Time(c.DateEnd))
+	for idx, exception := range append(append([]HeaterException(nil), c.Exceptions...), c.Holidays...) {
+		event = event.Str(fmt.Sprintf("exceptions[%d]", idx), exception.Name+" "+exception.Dates.String())
+	}
+	for idx, sensor := range c.WindowSensors {
+		event = event.Str(fmt.Sprintf("window_sensors[%d]", idx), sensor.GetEntityIDFullName())
+	}
 	for schedName, s := range c.Scheds {
 		for idx, sched := range s {
 			event = event.Object(fmt.Sprintf("%s[%d]", schedName, idx), sched)
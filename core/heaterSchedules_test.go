@@ -1,8 +1,14 @@
 package core
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/spf13/viper"
 )
 
 func TestSchedulesDays_AsFlag(t *testing.T) {
@@ -475,3 +481,193 @@ func TestHeaterSchedules_GetTemperatureToSet(t *testing.T) {
 		})
 	}
 }
+
+func TestHeaterSchedules_GetTemperatureToSet_overnightAndExceptions(t *testing.T) {
+	schedulesYAML := `
+default_eco: 16
+schedules:
+  week:
+    - beg: 07:00:00
+      end: 08:30:00
+      comfort: 20
+      eco: 17
+  friday,saturday:
+    - beg: 22:00:00
+      end: 06:30:00
+      comfort: 19
+      eco: 15
+exceptions:
+  - name: away
+    dates: 2026-12-20..2026-12-27
+    temperature: 12
+  - name: guests
+    dates: 2026-12-31
+    schedules:
+      - beg: 18:00:00
+        end: 02:00:00
+        comfort: 21
+        eco: 18
+`
+	holidaysYAML := `
+exceptions:
+  - name: guests_overridden
+    dates: 2026-12-31
+    temperature: 25
+  - name: new_year
+    dates: 2027-01-01
+    temperature: 14
+`
+	decode := func(data string, result any) {
+		vi := viper.New()
+		vi.SetConfigType("yaml")
+		if err := vi.ReadConfig(strings.NewReader(data)); err != nil {
+			t.Fatalf("unable to read yaml, err=%v", err)
+		}
+		err := vi.Unmarshal(result, func(dc *mapstructure.DecoderConfig) {
+			dc.DecodeHook = config.MapstructureDecodeHookFunc()
+		})
+		if err != nil {
+			t.Fatalf("unable to decode yaml, err=%v", err)
+		}
+	}
+	c := new(HeaterSchedules)
+	decode(schedulesYAML, c)
+	holidays := new(HeaterHolidays)
+	decode(holidaysYAML, holidays)
+	c.Holidays = holidays.Exceptions
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want float64
+	}{
+		{
+			name: "overnight_before_midnight",
+			t:    time.Date(2026, 10, 16, 23, 0, 0, 0, time.Local), // friday
+			want: 19,
+		},
+		{
+			name: "overnight_after_midnight",
+			t:    time.Date(2026, 10, 17, 5, 0, 0, 0, time.Local), // saturday
+			want: 19,
+		},
+		{
+			name: "overnight_ended",
+			t:    time.Date(2026, 10, 17, 7, 0, 0, 0, time.Local),
+			want: 15,
+		},
+		{
+			name: "overnight_ended_sunday",
+			t:    time.Date(2026, 10, 18, 5, 0, 0, 0, time.Local), // began on saturday
+			want: 19,
+		},
+		{
+			name: "not_scheduled_the_day_before",
+			t:    time.Date(2026, 10, 16, 5, 0, 0, 0, time.Local), // thursday
+			want: 16,
+		},
+		{
+			name: "overnight_before_comfort_of_the_day",
+			t:    time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local), // monday
+			want: 17,
+		},
+		{
+			name: "exception_temperature",
+			t:    time.Date(2026, 12, 25, 7, 30, 0, 0, time.Local),
+			want: 12,
+		},
+		{
+			name: "exception_schedules",
+			t:    time.Date(2026, 12, 31, 20, 0, 0, 0, time.Local),
+			want: 21,
+		},
+		{
+			name: "exception_out_of_schedules",
+			t:    time.Date(2026, 12, 31, 7, 30, 0, 0, time.Local),
+			want: 16,
+		},
+		{
+			name: "holiday",
+			t:    time.Date(2027, 1, 1, 1, 0, 0, 0, time.Local),
+			want: 14,
+		},
+		{
+			name: "after_exception",
+			t:    time.Date(2027, 1, 2, 1, 0, 0, 0, time.Local), // saturday, friday was a holiday
+			want: 16,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.GetTemperatureToSet(tt.t); got != tt.want {
+				t.Errorf("HeaterSchedules.GetTemperatureToSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaterSchedules_NextComfort(t *testing.T) {
+	c := &HeaterSchedules{
+		Scheds: map[SchedulesDays][]HeaterSchedule{
+			"week": {
+				{Beg: time.Date(0, 0, 0, 7, 0, 0, 0, time.Local), End: time.Date(0, 0, 0, 8, 30, 0, 0, time.Local), Comfort: 20},
+				{Beg: time.Date(0, 0, 0, 18, 0, 0, 0, time.Local), End: time.Date(0, 0, 0, 22, 0, 0, 0, time.Local), Comfort: 21},
+			},
+		},
+		Exceptions: []HeaterException{
+			{Name: "away", Dates: model.DateRange{
+				From: time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC),
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		t           time.Time
+		within      time.Duration
+		wantBeg     time.Time
+		wantComfort float64
+		wantOK      bool
+	}{
+		{
+			name:        "same_day",
+			t:           time.Date(2026, 1, 5, 6, 0, 0, 0, time.Local), // monday
+			within:      2 * time.Hour,
+			wantBeg:     time.Date(2026, 1, 5, 7, 0, 0, 0, time.Local),
+			wantComfort: 20,
+			wantOK:      true,
+		},
+		{
+			name:   "too_far",
+			t:      time.Date(2026, 1, 5, 4, 0, 0, 0, time.Local),
+			within: 2 * time.Hour,
+		},
+		{
+			name:        "next_day",
+			t:           time.Date(2026, 1, 5, 23, 0, 0, 0, time.Local),
+			within:      10 * time.Hour,
+			wantBeg:     time.Date(2026, 1, 6, 7, 0, 0, 0, time.Local),
+			wantComfort: 20,
+			wantOK:      true,
+		},
+		{
+			name:   "already_begun",
+			t:      time.Date(2026, 1, 5, 7, 30, 0, 0, time.Local),
+			within: time.Hour,
+		},
+		{
+			name:   "exception",
+			t:      time.Date(2026, 1, 9, 6, 0, 0, 0, time.Local), // friday away
+			within: 2 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			beg, comfort, ok := c.NextComfort(tt.t, tt.within)
+			if ok != tt.wantOK || !beg.Equal(tt.wantBeg) || comfort != tt.wantComfort {
+				t.Errorf("NextComfort() = %v, %v, %v, want %v, %v, %v", beg, comfort, ok, tt.wantBeg, tt.wantComfort, tt.wantOK)
+			}
+		})
+	}
+}
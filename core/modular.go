@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nmaupu/gotomation/model"
 )
 
 // Modular is an interface that will implement a check function
@@ -13,3 +14,10 @@ type Modular interface {
 	GetInterval() time.Duration
 	GinHandler(c *gin.Context)
 }
+
+// EventWatcher is implemented by Modular objects which have to be checked right away
+// when some events are received instead of waiting for their next interval
+type EventWatcher interface {
+	// Watches returns true if e requires a check
+	Watches(e *model.HassEvent) bool
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nmaupu/gotomation/logging"
+)
+
+const (
+	// DefaultPreheatMaxLead is the maximum time to start heating before a comfort schedule when not set
+	DefaultPreheatMaxLead = 2 * time.Hour
+	// DefaultPreheatRate is the time taken to raise the temperature by one degree until a rate has been learned
+	DefaultPreheatRate = 15 * time.Minute
+
+	// preheatMinDelta is the minimum temperature rise in degrees to learn from, smaller ones are too noisy
+	preheatMinDelta = 0.5
+	// preheatTolerance is how close to the setpoint in degrees the temperature has to be to consider it reached
+	preheatTolerance = 0.2
+	// preheatSmoothing is the weight of a new observation in the learned rate
+	preheatSmoothing = 0.3
+)
+
+var (
+	// preheatFileMutex protects state files shared by several heaters
+	preheatFileMutex sync.Mutex
+)
+
+// PreheatConfig configures when to start heating before a comfort schedule
+type PreheatConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxLead caps how long before a comfort schedule heating can start, DefaultPreheatMaxLead if not set
+	MaxLead time.Duration `mapstructure:"max_lead"`
+	// DefaultRate is the time taken to raise the temperature by one degree until a rate has been learned
+	DefaultRate time.Duration `mapstructure:"default_rate"`
+	// StateFile persists learned rates, they are kept in memory only if not set
+	// Several heaters can share the same file.
+	StateFile string `mapstructure:"state_file"`
+}
+
+// Validate returns an error if durations are negative
+func (c PreheatConfig) Validate() error {
+	if c.MaxLead < 0 || c.DefaultRate < 0 {
+		return fmt.Errorf("preheat: max_lead and default_rate cannot be negative")
+	}
+	return nil
+}
+
+// PreheatState is what has been learned for a thermostat
+type PreheatState struct {
+	// MinutesPerDegree is the time taken to raise the temperature by one degree
+	MinutesPerDegree float64 `json:"minutes_per_degree"`
+	// Samples is the number of warm-ups learned from
+	Samples int `json:"samples"`
+}
+
+// warmUp is a warm-up being observed
+type warmUp struct {
+	start       time.Time
+	temperature float64
+	setpoint    float64
+}
+
+// Preheater learns how fast a thermostat warms up and computes when to start heating to reach a setpoint in time
+type Preheater struct {
+	config     PreheatConfig
+	thermostat string
+
+	mutex   sync.Mutex
+	state   PreheatState
+	current *warmUp
+}
+
+// NewPreheater returns a Preheater for thermostat, reading its state from config's state file if any
+func NewPreheater(config PreheatConfig, thermostat string) *Preheater {
+	l := logging.NewLogger("NewPreheater").With().Str("thermostat", thermostat).Logger()
+
+	p := &Preheater{config: config, thermostat: thermostat}
+	if config.StateFile == "" {
+		return p
+	}
+
+	states, err := readPreheatStates(config.StateFile)
+	if err != nil {
+		l.Error().Err(err).Str("filename", config.StateFile).Msg("Unable to read preheat state, starting from scratch")
+		return p
+	}
+	p.state = states[thermostat]
+	return p
+}
+
+// GetThermostat returns the name of the thermostat learned from
+func (p *Preheater) GetThermostat() string {
+	return p.thermostat
+}
+
+// GetState returns what has been learned so far
+func (p *Preheater) GetState() PreheatState {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.state
+}
+
+// GetRate returns the time taken to raise the temperature by one degree
+func (p *Preheater) GetRate() time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.getRate()
+}
+
+func (p *Preheater) getRate() time.Duration {
+	if p.state.Samples > 0 {
+		return time.Duration(p.state.MinutesPerDegree * float64(time.Minute))
+	}
+	if p.config.DefaultRate > 0 {
+		return p.config.DefaultRate
+	}
+	return DefaultPreheatRate
+}
+
+// GetMaxLead returns how long before a comfort schedule heating can start at most
+func (p *Preheater) GetMaxLead() time.Duration {
+	if p.config.MaxLead > 0 {
+		return p.config.MaxLead
+	}
+	return DefaultPreheatMaxLead
+}
+
+// Lead returns how long it takes to warm up from temperature to setpoint, capped by the maximum lead
+func (p *Preheater) Lead(temperature, setpoint float64) time.Duration {
+	if setpoint <= temperature {
+		return 0
+	}
+	lead := time.Duration((setpoint - temperature) * float64(p.GetRate()))
+	if maxLead := p.GetMaxLead(); lead > maxLead {
+		return maxLead
+	}
+	return lead
+}
+
+// Observe learns from the thermostat's temperature and setpoint at now
+// A warm-up starts when the setpoint is above the temperature and ends when the temperature reaches it.
+// The warm-up is forgotten if the setpoint changes before or if it lasts too long.
+func (p *Preheater) Observe(now time.Time, temperature, setpoint float64) {
+	l := logging.NewLogger("Preheater.Observe").With().
+		Str("thermostat", p.thermostat).
+		Float64("temperature", temperature).
+		Float64("setpoint", setpoint).
+		Logger()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.current != nil && (p.current.setpoint != setpoint || now.Sub(p.current.start) > 2*p.GetMaxLead()) {
+		l.Debug().Msg("Warm-up interrupted, forgetting it")
+		p.current = nil
+	}
+
+	if p.current == nil {
+		if setpoint-temperature >= preheatMinDelta {
+			l.Debug().Msg("Warm-up started")
+			p.current = &warmUp{start: now, temperature: temperature, setpoint: setpoint}
+		}
+		return
+	}
+
+	if temperature < p.current.setpoint-preheatTolerance {
+		return
+	}
+
+	minutesPerDegree := now.Sub(p.current.start).Minutes() / (temperature - p.current.temperature)
+	if p.state.Samples == 0 {
+		p.state.MinutesPerDegree = minutesPerDegree
+	} else {
+		p.state.MinutesPerDegree = preheatSmoothing*minutesPerDegree + (1-preheatSmoothing)*p.state.MinutesPerDegree
+	}
+	p.state.Samples++
+	p.current = nil
+
+	l.Info().
+		Float64("observed_minutes_per_degree", minutesPerDegree).
+		Float64("minutes_per_degree", p.state.MinutesPerDegree).
+		Int("samples", p.state.Samples).
+		Msg("Warm-up done, preheat rate updated")
+
+	if err := p.save(); err != nil {
+		l.Error().Err(err).Str("filename", p.config.StateFile).Msg("Unable to save preheat state")
+	}
+}
+
+// save writes the state to the state file, keeping the other thermostats' ones
+func (p *Preheater) save() error {
+	if p.config.StateFile == "" {
+		return nil
+	}
+
+	preheatFileMutex.Lock()
+	defer preheatFileMutex.Unlock()
+
+	states, err := readPreheatStates(p.config.StateFile)
+	if err != nil {
+		return err
+	}
+	states[p.thermostat] = p.state
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Writing to a temporary file first not to lose everything if interrupted
+	tmp := p.config.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.config.StateFile)
+}
+
+// readPreheatStates returns the states of filename by thermostat, none if filename does not exist
+func readPreheatStates(filename string) (map[string]PreheatState, error) {
+	states := make(map[string]PreheatState)
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreheater_Lead(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      PreheatConfig
+		state       PreheatState
+		temperature float64
+		setpoint    float64
+		want        time.Duration
+	}{
+		{
+			name:        "default_rate",
+			temperature: 18,
+			setpoint:    20,
+			want:        2 * DefaultPreheatRate,
+		},
+		{
+			name:        "configured_default_rate",
+			config:      PreheatConfig{DefaultRate: 10 * time.Minute},
+			temperature: 18,
+			setpoint:    19.5,
+			want:        15 * time.Minute,
+		},
+		{
+			name:        "learned_rate",
+			config:      PreheatConfig{DefaultRate: 10 * time.Minute},
+			state:       PreheatState{MinutesPerDegree: 30, Samples: 2},
+			temperature: 18,
+			setpoint:    20,
+			want:        time.Hour,
+		},
+		{
+			name:        "capped",
+			config:      PreheatConfig{MaxLead: 45 * time.Minute},
+			state:       PreheatState{MinutesPerDegree: 30, Samples: 2},
+			temperature: 18,
+			setpoint:    20,
+			want:        45 * time.Minute,
+		},
+		{
+			name:        "already_warm",
+			temperature: 20.5,
+			setpoint:    20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPreheater(tt.config, "climate.living")
+			p.state = tt.state
+			if got := p.Lead(tt.temperature, tt.setpoint); got != tt.want {
+				t.Errorf("Lead() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreheater_Observe(t *testing.T) {
+	type observation struct {
+		after       time.Duration
+		temperature float64
+		setpoint    float64
+	}
+
+	tests := []struct {
+		name         string
+		state        PreheatState
+		observations []observation
+		want         PreheatState
+	}{
+		{
+			name: "first_warm_up",
+			observations: []observation{
+				{0, 18, 17},
+				{10 * time.Minute, 18, 20},
+				{40 * time.Minute, 19, 20},
+				{70 * time.Minute, 19.9, 20},
+			},
+			want: PreheatState{MinutesPerDegree: 60 / 1.9, Samples: 1},
+		},
+		{
+			name:  "smoothed",
+			state: PreheatState{MinutesPerDegree: 20, Samples: 3},
+			observations: []observation{
+				{0, 18, 20},
+				{80 * time.Minute, 20, 20},
+			},
+			want: PreheatState{MinutesPerDegree: 0.3*40 + 0.7*20, Samples: 4},
+		},
+		{
+			name:  "setpoint_changed",
+			state: PreheatState{MinutesPerDegree: 20, Samples: 3},
+			observations: []observation{
+				{0, 18, 20},
+				{30 * time.Minute, 19, 17},
+				{80 * time.Minute, 20, 17},
+			},
+			want: PreheatState{MinutesPerDegree: 20, Samples: 3},
+		},
+		{
+			name: "too_long",
+			observations: []observation{
+				{0, 18, 20},
+				{5 * time.Hour, 20, 20},
+			},
+		},
+		{
+			name: "delta_too_small",
+			observations: []observation{
+				{0, 19.7, 20},
+				{10 * time.Minute, 20, 20},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateFile := filepath.Join(t.TempDir(), "preheat.json")
+			p := NewPreheater(PreheatConfig{StateFile: stateFile}, "climate.living")
+			p.state = tt.state
+
+			start := time.Date(2026, 1, 5, 6, 0, 0, 0, time.Local)
+			for _, o := range tt.observations {
+				p.Observe(start.Add(o.after), o.temperature, o.setpoint)
+			}
+
+			if got := p.GetState(); got.Samples != tt.want.Samples || !floatEquals(got.MinutesPerDegree, tt.want.MinutesPerDegree) {
+				t.Errorf("GetState() = %+v, want %+v", got, tt.want)
+			}
+			// Learned rates are read back by a new Preheater, other thermostats starting from scratch
+			if got := NewPreheater(PreheatConfig{StateFile: stateFile}, "climate.living").GetState(); tt.want.Samples > tt.state.Samples &&
+				(got.Samples != tt.want.Samples || !floatEquals(got.MinutesPerDegree, tt.want.MinutesPerDegree)) {
+				t.Errorf("persisted state = %+v, want %+v", got, tt.want)
+			}
+			if got := NewPreheater(PreheatConfig{StateFile: stateFile}, "climate.bedroom").GetState(); got.Samples != 0 {
+				t.Errorf("other thermostat's state = %+v, want none", got)
+			}
+		})
+	}
+}
+
+func floatEquals(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -3,7 +3,6 @@ package core
 import (
 	"fmt"
 	"github.com/nmaupu/gotomation/app"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/routines"
@@ -19,7 +18,7 @@ type RandomLightsRoutine interface {
 	GetSlots() uint32
 }
 
-func NewRandomLightsRoutine(name string, slots uint32, lights []model.RandomLight, startTime, endTime time.Time, odds uint32, refreshEvery time.Duration) (RandomLightsRoutine, error) {
+func NewRandomLightsRoutine(runtime *Runtime, name string, slots uint32, lights []model.RandomLight, startTime, endTime time.Time, odds uint32, refreshEvery time.Duration) (RandomLightsRoutine, error) {
 	if name == "" {
 		return nil, fmt.Errorf("name must not be empty")
 	}
@@ -29,6 +28,7 @@ func NewRandomLightsRoutine(name string, slots uint32, lights []model.RandomLigh
 	}
 
 	return &randomLightsRoutine{
+		runtime:      runtime,
 		name:         name,
 		nbSlots:      slots,
 		lights:       lights,
@@ -45,6 +45,7 @@ type autoLight struct {
 }
 
 type randomLightsRoutine struct {
+	runtime              *Runtime
 	name                 string
 	started              bool
 	mutexStopStart       sync.Mutex
@@ -88,7 +89,7 @@ func (r *randomLightsRoutine) Start() error {
 
 	// Setting all lights to off before starting
 	for _, light := range r.lights {
-		err := httpclient.GetSimpleClient().CallService(light.Entity, "turn_off", nil)
+		err := r.runtime.Client(light.Entity).CallService(light.Entity, "turn_off", nil)
 		if err != nil {
 			l.Error().Err(err).EmbedObject(light.Entity).Msg("unable to turn_off light")
 			// here we ignore the error, log only to get a trace of the issue
@@ -138,9 +139,9 @@ func (r *randomLightsRoutine) refresh() {
 	// Generate duration depending on the light
 	// send message
 
-	sunrise, _, err := Coords().GetSunriseSunset()
+	sunrise, _, err := r.runtime.GetSunriseSunset()
 	if err != nil {
-		l.Error().Err(err).Msg("unable to get sunrise time")
+		l.Error().Err(err).Msg("unable to get sunrise time, nothing to do")
 		return
 	}
 
@@ -214,7 +215,7 @@ func (r *randomLightsRoutine) autoLightRoutine() {
 				Msg("Slot available, setting a light ON")
 
 			l.Debug().EmbedObject(msg.Entity).Msg("Setting light to ON")
-			err := httpclient.GetSimpleClient().CallService(msg.Entity, "turn_on", map[string]interface{}{
+			err := r.runtime.Client(msg.Entity).CallService(msg.Entity, "turn_on", map[string]interface{}{
 				"brightness": 90,
 			})
 			if err != nil {
@@ -224,7 +225,7 @@ func (r *randomLightsRoutine) autoLightRoutine() {
 			lightsStatus[msg.Entity.GetEntityIDFullName()] = true
 
 			time.AfterFunc(msg.duration, func() {
-				err := httpclient.GetSimpleClient().CallService(msg.Entity, "turn_off", nil)
+				err := r.runtime.Client(msg.Entity).CallService(msg.Entity, "turn_off", nil)
 				if err != nil {
 					l.Error().Err(err).EmbedObject(msg.Entity).Msg("unable to turn_off light")
 					// here we ignore the error, log only to get a trace of the issue
@@ -94,3 +94,9 @@ func Test_randomLightsRoutine_CheckTime(t *testing.T) {
 		})
 	}
 }
+
+func Test_randomLightsRoutine_refresh_withoutCoordinates(t *testing.T) {
+	r := &randomLightsRoutine{runtime: &Runtime{}}
+	// Sunrise is not known, nothing is done instead of panicking
+	r.refresh()
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
+)
+
+// routedPattern is a regexp pattern routing to a trigger
+// re is nil for a literal, without regexp special characters, which is then matched as a substring
+type routedPattern struct {
+	literal string
+	re      *regexp.Regexp
+	trigger int
+}
+
+// matches returns true if the pattern is found in s, as regexp.MatchString would
+func (p routedPattern) matches(s string) bool {
+	if p.re == nil {
+		return strings.Contains(s, p.literal)
+	}
+	return p.re.MatchString(s)
+}
+
+// RoutingIndex gives the triggers to call for an event
+// Entity_ids and event types are regexp patterns matched anywhere in the event's ones, as they always have been,
+// so literals such as light.living also match light.living_room. Only the entity_ids of the event's instance and
+// domain are evaluated, literals are matched as substrings and other patterns are compiled once.
+type RoutingIndex struct {
+	triggers []Triggerable
+	// entityPatterns are entity_id patterns by instance:domain
+	entityPatterns map[string][]routedPattern
+	// eventTypePatterns are event type patterns
+	eventTypePatterns []routedPattern
+}
+
+// NewRoutingIndex returns the index of triggers' entities and event types, invalid patterns are ignored
+func NewRoutingIndex(triggers []Triggerable) *RoutingIndex {
+	l := logging.NewLogger("NewRoutingIndex")
+
+	idx := &RoutingIndex{
+		triggers:       triggers,
+		entityPatterns: make(map[string][]routedPattern),
+	}
+
+	for i, trigger := range triggers {
+		actionable := trigger.GetActionable()
+
+		for _, entity := range actionable.GetEntitiesForTrigger() {
+			p, err := newRoutedPattern(entity.EntityID, i)
+			if err != nil {
+				l.Error().Err(err).
+					Str("trigger", trigger.GetName()).
+					Str("pattern", entity.EntityID).
+					Msg("Entity pattern is not correct, ignoring")
+				continue
+			}
+			key := domainKey(entity)
+			idx.entityPatterns[key] = append(idx.entityPatterns[key], p)
+		}
+
+		for _, eventType := range actionable.GetEventTypesForTrigger() {
+			p, err := newRoutedPattern(eventType, i)
+			if err != nil {
+				l.Error().Err(err).
+					Str("trigger", trigger.GetName()).
+					Str("pattern", eventType).
+					Msg("Event type pattern is not correct, ignoring")
+				continue
+			}
+			idx.eventTypePatterns = append(idx.eventTypePatterns, p)
+		}
+	}
+
+	return idx
+}
+
+// newRoutedPattern returns the pattern routing expr to trigger, compiled if it is not a literal
+func newRoutedPattern(expr string, trigger int) (routedPattern, error) {
+	if isLiteral(expr) {
+		return routedPattern{literal: expr, trigger: trigger}, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return routedPattern{}, err
+	}
+	return routedPattern{re: re, trigger: trigger}, nil
+}
+
+// Match returns the triggers to call for e, each one only once and in the order they were indexed
+func (idx *RoutingIndex) Match(e *model.HassEvent) []Triggerable {
+	if idx == nil {
+		return nil
+	}
+
+	matched := make(map[int]bool)
+	for _, p := range idx.eventTypePatterns {
+		if !matched[p.trigger] && p.matches(e.Event.EventType) {
+			matched[p.trigger] = true
+		}
+	}
+
+	if entity := e.GetEntity(); entity.Domain != "" {
+		for _, p := range idx.entityPatterns[domainKey(entity)] {
+			if !matched[p.trigger] && p.matches(entity.EntityID) {
+				matched[p.trigger] = true
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(matched))
+	for i := range matched {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	res := make([]Triggerable, 0, len(indexes))
+	for _, i := range indexes {
+		res = append(res, idx.triggers[i])
+	}
+	return res
+}
+
+// isLiteral returns true if s does not contain any regexp special character
+func isLiteral(s string) bool {
+	return regexp.QuoteMeta(s) == s
+}
+
+// domainKey returns the key of entity's instance and domain
+func domainKey(entity model.HassEntity) string {
+	return entity.Instance + model.InstanceSeparator + entity.Domain
+}
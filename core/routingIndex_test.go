@@ -0,0 +1,94 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+func newRoutedTrigger(name string, entities []string, eventTypes []string) Triggerable {
+	action := &Action{EventTypes: eventTypes}
+	action.Name = name
+	for _, entity := range entities {
+		action.Entities = append(action.Entities, model.NewHassEntity(entity))
+	}
+	return &Trigger{Action: action}
+}
+
+func TestRoutingIndex_Match(t *testing.T) {
+	triggers := []Triggerable{
+		newRoutedTrigger("exact", []string{"binary_sensor.door", "light.living"}, nil),
+		newRoutedTrigger("pattern", []string{"sensor.humidity_.*"}, nil),
+		newRoutedTrigger("instance", []string{"garage:binary_sensor.door"}, nil),
+		newRoutedTrigger("events", nil, []string{"roku_command", "call_.*"}),
+		newRoutedTrigger("invalid", []string{"sensor.(["}, []string{"(["}),
+		newRoutedTrigger("both", []string{"binary_sensor.door"}, []string{"state_changed"}),
+	}
+	idx := NewRoutingIndex(triggers)
+
+	tests := []struct {
+		name      string
+		instance  string
+		eventType string
+		entityID  string
+		want      []string
+	}{
+		{
+			name:      "exact_entity",
+			eventType: "state_changed",
+			entityID:  "binary_sensor.door",
+			want:      []string{"exact", "both"},
+		},
+		{
+			name:      "literal_matches_anywhere",
+			eventType: "call_service",
+			entityID:  "light.living_room",
+			want:      []string{"exact", "events"},
+		},
+		{
+			name:      "literal_event_type_matches_anywhere",
+			eventType: "roku_command_received",
+			want:      []string{"events"},
+		},
+		{
+			name:      "pattern",
+			eventType: "state_changed",
+			entityID:  "sensor.humidity_basement",
+			want:      []string{"pattern", "both"},
+		},
+		{
+			name:      "named_instance",
+			instance:  "garage",
+			eventType: "other",
+			entityID:  "binary_sensor.door",
+			want:      []string{"instance"},
+		},
+		{
+			name:      "event_type",
+			eventType: "roku_command",
+			want:      []string{"events"},
+		},
+		{
+			name:      "nothing",
+			eventType: "other",
+			entityID:  "switch.fan",
+			want:      []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := model.HassEvent{Instance: tt.instance}
+			evt.Event.EventType = tt.eventType
+			evt.Event.Data.EntityID = tt.entityID
+
+			got := make([]string, 0)
+			for _, trigger := range idx.Match(&evt) {
+				got = append(got, trigger.GetActionable().GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
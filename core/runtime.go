@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nmaupu/gotomation/httpclient"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+	"github.com/nmaupu/gotomation/templating"
+	"github.com/nmaupu/gotomation/thirdparty"
+)
+
+var (
+	_ templating.Env = (*Runtime)(nil)
+)
+
+// HassInstance holds the clients of a Home Assistant instance
+type HassInstance struct {
+	SimpleClient    httpclient.SimpleClient
+	WebSocketClient httpclient.WebSocketClient
+}
+
+// Runtime holds the dependencies shared by modules, actions and crons
+// It is built by smarthome.Init and bound to every Modular and Actionable
+type Runtime struct {
+	// SimpleClient reads states and calls services on the default Home Assistant instance
+	SimpleClient httpclient.SimpleClient
+	// WebSocketClient is the connection to the default instance's WebSocket API, nil if disabled
+	WebSocketClient httpclient.WebSocketClient
+	// Instances are the named Home Assistant instances by name
+	Instances map[string]HassInstance
+	// Coordinates are the coordinates of the home zone, nil if Home Assistant is disabled
+	Coordinates Coordinates
+	// GoogleConfig gives access to the Google API, nil if not configured
+	GoogleConfig thirdparty.GoogleConfig
+	// Senders are the configured senders by name
+	Senders map[string]messaging.Sender
+	// Checkers returns all checkers corresponding to a given module name
+	Checkers func(name string) []Checkable
+	// Automates returns the checkers' modules and the triggers' actions corresponding to a given name
+	Automates func(name string) []Automate
+}
+
+// GetSender returns a Sender given its name, nil if it does not exist
+func (r *Runtime) GetSender(name string) messaging.Sender {
+	return r.Senders[name]
+}
+
+// GetCheckers returns all checkers corresponding to a given module name
+func (r *Runtime) GetCheckers(name string) []Checkable {
+	if r.Checkers == nil {
+		return nil
+	}
+	return r.Checkers(name)
+}
+
+// GetAutomates returns the checkers' modules and the triggers' actions corresponding to a given name
+func (r *Runtime) GetAutomates(name string) []Automate {
+	if r.Automates == nil {
+		return nil
+	}
+	return r.Automates(name)
+}
+
+// GetInstance returns the clients of the Home Assistant instance called name, the default one if name is empty
+func (r *Runtime) GetInstance(name string) (HassInstance, bool) {
+	if name == "" {
+		return HassInstance{SimpleClient: r.SimpleClient, WebSocketClient: r.WebSocketClient}, r.SimpleClient != nil
+	}
+	instance, ok := r.Instances[name]
+	return instance, ok
+}
+
+// Client returns the SimpleClient of the Home Assistant instance entity belongs to
+// If this instance is not configured, the returned client fails every call
+func (r *Runtime) Client(entity model.HassEntity) httpclient.SimpleClient {
+	instance, ok := r.GetInstance(entity.Instance)
+	if !ok || instance.SimpleClient == nil {
+		return httpclient.NewUnknownInstanceClient(entity.Instance)
+	}
+	return instance.SimpleClient
+}
+
+// GetEntity returns entity with its current state from the Home Assistant instance it belongs to
+func (r *Runtime) GetEntity(entity model.HassEntity) (model.HassEntity, error) {
+	return r.Client(entity).GetEntity(entity.Domain, entity.EntityID)
+}
+
+// GetSunriseSunset returns today's sunrise and sunset at the home zone
+func (r *Runtime) GetSunriseSunset() (time.Time, time.Time, error) {
+	if r.Coordinates == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("coordinates are not available")
+	}
+	return r.Coordinates.GetSunriseSunset()
+}
+
+// GetSunPosition returns the sun's elevation and azimuth in degrees at t at the home zone
+func (r *Runtime) GetSunPosition(t time.Time) (float64, float64, error) {
+	if r.Coordinates == nil {
+		return 0, 0, fmt.Errorf("coordinates are not available")
+	}
+	elevation, azimuth := r.Coordinates.GetSunPosition(t)
+	return elevation, azimuth, nil
+}
+
+// IsDark returns true if the sun is below elevation (in degrees) at the home zone, false if coordinates are not available
+func (r *Runtime) IsDark(elevation float64) bool {
+	return r.Coordinates != nil && r.Coordinates.IsDark(elevation)
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+	"github.com/nmaupu/gotomation/templating"
+)
+
+// Step is an action run by crons and rules
+// It calls a service on its entities, enables or disables checkers and triggers and sends a message,
+// then waits for Delay before the next step runs.
+type Step struct {
+	// Entity to call Service on
+	Entity model.HassEntity `mapstructure:"entity"`
+	// Entities to call Service on, in addition to Entity
+	Entities []model.HassEntity `mapstructure:"entities"`
+	// Service to call, such as turn_on, the domain is the one of each entity
+	Service string `mapstructure:"service"`
+	// Data are the service's extra parameters, such as brightness or temperature
+	Data map[string]any `mapstructure:"data"`
+	// Enable are the names of the checkers and triggers to enable
+	Enable []string `mapstructure:"enable"`
+	// Disable are the names of the checkers and triggers to disable
+	Disable []string `mapstructure:"disable"`
+	// Sender to send Message with
+	Sender string `mapstructure:"sender"`
+	// Message is a template executed with the triggering event if any
+	Message templating.Template `mapstructure:"message"`
+	// Delay to wait before running the next step
+	Delay time.Duration `mapstructure:"delay"`
+}
+
+// GetEntities returns all the entities Service is called on
+func (s Step) GetEntities() []model.HassEntity {
+	entities := make([]model.HassEntity, 0, len(s.Entities)+1)
+	if s.Entity.EntityID != "" {
+		entities = append(entities, s.Entity)
+	}
+	return append(entities, s.Entities...)
+}
+
+// ValidateSteps checks that steps are usable, errors are prefixed with name and the step's index
+func ValidateSteps(runtime *Runtime, name string, steps []Step) []error {
+	var errs []error
+	for i, step := range steps {
+		if step.Service == "" && step.Sender == "" && step.Delay <= 0 && len(step.Enable) == 0 && len(step.Disable) == 0 {
+			errs = append(errs, fmt.Errorf("%s[%d]: service, enable, disable, sender or delay has to be set", name, i))
+		}
+		if step.Service != "" && len(step.GetEntities()) == 0 {
+			errs = append(errs, fmt.Errorf("%s[%d]: entity is not set", name, i))
+		}
+		if step.Service == "" && len(step.GetEntities()) > 0 {
+			errs = append(errs, fmt.Errorf("%s[%d]: service is not set", name, i))
+		}
+		if step.Sender != "" && runtime.GetSender(step.Sender) == nil {
+			errs = append(errs, fmt.Errorf("%s[%d].sender: %q is not configured", name, i, step.Sender))
+		}
+	}
+	return errs
+}
+
+// RunSteps runs steps one after the other until they are done or cancel is closed
+// event is the event which triggered the steps, nil if none. It returns false if steps have been cancelled.
+func RunSteps(runtime *Runtime, steps []Step, event *model.HassEvent, cancel <-chan struct{}) bool {
+	l := logging.NewLogger("RunSteps")
+
+	for i, step := range steps {
+		stepLogger := l.With().Int("step", i).Logger()
+
+		if step.Service != "" {
+			for _, entity := range step.GetEntities() {
+				stepLogger.Debug().
+					Object("entity", entity).
+					Str("service", step.Service).
+					Msg("Calling service")
+				data := make(map[string]any, len(step.Data))
+				for k, v := range step.Data {
+					data[k] = v
+				}
+				if err := runtime.Client(entity).CallService(entity, step.Service, data); err != nil {
+					stepLogger.Error().Err(err).Object("entity", entity).Msg("An error occurred calling service")
+				}
+			}
+		}
+
+		setAutomatesEnabled(runtime, step.Enable, true)
+		setAutomatesEnabled(runtime, step.Disable, false)
+
+		if step.Sender != "" {
+			if err := step.send(runtime, event); err != nil {
+				stepLogger.Error().Err(err).Str("sender", step.Sender).Msg("Unable to send message")
+			}
+		}
+
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-cancel:
+				stepLogger.Debug().Msg("Cancelled, remaining steps are skipped")
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// send sends the message of step to its sender
+func (s Step) send(runtime *Runtime, event *model.HassEvent) error {
+	sender := runtime.GetSender(s.Sender)
+	if sender == nil {
+		return fmt.Errorf("sender does not exist")
+	}
+
+	data := struct {
+		Event    model.HassEventData
+		Instance string
+	}{}
+	if event != nil {
+		data.Event = event.Event.Data
+		data.Instance = event.Instance
+	}
+	msg, err := s.Message.Execute(runtime, data)
+	if err != nil {
+		return err
+	}
+
+	return sender.Send(messaging.Message{Content: msg}, event)
+}
+
+// setAutomatesEnabled enables or disables the checkers and triggers called names
+func setAutomatesEnabled(runtime *Runtime, names []string, enabled bool) {
+	l := logging.NewLogger("setAutomatesEnabled").With().Bool("enabled", enabled).Logger()
+
+	for _, name := range names {
+		automates := runtime.GetAutomates(name)
+		if len(automates) == 0 {
+			l.Warn().Str("name", name).Msg("No checker nor trigger found")
+			continue
+		}
+		for _, automate := range automates {
+			l.Info().Str("name", name).Msg("Setting checker or trigger state")
+			if enabled {
+				automate.Enable()
+			} else {
+				automate.Disable()
+			}
+		}
+	}
+}
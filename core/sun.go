@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// SunriseElevation is the sun's elevation in degrees at sunrise and sunset, taking refraction into account
+	SunriseElevation = -0.833
+	// CivilTwilightElevation is the sun's elevation in degrees at dawn and dusk
+	CivilTwilightElevation = -6.0
+	// NauticalTwilightElevation is the sun's elevation in degrees at nautical dawn and dusk
+	NauticalTwilightElevation = -12.0
+	// AstronomicalTwilightElevation is the sun's elevation in degrees at astronomical dawn and dusk
+	AstronomicalTwilightElevation = -18.0
+	// DarkElevation is the sun's elevation in degrees below which it is considered dark outside
+	DarkElevation = -4.0
+
+	// j2000 is the Julian day of 2000-01-01 12:00 UTC
+	j2000 = 2451545.0
+	// unixEpochJulianDay is the Julian day of 1970-01-01 00:00 UTC
+	unixEpochJulianDay = 2440587.5
+	// earthObliquity is the tilt of Earth's axis in degrees
+	earthObliquity = 23.4397
+)
+
+// ErrSunDoesNotCross is returned when the sun does not cross the requested elevation on a given day (polar day or night)
+var ErrSunDoesNotCross = fmt.Errorf("sun does not cross this elevation on this day")
+
+// SunTimes returns when the sun goes above elevation in the morning and below elevation in the evening
+// on date's day, date's location being used for both the day and the returned times
+// Latitude and longitude are in degrees, longitude being positive east of Greenwich.
+func SunTimes(latitude, longitude float64, date time.Time, elevation float64) (time.Time, time.Time, error) {
+	transit, declination := solarTransit(longitude, date)
+
+	lat := deg2rad(latitude)
+	cosHourAngle := (math.Sin(deg2rad(elevation)) - math.Sin(lat)*math.Sin(declination)) /
+		(math.Cos(lat) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, ErrSunDoesNotCross
+	}
+	hourAngle := rad2deg(math.Acos(cosHourAngle)) / 360
+
+	loc := date.Location()
+	return julianDayToTime(transit - hourAngle).In(loc), julianDayToTime(transit + hourAngle).In(loc), nil
+}
+
+// SunInfo gives the sun's events of a day, an event is the zero time if the sun does not cross its elevation that day
+type SunInfo struct {
+	SolarNoon        time.Time
+	Sunrise          time.Time
+	Sunset           time.Time
+	CivilDawn        time.Time
+	CivilDusk        time.Time
+	NauticalDawn     time.Time
+	NauticalDusk     time.Time
+	AstronomicalDawn time.Time
+	AstronomicalDusk time.Time
+}
+
+// GetSunInfo returns the sun's events on date's day, times are in date's location
+func GetSunInfo(latitude, longitude float64, date time.Time) SunInfo {
+	transit, _ := solarTransit(longitude, date)
+	info := SunInfo{SolarNoon: julianDayToTime(transit).In(date.Location())}
+	// Errors are ignored on purpose, times stay zero
+	info.Sunrise, info.Sunset, _ = SunTimes(latitude, longitude, date, SunriseElevation)
+	info.CivilDawn, info.CivilDusk, _ = SunTimes(latitude, longitude, date, CivilTwilightElevation)
+	info.NauticalDawn, info.NauticalDusk, _ = SunTimes(latitude, longitude, date, NauticalTwilightElevation)
+	info.AstronomicalDawn, info.AstronomicalDusk, _ = SunTimes(latitude, longitude, date, AstronomicalTwilightElevation)
+	return info
+}
+
+// SunPosition returns the sun's elevation and azimuth in degrees at t
+// Elevation is geometric (refraction is not taken into account), azimuth is measured clockwise from the north.
+func SunPosition(latitude, longitude float64, t time.Time) (float64, float64) {
+	d := timeToJulianDay(t) - j2000
+	_, eclipticLongitude := sunAnomalyAndLongitude(d)
+	obliquity := deg2rad(earthObliquity)
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(obliquity))
+	rightAscension := math.Atan2(math.Sin(eclipticLongitude)*math.Cos(obliquity), math.Cos(eclipticLongitude))
+
+	siderealTime := deg2rad(280.16 + 360.9856235*d + longitude)
+	hourAngle := siderealTime - rightAscension
+	lat := deg2rad(latitude)
+
+	elevation := math.Asin(math.Sin(lat)*math.Sin(declination) + math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle))
+	// Azimuth from the south, turned to be from the north
+	azimuth := math.Atan2(math.Sin(hourAngle), math.Cos(hourAngle)*math.Sin(lat)-math.Tan(declination)*math.Cos(lat))
+	return rad2deg(elevation), math.Mod(rad2deg(azimuth)+180, 360)
+}
+
+// sunAnomalyAndLongitude returns the sun's mean anomaly and ecliptic longitude in radians d days after J2000
+func sunAnomalyAndLongitude(d float64) (float64, float64) {
+	meanAnomaly := deg2rad(math.Mod(357.5291+0.98560028*d, 360))
+	center := 1.9148*math.Sin(meanAnomaly) + 0.0200*math.Sin(2*meanAnomaly) + 0.0003*math.Sin(3*meanAnomaly)
+	eclipticLongitude := deg2rad(math.Mod(rad2deg(meanAnomaly)+center+180+102.9372, 360))
+	return meanAnomaly, eclipticLongitude
+}
+
+// solarTransit returns the Julian day of the solar noon of date's day at longitude and the sun's declination in radians
+// See https://en.wikipedia.org/wiki/Sunrise_equation
+func solarTransit(longitude float64, date time.Time) (float64, float64) {
+	// Days since J2000 of date's calendar day
+	day := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	n := math.Round(timeToJulianDay(day) - j2000)
+
+	meanSolarTime := n - longitude/360
+	meanAnomaly, eclipticLongitude := sunAnomalyAndLongitude(meanSolarTime)
+	transit := j2000 + meanSolarTime + 0.0053*math.Sin(meanAnomaly) - 0.0069*math.Sin(2*eclipticLongitude)
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(deg2rad(earthObliquity)))
+
+	return transit, declination
+}
+
+func timeToJulianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + unixEpochJulianDay
+}
+
+func julianDayToTime(jd float64) time.Time {
+	return time.Unix(0, int64((jd-unixEpochJulianDay)*86400*float64(time.Second))).UTC()
+}
+
+func deg2rad(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func rad2deg(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// SunEventSunrise is the time when the sun rises
+	SunEventSunrise = "sunrise"
+	// SunEventSunset is the time when the sun sets
+	SunEventSunset = "sunset"
+	// SunEventDawn is the beginning of the civil twilight in the morning
+	SunEventDawn = "dawn"
+	// SunEventDusk is the end of the civil twilight in the evening
+	SunEventDusk = "dusk"
+
+	// maxSunScheduleDays is the number of days to look for the next sun event, the sun may not rise for months near the poles
+	maxSunScheduleDays = 366
+)
+
+var (
+	_ cron.Schedule = (*SunSchedule)(nil)
+)
+
+// SunSchedule is a cron schedule firing every day at a time relative to a sun event
+type SunSchedule struct {
+	// Event is one of sunrise, sunset, dawn or dusk
+	Event string
+	// Offset is added to the event's time
+	Offset time.Duration
+	// Coordinates are used to compute the event's time, the schedule never fires if nil
+	Coordinates Coordinates
+}
+
+// ParseSchedule parses a cron expression or a sun expression such as @sunset, @sunset -30m or @sunrise+15m
+// Sun expressions use coords to compute their next fire time.
+func ParseSchedule(expr string, coords Coordinates) (cron.Schedule, error) {
+	schedule, ok, err := parseSunSchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return cron.ParseStandard(expr)
+	}
+	schedule.Coordinates = coords
+	return schedule, nil
+}
+
+// parseSunSchedule parses a sun expression, ok is false if expr is not a sun expression
+func parseSunSchedule(expr string) (*SunSchedule, bool, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "@") {
+		return nil, false, nil
+	}
+
+	event := strings.ToLower(expr[1:])
+	offset := ""
+	if i := strings.IndexAny(event, "+-"); i >= 0 {
+		event, offset = event[:i], strings.ReplaceAll(event[i:], " ", "")
+	}
+	event = strings.TrimSpace(event)
+
+	switch event {
+	case SunEventSunrise, SunEventSunset, SunEventDawn, SunEventDusk:
+	default:
+		return nil, false, nil // maybe a cron descriptor such as @daily
+	}
+
+	schedule := &SunSchedule{Event: event}
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid offset %q for @%s: %w", offset, event, err)
+		}
+		schedule.Offset = d
+	}
+	return schedule, true, nil
+}
+
+// Next returns the next time the schedule fires after t, the zero time if it cannot be computed
+func (s *SunSchedule) Next(t time.Time) time.Time {
+	l := logging.NewLogger("SunSchedule.Next")
+	if s.Coordinates == nil {
+		l.Error().Str("event", s.Event).Msg("Coordinates are not available, cannot compute next sun event")
+		return time.Time{}
+	}
+
+	// Days are the ones of the coordinates' time zone
+	t = t.In(s.Coordinates.GetLocation())
+	// Starting the day before in case a negative offset brings tomorrow's event today
+	day := time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, t.Location()).AddDate(0, 0, -1)
+	for i := 0; i < maxSunScheduleDays; i++ {
+		eventTime, err := s.eventTime(day.AddDate(0, 0, i))
+		if err != nil {
+			continue
+		}
+		if next := eventTime.Add(s.Offset).Truncate(time.Second); next.After(t) {
+			return next
+		}
+	}
+
+	l.Error().Str("event", s.Event).Msg("No sun event found in the coming year")
+	return time.Time{}
+}
+
+// eventTime returns the time of the event on day's day
+func (s *SunSchedule) eventTime(day time.Time) (time.Time, error) {
+	elevation := SunriseElevation
+	if s.Event == SunEventDawn || s.Event == SunEventDusk {
+		elevation = CivilTwilightElevation
+	}
+
+	morning, evening, err := s.Coordinates.GetSunTimes(day, elevation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if s.Event == SunEventSunrise || s.Event == SunEventDawn {
+		return morning, nil
+	}
+	return evening, nil
+}
+
+// String godoc
+func (s *SunSchedule) String() string {
+	if s.Offset == 0 {
+		return "@" + s.Event
+	}
+	sign := "+"
+	if s.Offset < 0 {
+		sign = ""
+	}
+	return fmt.Sprintf("@%s %s%s", s.Event, sign, s.Offset)
+}
@@ -0,0 +1,253 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("unable to load location %s, err=%v", name, err)
+	}
+	return loc
+}
+
+func TestSunTimes(t *testing.T) {
+	paris := mustLoadLocation(t, "Europe/Paris")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name        string
+		latitude    float64
+		longitude   float64
+		date        time.Time
+		elevation   float64
+		wantMorning time.Time
+		wantEvening time.Time
+		wantErr     bool
+	}{
+		{
+			name:        "paris_summer_sunrise_sunset",
+			latitude:    48.8566,
+			longitude:   2.3522,
+			date:        time.Date(2024, 6, 21, 0, 0, 0, 0, paris),
+			elevation:   SunriseElevation,
+			wantMorning: time.Date(2024, 6, 21, 5, 47, 0, 0, paris),
+			wantEvening: time.Date(2024, 6, 21, 21, 58, 0, 0, paris),
+		},
+		{
+			name:        "paris_summer_dawn_dusk",
+			latitude:    48.8566,
+			longitude:   2.3522,
+			date:        time.Date(2024, 6, 21, 15, 0, 0, 0, paris),
+			elevation:   CivilTwilightElevation,
+			wantMorning: time.Date(2024, 6, 21, 5, 2, 0, 0, paris),
+			wantEvening: time.Date(2024, 6, 21, 22, 43, 0, 0, paris),
+		},
+		{
+			name:        "new_york_dst_day",
+			latitude:    40.7128,
+			longitude:   -74.0060,
+			date:        time.Date(2024, 3, 10, 12, 0, 0, 0, newYork),
+			elevation:   SunriseElevation,
+			wantMorning: time.Date(2024, 3, 10, 7, 15, 0, 0, newYork),
+			wantEvening: time.Date(2024, 3, 10, 18, 58, 0, 0, newYork),
+		},
+		{
+			name:      "polar_night",
+			latitude:  69.6492,
+			longitude: 18.9553,
+			date:      time.Date(2024, 12, 21, 12, 0, 0, 0, time.UTC),
+			elevation: SunriseElevation,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			morning, evening, err := SunTimes(tt.latitude, tt.longitude, tt.date, tt.elevation)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SunTimes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			// The algorithm is accurate to a couple of minutes
+			if d := morning.Sub(tt.wantMorning).Abs(); d > 3*time.Minute {
+				t.Errorf("SunTimes() morning = %v, want %v", morning, tt.wantMorning)
+			}
+			if d := evening.Sub(tt.wantEvening).Abs(); d > 3*time.Minute {
+				t.Errorf("SunTimes() evening = %v, want %v", evening, tt.wantEvening)
+			}
+		})
+	}
+}
+
+func TestGetSunInfo(t *testing.T) {
+	paris := mustLoadLocation(t, "Europe/Paris")
+
+	tests := []struct {
+		name string
+		date time.Time
+		want SunInfo
+	}{
+		{
+			name: "paris_summer",
+			date: time.Date(2024, 6, 21, 8, 0, 0, 0, paris),
+			want: SunInfo{
+				SolarNoon:    time.Date(2024, 6, 21, 13, 52, 0, 0, paris),
+				Sunrise:      time.Date(2024, 6, 21, 5, 47, 0, 0, paris),
+				Sunset:       time.Date(2024, 6, 21, 21, 58, 0, 0, paris),
+				CivilDawn:    time.Date(2024, 6, 21, 5, 4, 0, 0, paris),
+				CivilDusk:    time.Date(2024, 6, 21, 22, 41, 0, 0, paris),
+				NauticalDawn: time.Date(2024, 6, 21, 4, 3, 0, 0, paris),
+				NauticalDusk: time.Date(2024, 6, 21, 23, 41, 0, 0, paris),
+				// Astronomical night never comes in Paris around the summer solstice
+			},
+		},
+		{
+			name: "paris_winter",
+			date: time.Date(2024, 12, 21, 20, 0, 0, 0, paris),
+			want: SunInfo{
+				SolarNoon:        time.Date(2024, 12, 21, 12, 49, 0, 0, paris),
+				Sunrise:          time.Date(2024, 12, 21, 8, 41, 0, 0, paris),
+				Sunset:           time.Date(2024, 12, 21, 16, 56, 0, 0, paris),
+				CivilDawn:        time.Date(2024, 12, 21, 8, 3, 0, 0, paris),
+				CivilDusk:        time.Date(2024, 12, 21, 17, 34, 0, 0, paris),
+				NauticalDawn:     time.Date(2024, 12, 21, 7, 22, 0, 0, paris),
+				NauticalDusk:     time.Date(2024, 12, 21, 18, 14, 0, 0, paris),
+				AstronomicalDawn: time.Date(2024, 12, 21, 6, 45, 0, 0, paris),
+				AstronomicalDusk: time.Date(2024, 12, 21, 18, 52, 0, 0, paris),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetSunInfo(48.8566, 2.3522, tt.date)
+			gotTimes := []time.Time{got.SolarNoon, got.Sunrise, got.Sunset, got.CivilDawn, got.CivilDusk,
+				got.NauticalDawn, got.NauticalDusk, got.AstronomicalDawn, got.AstronomicalDusk}
+			wantTimes := []time.Time{tt.want.SolarNoon, tt.want.Sunrise, tt.want.Sunset, tt.want.CivilDawn, tt.want.CivilDusk,
+				tt.want.NauticalDawn, tt.want.NauticalDusk, tt.want.AstronomicalDawn, tt.want.AstronomicalDusk}
+			for i := range gotTimes {
+				if gotTimes[i].IsZero() != wantTimes[i].IsZero() || gotTimes[i].Sub(wantTimes[i]).Abs() > 3*time.Minute {
+					t.Errorf("GetSunInfo() = %+v, want %+v", got, tt.want)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestSunPosition(t *testing.T) {
+	paris := mustLoadLocation(t, "Europe/Paris")
+
+	tests := []struct {
+		name          string
+		t             time.Time
+		wantElevation float64
+		wantAzimuth   float64
+	}{
+		{
+			name:          "solar_noon",
+			t:             time.Date(2024, 6, 21, 13, 52, 0, 0, paris),
+			wantElevation: 64.6,
+			wantAzimuth:   180,
+		},
+		{
+			name:          "solar_midnight",
+			t:             time.Date(2024, 12, 22, 0, 49, 0, 0, paris),
+			wantElevation: -64.6,
+			wantAzimuth:   0,
+		},
+		{
+			name:          "sunset",
+			t:             time.Date(2024, 6, 21, 21, 58, 0, 0, paris),
+			wantElevation: -0.8,
+			wantAzimuth:   308,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elevation, azimuth := SunPosition(48.8566, 2.3522, tt.t)
+			if math.Abs(elevation-tt.wantElevation) > 1 {
+				t.Errorf("SunPosition() elevation = %v, want %v", elevation, tt.wantElevation)
+			}
+			// Azimuth is circular, 359 is close to 0
+			if d := math.Abs(azimuth - tt.wantAzimuth); math.Min(d, 360-d) > 2 {
+				t.Errorf("SunPosition() azimuth = %v, want %v", azimuth, tt.wantAzimuth)
+			}
+		})
+	}
+}
+
+func TestSunSchedule_Next(t *testing.T) {
+	paris := mustLoadLocation(t, "Europe/Paris")
+	coords := NewCoordinates(48.8566, 2.3522)
+
+	tests := []struct {
+		name    string
+		expr    string
+		now     time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "sunset_offset",
+			expr: "@sunset -30m",
+			now:  time.Date(2024, 6, 21, 12, 0, 0, 0, paris),
+			want: time.Date(2024, 6, 21, 21, 28, 0, 0, paris),
+		},
+		{
+			name: "sunrise_tomorrow",
+			expr: "@sunrise+15m",
+			now:  time.Date(2024, 6, 21, 12, 0, 0, 0, paris),
+			want: time.Date(2024, 6, 22, 6, 2, 0, 0, paris),
+		},
+		{
+			name: "dusk",
+			expr: "@Dusk",
+			now:  time.Date(2024, 6, 21, 12, 0, 0, 0, paris),
+			want: time.Date(2024, 6, 21, 22, 43, 0, 0, paris),
+		},
+		{
+			name: "sunrise_after_dst_change",
+			expr: "@sunrise",
+			now:  time.Date(2024, 3, 30, 22, 0, 0, 0, paris),
+			want: time.Date(2024, 3, 31, 7, 29, 0, 0, paris),
+		},
+		{
+			name: "negative_offset_next_day_event",
+			expr: "@sunrise -7h",
+			now:  time.Date(2024, 6, 21, 22, 0, 0, 0, paris),
+			want: time.Date(2024, 6, 21, 22, 47, 0, 0, paris),
+		},
+		{
+			name:    "invalid_offset",
+			expr:    "@sunset -30x",
+			wantErr: true,
+		},
+		{
+			name: "cron_descriptor",
+			expr: "@daily",
+			now:  time.Date(2024, 6, 21, 12, 0, 0, 0, paris),
+			want: time.Date(2024, 6, 22, 0, 0, 0, 0, paris),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr, coords)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := schedule.Next(tt.now); got.Sub(tt.want).Abs() > 3*time.Minute {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/model/config"
 )
 
@@ -13,7 +14,8 @@ var (
 
 // Trigger triggers an action when a change occurs
 type Trigger struct {
-	Action Actionable
+	Action     Actionable
+	dispatcher dispatcher
 }
 
 // Configure godoc
@@ -30,6 +32,9 @@ func (t *Trigger) Configure(data interface{}, action interface{}) error {
 	if err != nil {
 		return err
 	}
+	if err := t.Action.GetDispatchOptions().Validate(); err != nil {
+		return err
+	}
 
 	l.Trace().Msgf("%+v", action)
 
@@ -45,3 +50,35 @@ func (t *Trigger) GetActionable() Actionable {
 func (t *Trigger) GetName() string {
 	return fmt.Sprintf("trigger/%s", t.Action.GetName())
 }
+
+// Dispatch godoc
+func (t *Trigger) Dispatch(e *model.HassEvent, requeue func(e *model.HassEvent)) {
+	opts := t.Action.GetDispatchOptions()
+	if opts.IsZero() {
+		t.Action.Trigger(e)
+		return
+	}
+
+	t.dispatcher.dispatch(opts, e, t.trigger, requeue)
+}
+
+// Fire godoc
+func (t *Trigger) Fire(e *model.HassEvent) {
+	t.dispatcher.fire(t.Action.GetDispatchOptions(), e, t.trigger)
+}
+
+// trigger calls the Action's Trigger with e if it is still enabled
+func (t *Trigger) trigger(e *model.HassEvent) {
+	// Action may have been disabled while the event was pending
+	if t.Action.IsEnabled() {
+		t.Action.Trigger(e)
+	}
+}
+
+// Release godoc
+func (t *Trigger) Release() {
+	t.dispatcher.release()
+	if releasable, ok := t.Action.(Releasable); ok {
+		releasable.Release()
+	}
+}
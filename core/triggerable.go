@@ -1,8 +1,17 @@
 package core
 
+import "github.com/nmaupu/gotomation/model"
+
 // Triggerable is an interface to trigger an action when a change is detected
 type Triggerable interface {
 	Configurable
 	GetActionable() Actionable
 	GetName() string
+	// Dispatch triggers the Actionable with e according to its DispatchOptions
+	// A delayed event is given to requeue once its delay has elapsed, to be passed to Fire by a worker.
+	Dispatch(e *model.HassEvent, requeue func(e *model.HassEvent))
+	// Fire triggers the Actionable with e whose dispatch delay has elapsed
+	Fire(e *model.HassEvent)
+	// Release cancels pending events and releases the Actionable if it is Releasable
+	Release()
 }
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+const (
+	// DefaultWindowClosedFor is how long windows have to be closed before resuming schedules when not set
+	DefaultWindowClosedFor = 5 * time.Minute
+	// DefaultTemperatureDropWithin is the duration a temperature drop is looked for when not set
+	DefaultTemperatureDropWithin = 10 * time.Minute
+)
+
+// TemperatureDrop detects an open window when the temperature drops by Degrees within Within
+type TemperatureDrop struct {
+	// Degrees is the drop detecting an open window, detection is disabled if not set
+	Degrees float64 `mapstructure:"degrees"`
+	// Within is the duration the drop is looked for, DefaultTemperatureDropWithin if not set
+	Within time.Duration `mapstructure:"within"`
+}
+
+// WindowState tells if schedules are suspended because a window is open
+type WindowState struct {
+	Suspended bool
+	// Reason is why schedules are suspended
+	Reason string
+	// Since is when schedules have been suspended
+	Since time.Time
+	// LastOpen is the last time a window has been detected open
+	LastOpen time.Time
+}
+
+// temperatureSample is a temperature measured at a given time
+type temperatureSample struct {
+	time        time.Time
+	temperature float64
+}
+
+// WindowDetector suspends a heater's schedules while a window is open
+// A window is open when one of the window sensors is on or when the temperature drops sharply.
+// Schedules are resumed once no window has been detected open for a while.
+type WindowDetector struct {
+	mutex   sync.Mutex
+	state   WindowState
+	samples []temperatureSample
+}
+
+// GetState returns whether schedules are suspended and why
+func (d *WindowDetector) GetState() WindowState {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.state
+}
+
+// Update detects open windows at now from the window sensors being open and the current temperature if known
+// It returns the updated state.
+func (d *WindowDetector) Update(now time.Time, schedules *HeaterSchedules, openSensors []model.HassEntity, temperature *float64) WindowState {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	reason := ""
+	if len(openSensors) > 0 {
+		reason = fmt.Sprintf("window %s is open", openSensors[0].GetEntityIDFullName())
+	}
+	if drop := d.temperatureDrop(now, schedules.TemperatureDrop, temperature); reason == "" && drop > 0 {
+		reason = fmt.Sprintf("temperature dropped by %.1f° within %s", drop, schedules.TemperatureDrop.getWithin())
+	}
+
+	if reason != "" {
+		if !d.state.Suspended {
+			d.state.Since = now
+		}
+		d.state.Suspended = true
+		d.state.Reason = reason
+		d.state.LastOpen = now
+		return d.state
+	}
+
+	if d.state.Suspended && now.Sub(d.state.LastOpen) >= schedules.getWindowClosedFor() {
+		d.state = WindowState{LastOpen: d.state.LastOpen}
+		// Forgetting the temperatures measured while the window was open
+		d.samples = nil
+	}
+	return d.state
+}
+
+// temperatureDrop records temperature and returns the drop detected within drop's duration, 0 if none
+func (d *WindowDetector) temperatureDrop(now time.Time, drop TemperatureDrop, temperature *float64) float64 {
+	if drop.Degrees <= 0 || temperature == nil {
+		return 0
+	}
+
+	kept := d.samples[:0]
+	for _, sample := range d.samples {
+		if now.Sub(sample.time) <= drop.getWithin() {
+			kept = append(kept, sample)
+		}
+	}
+	d.samples = append(kept, temperatureSample{time: now, temperature: *temperature})
+
+	highest := *temperature
+	for _, sample := range d.samples {
+		if sample.temperature > highest {
+			highest = sample.temperature
+		}
+	}
+	if highest-*temperature >= drop.Degrees {
+		return highest - *temperature
+	}
+	return 0
+}
+
+func (t TemperatureDrop) getWithin() time.Duration {
+	if t.Within > 0 {
+		return t.Within
+	}
+	return DefaultTemperatureDropWithin
+}
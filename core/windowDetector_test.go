@@ -0,0 +1,123 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+func TestWindowDetector_Update(t *testing.T) {
+	start := time.Date(2026, time.January, 12, 10, 0, 0, 0, time.UTC)
+	window := model.NewHassEntity("binary_sensor.living_window")
+
+	// observation is what is seen at start+at
+	type observation struct {
+		at          time.Duration
+		open        bool
+		temperature float64
+	}
+
+	tests := []struct {
+		name         string
+		schedules    HeaterSchedules
+		observations []observation
+		want         bool
+		wantReason   string
+		wantSince    time.Duration
+	}{
+		{
+			name:         "closed",
+			schedules:    HeaterSchedules{WindowSensors: []model.HassEntity{window}},
+			observations: []observation{{at: 0, temperature: 20}},
+		},
+		{
+			name:         "open",
+			schedules:    HeaterSchedules{WindowSensors: []model.HassEntity{window}},
+			observations: []observation{{at: 0, temperature: 20}, {at: time.Minute, open: true, temperature: 20}},
+			want:         true,
+			wantReason:   "window binary_sensor.living_window is open",
+			wantSince:    time.Minute,
+		},
+		{
+			name:      "closed_not_long_enough",
+			schedules: HeaterSchedules{WindowSensors: []model.HassEntity{window}, WindowClosedFor: 10 * time.Minute},
+			observations: []observation{
+				{at: 0, open: true, temperature: 20},
+				{at: 5 * time.Minute, open: true, temperature: 20},
+				{at: 6 * time.Minute, temperature: 20},
+				{at: 10 * time.Minute, temperature: 20},
+			},
+			want:       true,
+			wantReason: "window binary_sensor.living_window is open",
+		},
+		{
+			name:      "closed_long_enough",
+			schedules: HeaterSchedules{WindowSensors: []model.HassEntity{window}, WindowClosedFor: 4 * time.Minute},
+			observations: []observation{
+				{at: 0, open: true, temperature: 20},
+				{at: 5 * time.Minute, open: true, temperature: 20},
+				{at: 6 * time.Minute, temperature: 20},
+				{at: 10 * time.Minute, temperature: 20},
+			},
+		},
+		{
+			name:      "temperature_drop",
+			schedules: HeaterSchedules{TemperatureDrop: TemperatureDrop{Degrees: 1, Within: 10 * time.Minute}},
+			observations: []observation{
+				{at: 0, temperature: 20},
+				{at: 5 * time.Minute, temperature: 19.5},
+				{at: 10 * time.Minute, temperature: 18.8},
+			},
+			want:       true,
+			wantReason: "temperature dropped by 1.2° within 10m0s",
+			wantSince:  10 * time.Minute,
+		},
+		{
+			name:      "temperature_slow_drop",
+			schedules: HeaterSchedules{TemperatureDrop: TemperatureDrop{Degrees: 1, Within: 10 * time.Minute}},
+			observations: []observation{
+				{at: 0, temperature: 20},
+				{at: 10 * time.Minute, temperature: 19.5},
+				{at: 20 * time.Minute, temperature: 19},
+			},
+		},
+		{
+			name:      "temperature_stable_again",
+			schedules: HeaterSchedules{TemperatureDrop: TemperatureDrop{Degrees: 1, Within: 10 * time.Minute}, WindowClosedFor: 10 * time.Minute},
+			observations: []observation{
+				{at: 0, temperature: 20},
+				{at: 5 * time.Minute, temperature: 18.5},
+				{at: 20 * time.Minute, temperature: 18.5},
+				{at: 25 * time.Minute, temperature: 18.6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := new(WindowDetector)
+			var got WindowState
+			for _, o := range tt.observations {
+				var open []model.HassEntity
+				if o.open {
+					open = append(open, window)
+				}
+				temperature := o.temperature
+				got = d.Update(start.Add(o.at), &tt.schedules, open, &temperature)
+			}
+
+			if got.Suspended != tt.want {
+				t.Errorf("Suspended = %v, want %v", got.Suspended, tt.want)
+			}
+			if got.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", got.Reason, tt.wantReason)
+			}
+			if tt.want && tt.wantSince > 0 && !got.Since.Equal(start.Add(tt.wantSince)) {
+				t.Errorf("Since = %v, want %v", got.Since, start.Add(tt.wantSince))
+			}
+			if got != d.GetState() {
+				t.Errorf("GetState() = %+v, want %+v", d.GetState(), got)
+			}
+		})
+	}
+}
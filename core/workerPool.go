@@ -0,0 +1,288 @@
+package core
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nmaupu/gotomation/app"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/routines"
+)
+
+const (
+	// DefaultWorkers is the number of triggers run at the same time when not configured
+	DefaultWorkers = 4
+	// DefaultQueueDepth is the number of events waiting for a trigger when not configured
+	DefaultQueueDepth = 100
+)
+
+var (
+	_ routines.Runnable = (*WorkerPool)(nil)
+)
+
+// WorkerPoolStats are the counters of a WorkerPool
+type WorkerPoolStats struct {
+	Workers    int `json:"workers"`
+	QueueDepth int `json:"queue_depth"`
+	// Queued is the number of events waiting for their trigger
+	Queued int `json:"queued"`
+	// Processed is the number of events dispatched to their trigger
+	Processed uint64 `json:"processed"`
+	// Dropped is the number of events dropped because their trigger's queue was full
+	Dropped uint64 `json:"dropped"`
+	// DroppedByTrigger is Dropped by trigger's name
+	DroppedByTrigger map[string]uint64 `json:"dropped_by_trigger"`
+}
+
+// queuedEvent is an event waiting for its trigger
+type queuedEvent struct {
+	event model.HassEvent
+	// due is true for an event given back by the trigger once its dispatch delay has elapsed
+	due bool
+}
+
+// eventQueue holds the events waiting for a trigger
+type eventQueue struct {
+	trigger Triggerable
+	events  []queuedEvent
+	// scheduled is true when the queue is waiting for a worker or being processed by one
+	scheduled bool
+}
+
+// WorkerPool dispatches events to triggers with a bounded number of go routines
+// Each trigger has its own queue: its events are dispatched one at a time, in the order they are enqueued.
+type WorkerPool struct {
+	workers    int
+	queueDepth int
+
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	queues map[Triggerable]*eventQueue
+	// ready are the queues having events to dispatch, in the order they got them
+	ready []*eventQueue
+	stats WorkerPoolStats
+
+	started bool
+	stopped bool
+	// running is used by Stop to wait for workers to return
+	running sync.WaitGroup
+	// mutexStopStart serializes Start and Stop
+	mutexStopStart sync.Mutex
+}
+
+// NewWorkerPool returns a new WorkerPool running at most workers triggers at the same time
+// and keeping at most queueDepth events per trigger, defaults are used for zero values
+func NewWorkerPool(workers int, queueDepth int) *WorkerPool {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if queueDepth <= 0 {
+		queueDepth = DefaultQueueDepth
+	}
+	p := &WorkerPool{
+		workers:    workers,
+		queueDepth: queueDepth,
+		queues:     make(map[Triggerable]*eventQueue),
+		stats: WorkerPoolStats{
+			Workers:          workers,
+			QueueDepth:       queueDepth,
+			DroppedByTrigger: make(map[string]uint64),
+		},
+	}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+// Enqueue adds e to trigger's queue, it returns false if the queue is full and e has been dropped
+func (p *WorkerPool) Enqueue(trigger Triggerable, e *model.HassEvent) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	q, ok := p.queues[trigger]
+	if !ok {
+		q = &eventQueue{trigger: trigger}
+		p.queues[trigger] = q
+	}
+	return p.enqueue(q, queuedEvent{event: *e})
+}
+
+// requeue adds e, whose dispatch delay has elapsed, back to trigger's queue
+// e is dropped if trigger has been removed meanwhile.
+func (p *WorkerPool) requeue(trigger Triggerable, e *model.HassEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	q, ok := p.queues[trigger]
+	if !ok {
+		return
+	}
+	p.enqueue(q, queuedEvent{event: *e, due: true})
+}
+
+// enqueue adds evt to q and schedules q if needed, it returns false if q is full and evt has been dropped
+// p.mutex has to be locked by the caller
+func (p *WorkerPool) enqueue(q *eventQueue, evt queuedEvent) bool {
+	l := logging.NewLogger("WorkerPool.enqueue")
+	trigger := q.trigger
+
+	if len(q.events) >= p.queueDepth {
+		p.stats.Dropped++
+		p.stats.DroppedByTrigger[trigger.GetName()]++
+		l.Warn().
+			Str("trigger", trigger.GetName()).
+			Int("queue_depth", p.queueDepth).
+			Msg("Trigger's queue is full, dropping event")
+		return false
+	}
+
+	q.events = append(q.events, evt)
+	p.stats.Queued++
+	if !q.scheduled {
+		q.scheduled = true
+		p.ready = append(p.ready, q)
+		p.cond.Signal()
+	}
+	return true
+}
+
+// Remove drops the events waiting for trigger, the one being dispatched if any is not interrupted
+func (p *WorkerPool) Remove(trigger Triggerable) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	q, ok := p.queues[trigger]
+	if !ok {
+		return
+	}
+	p.stats.Queued -= len(q.events)
+	q.events = nil
+	delete(p.queues, trigger)
+}
+
+// Stats returns a copy of the pool's counters
+func (p *WorkerPool) Stats() WorkerPoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.DroppedByTrigger = make(map[string]uint64, len(p.stats.DroppedByTrigger))
+	for name, dropped := range p.stats.DroppedByTrigger {
+		stats.DroppedByTrigger[name] = dropped
+	}
+	return stats
+}
+
+// GinHandler godoc
+func (p *WorkerPool) GinHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, p.Stats())
+}
+
+// Start starts the workers
+func (p *WorkerPool) Start() error {
+	p.mutexStopStart.Lock()
+	defer p.mutexStopStart.Unlock()
+	if p.started {
+		return nil
+	}
+
+	p.mutex.Lock()
+	p.stopped = false
+	p.mutex.Unlock()
+
+	for i := 0; i < p.workers; i++ {
+		app.RoutinesWG.Add(1)
+		p.running.Add(1)
+		go func() {
+			defer app.RoutinesWG.Done()
+			defer p.running.Done()
+			p.work()
+		}()
+	}
+	p.started = true
+	return nil
+}
+
+// Stop stops the workers once the events being dispatched are done, queued events are kept
+func (p *WorkerPool) Stop() {
+	p.mutexStopStart.Lock()
+	defer p.mutexStopStart.Unlock()
+	if !p.started {
+		return
+	}
+
+	p.mutex.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mutex.Unlock()
+
+	p.running.Wait()
+	p.started = false
+}
+
+// work dispatches events of ready queues until the pool is stopped
+func (p *WorkerPool) work() {
+	l := logging.NewLogger("WorkerPool.work")
+
+	for {
+		p.mutex.Lock()
+		for len(p.ready) == 0 && !p.stopped {
+			p.cond.Wait()
+		}
+		if p.stopped {
+			p.mutex.Unlock()
+			l.Trace().Msg("Worker stopped")
+			return
+		}
+
+		q := p.ready[0]
+		p.ready = p.ready[1:]
+		if len(q.events) == 0 { // removed meanwhile
+			q.scheduled = false
+			p.mutex.Unlock()
+			continue
+		}
+		evt := q.events[0]
+		q.events = q.events[1:]
+		p.stats.Queued--
+		p.mutex.Unlock()
+
+		if evt.due {
+			q.trigger.Fire(&evt.event)
+		} else {
+			trigger := q.trigger
+			q.trigger.Dispatch(&evt.event, func(e *model.HassEvent) {
+				p.requeue(trigger, e)
+			})
+		}
+
+		p.mutex.Lock()
+		p.stats.Processed++
+		if len(q.events) > 0 {
+			// Going back at the end of the line so that a busy trigger does not starve the others
+			p.ready = append(p.ready, q)
+			p.cond.Signal()
+		} else {
+			q.scheduled = false
+		}
+		p.mutex.Unlock()
+	}
+}
+
+// GetName godoc
+func (p *WorkerPool) GetName() string {
+	return "WorkerPool"
+}
+
+// IsStarted godoc
+func (p *WorkerPool) IsStarted() bool {
+	p.mutexStopStart.Lock()
+	defer p.mutexStopStart.Unlock()
+	return p.started
+}
+
+// IsAutoStart godoc
+func (p *WorkerPool) IsAutoStart() bool {
+	return true
+}
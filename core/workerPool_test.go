@@ -0,0 +1,220 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+// recordingTriggerable records the states of the events it is dispatched
+type recordingTriggerable struct {
+	name  string
+	delay time.Duration
+	// requeueAfter delays the events given to Dispatch, they are recorded by Fire
+	requeueAfter time.Duration
+	mutex        sync.Mutex
+	states       []string
+	running      *concurrencyCounter
+}
+
+// concurrencyCounter counts triggers running at the same time
+type concurrencyCounter struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyCounter) add(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current += n
+	if c.current > c.max {
+		c.max = c.current
+	}
+}
+
+func (r *recordingTriggerable) Configure(config interface{}, obj interface{}) error { return nil }
+func (r *recordingTriggerable) GetActionable() Actionable                           { return nil }
+func (r *recordingTriggerable) GetName() string                                     { return r.name }
+func (r *recordingTriggerable) Release()                                            {}
+
+func (r *recordingTriggerable) Dispatch(e *model.HassEvent, requeue func(e *model.HassEvent)) {
+	if r.requeueAfter > 0 {
+		evt := *e
+		time.AfterFunc(r.requeueAfter, func() {
+			requeue(&evt)
+		})
+		return
+	}
+	r.Fire(e)
+}
+
+func (r *recordingTriggerable) Fire(e *model.HassEvent) {
+	if r.running != nil {
+		r.running.add(1)
+		defer r.running.add(-1)
+	}
+	time.Sleep(r.delay)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states = append(r.states, e.Event.Data.NewState.State)
+}
+
+func (r *recordingTriggerable) getStates() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.states...)
+}
+
+func TestWorkerPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     int
+		queueDepth  int
+		triggers    int
+		events      int
+		delay       time.Duration
+		startAfter  bool // enqueuing events before starting the pool
+		wantDropped uint64
+		wantStates  int // number of events dispatched to each trigger
+	}{
+		{
+			name:       "ordered",
+			workers:    4,
+			queueDepth: 100,
+			triggers:   1,
+			events:     50,
+			wantStates: 50,
+		},
+		{
+			name:       "bounded",
+			workers:    2,
+			queueDepth: 100,
+			triggers:   6,
+			events:     3,
+			delay:      5 * time.Millisecond,
+			wantStates: 3,
+		},
+		{
+			name:        "queue_full",
+			workers:     1,
+			queueDepth:  5,
+			triggers:    2,
+			events:      8,
+			startAfter:  true,
+			wantDropped: 6,
+			wantStates:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewWorkerPool(tt.workers, tt.queueDepth)
+			if !tt.startAfter {
+				p.Start()
+			}
+			t.Cleanup(p.Stop)
+
+			running := &concurrencyCounter{}
+			triggers := make([]*recordingTriggerable, 0, tt.triggers)
+			for i := 0; i < tt.triggers; i++ {
+				triggers = append(triggers, &recordingTriggerable{name: fmt.Sprintf("trigger%d", i), delay: tt.delay, running: running})
+			}
+
+			want := make([]string, 0, tt.events)
+			for i := 0; i < tt.events; i++ {
+				evt := newStateEvent("sensor.humidity", fmt.Sprint(i))
+				for _, trigger := range triggers {
+					p.Enqueue(trigger, &evt)
+				}
+				if i < tt.wantStates {
+					want = append(want, fmt.Sprint(i))
+				}
+			}
+			if tt.startAfter {
+				p.Start()
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for p.Stats().Processed < uint64(tt.triggers*tt.wantStates) && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			for _, trigger := range triggers {
+				if got := trigger.getStates(); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s states = %v, want %v", trigger.name, got, want)
+				}
+			}
+			if running.max > tt.workers {
+				t.Errorf("%d triggers ran at the same time, want at most %d", running.max, tt.workers)
+			}
+			if got := p.Stats(); got.Dropped != tt.wantDropped || got.Queued != 0 {
+				t.Errorf("Stats() = %+v, want %d dropped and nothing queued", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_Remove(t *testing.T) {
+	p := NewWorkerPool(1, 10)
+	trigger := &recordingTriggerable{name: "trigger"}
+	for i := 0; i < 3; i++ {
+		evt := newStateEvent("sensor.humidity", fmt.Sprint(i))
+		p.Enqueue(trigger, &evt)
+	}
+	p.Remove(trigger)
+	p.Start()
+	t.Cleanup(p.Stop)
+	time.Sleep(20 * time.Millisecond)
+
+	if got := trigger.getStates(); len(got) != 0 {
+		t.Errorf("states = %v, want none", got)
+	}
+	if got := p.Stats(); got.Queued != 0 || got.Processed != 0 {
+		t.Errorf("Stats() = %+v, want nothing queued nor processed", got)
+	}
+}
+
+func TestWorkerPool_requeue(t *testing.T) {
+	p := NewWorkerPool(4, 10)
+	p.Start()
+	t.Cleanup(p.Stop)
+
+	running := &concurrencyCounter{}
+	trigger := &recordingTriggerable{name: "trigger", delay: 20 * time.Millisecond, requeueAfter: 5 * time.Millisecond, running: running}
+	removed := &recordingTriggerable{name: "removed", requeueAfter: 20 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		evt := newStateEvent("sensor.humidity", fmt.Sprint(i))
+		p.Enqueue(trigger, &evt)
+		p.Enqueue(removed, &evt)
+	}
+	time.Sleep(10 * time.Millisecond)
+	p.Remove(removed)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(trigger.getStates()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Timers expire in any order
+	got := trigger.getStates()
+	sort.Strings(got)
+	if want := []string{"0", "1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("states = %v, want %v", got, want)
+	}
+	// Due events are fired by the trigger's queue, one at a time
+	if running.max != 1 {
+		t.Errorf("%d events have been fired at the same time, want 1", running.max)
+	}
+	if got := removed.getStates(); len(got) != 0 {
+		t.Errorf("removed states = %v, want none", got)
+	}
+	if got := p.Stats(); got.Processed != 9 || got.Queued != 0 {
+		t.Errorf("Stats() = %+v, want 9 processed and nothing queued", got)
+	}
+}
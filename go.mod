@@ -9,7 +9,6 @@ require (
 	github.com/go-ping/ping v1.2.0
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
 	github.com/gobwas/ws v1.4.0
-	github.com/kelvins/sunrisesunset v0.0.0-20230419165732-4d545fa3ee7d
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/pkg/errors v0.9.1
@@ -20,6 +19,7 @@ require (
 	golang.org/x/net v0.47.0
 	golang.org/x/oauth2 v0.24.0
 	google.golang.org/api v0.214.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -87,5 +87,4 @@ require (
 	google.golang.org/grpc v1.69.2 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultBackoffMin is the delay before the first reconnection attempt
+	DefaultBackoffMin = 1 * time.Second
+	// DefaultBackoffMax is the maximum delay between two reconnection attempts
+	DefaultBackoffMax = 1 * time.Minute
+	// DefaultBackoffFactor multiplies the delay after each failed attempt
+	DefaultBackoffFactor = 2
+	// DefaultBackoffJitter is the ratio of the delay randomly added or removed
+	DefaultBackoffJitter = 0.2
+)
+
+// backoff computes exponential delays with jitter between connection attempts
+type backoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+	Jitter float64
+
+	attempt int
+}
+
+// newBackoff returns a backoff configured with default values
+func newBackoff() *backoff {
+	return &backoff{
+		Min:    DefaultBackoffMin,
+		Max:    DefaultBackoffMax,
+		Factor: DefaultBackoffFactor,
+		Jitter: DefaultBackoffJitter,
+	}
+}
+
+// Next returns the delay to wait before the next attempt
+func (b *backoff) Next() time.Duration {
+	d := float64(b.Min) * math.Pow(b.Factor, float64(b.attempt))
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	} else {
+		b.attempt++
+	}
+
+	// Spreading reconnections of several clients with a random delta of +/- Jitter
+	d += d * b.Jitter * (rand.Float64()*2 - 1)
+	return time.Duration(d)
+}
+
+// Reset restarts delays from Min, to be called after a successful attempt
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
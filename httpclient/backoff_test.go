@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	b := &backoff{
+		Min:    time.Second,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: 0.2,
+	}
+
+	tests := []struct {
+		name string
+		want time.Duration
+	}{
+		{name: "attempt_1", want: 1 * time.Second},
+		{name: "attempt_2", want: 2 * time.Second},
+		{name: "attempt_3", want: 4 * time.Second},
+		{name: "attempt_4", want: 8 * time.Second},
+		{name: "attempt_5_capped", want: 10 * time.Second},
+		{name: "attempt_6_capped", want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := b.Next()
+			minWant := time.Duration(float64(tt.want) * (1 - b.Jitter))
+			maxWant := time.Duration(float64(tt.want) * (1 + b.Jitter))
+			if got < minWant || got > maxWant {
+				t.Errorf("backoff.Next() = %s, want between %s and %s", got, minWant, maxWant)
+			}
+		})
+	}
+
+	b.Reset()
+	if got := b.Next(); got > time.Duration(float64(time.Second)*(1+b.Jitter)) {
+		t.Errorf("backoff.Next() after Reset() = %s, want around %s", got, time.Second)
+	}
+}
@@ -0,0 +1,57 @@
+package httpclient
+
+// ConnectionState is the state of the connection between a WebSocketClient and the server
+type ConnectionState int
+
+const (
+	// ConnectionDisconnected means no connection is established
+	ConnectionDisconnected ConnectionState = iota
+	// ConnectionConnecting means the client is trying to reach the server
+	ConnectionConnecting
+	// ConnectionAuthenticating means the connection is established, waiting for the authentication to complete
+	ConnectionAuthenticating
+	// ConnectionReady means the client is authenticated and can send commands to the server
+	ConnectionReady
+	// ConnectionStopping means Stop has been called and the client is releasing its resources
+	ConnectionStopping
+)
+
+// ConnectionStateCallback is called when the connection goes from previous to current state
+type ConnectionStateCallback func(previous, current ConnectionState)
+
+// connectionTransitions lists the states reachable from each state
+var connectionTransitions = map[ConnectionState][]ConnectionState{
+	ConnectionDisconnected:   {ConnectionConnecting, ConnectionStopping},
+	ConnectionConnecting:     {ConnectionAuthenticating, ConnectionStopping},
+	ConnectionAuthenticating: {ConnectionReady, ConnectionDisconnected, ConnectionStopping},
+	ConnectionReady:          {ConnectionDisconnected, ConnectionStopping},
+	ConnectionStopping:       {ConnectionDisconnected},
+}
+
+// canTransitionTo returns true if the state can go to next
+func (s ConnectionState) canTransitionTo(next ConnectionState) bool {
+	for _, state := range connectionTransitions[s] {
+		if state == next {
+			return true
+		}
+	}
+	return false
+}
+
+// String godoc
+func (s ConnectionState) String() string {
+	switch s {
+	case ConnectionDisconnected:
+		return "disconnected"
+	case ConnectionConnecting:
+		return "connecting"
+	case ConnectionAuthenticating:
+		return "authenticating"
+	case ConnectionReady:
+		return "ready"
+	case ConnectionStopping:
+		return "stopping"
+	default:
+		return "unknown"
+	}
+}
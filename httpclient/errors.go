@@ -1,6 +1,10 @@
 package httpclient
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/nmaupu/gotomation/model"
+)
 
 var _ error = (*ErrorStatusNotOK)(nil)
 
@@ -20,3 +24,23 @@ func NewErrorStatusNotOK(status int) *ErrorStatusNotOK {
 func (e ErrorStatusNotOK) Error() string {
 	return e.Message
 }
+
+var _ error = (*ErrorResult)(nil)
+
+// ErrorResult is used to notify caller that the server answered a request with an unsuccessful result
+type ErrorResult struct {
+	Code    string
+	Message string
+}
+
+// NewErrorResult returns a new ErrorResult from an unsuccessful result
+func NewErrorResult(result model.HassResult) *ErrorResult {
+	return &ErrorResult{
+		Code:    result.Error.Code,
+		Message: result.Error.Message,
+	}
+}
+
+func (e ErrorResult) Error() string {
+	return fmt.Sprintf("request failed, code=%s, message=%s", e.Code, e.Message)
+}
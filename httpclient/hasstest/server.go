@@ -0,0 +1,415 @@
+// Package hasstest provides a fake Home Assistant server for tests
+package hasstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nmaupu/gotomation/model"
+)
+
+const (
+	// DefaultToken is the access token accepted by a new Server
+	DefaultToken = "hasstest-token"
+	// HassVersion is the version reported by the server during the auth handshake
+	HassVersion = "2026.10.0"
+)
+
+// ServiceCall is a service call received by the server, either from the REST or the WebSocket API
+type ServiceCall struct {
+	Domain   string
+	Service  string
+	EntityID []string
+	// Data is the service data without the targeted entities
+	Data map[string]any
+}
+
+// Server is an in-process Home Assistant server speaking the REST and WebSocket APIs
+// Service calls are recorded and do not change states, use SetState to simulate their effect
+type Server struct {
+	*httptest.Server
+	// Token is the access token clients must use
+	Token string
+
+	mutex        sync.Mutex
+	states       map[string]model.HassState
+	serviceCalls []ServiceCall
+	conns        map[*conn]struct{}
+	// dropPongs is true when pings are left unanswered
+	dropPongs atomic.Bool
+}
+
+// conn is a WebSocket connection to the server
+type conn struct {
+	net.Conn
+	mutexWrite sync.Mutex
+	// subscriptions are event types subscribed by id, an empty event type matches all events
+	mutexSubscriptions sync.Mutex
+	subscriptions      map[uint64]string
+}
+
+// message is the common part of messages sent by clients
+type message struct {
+	ID           uint64                   `json:"id"`
+	Type         string                   `json:"type"`
+	AccessToken  string                   `json:"access_token"`
+	EventType    string                   `json:"event_type"`
+	Subscription uint64                   `json:"subscription"`
+	Domain       string                   `json:"domain"`
+	Service      string                   `json:"service"`
+	ServiceData  map[string]any           `json:"service_data"`
+	Target       *model.HassServiceTarget `json:"target"`
+}
+
+// NewServer starts and returns a new Server knowing the given states
+// The caller should call Close when finished, to shut it down
+func NewServer(states ...model.HassState) *Server {
+	s := &Server{
+		Token:  DefaultToken,
+		states: make(map[string]model.HassState, len(states)),
+		conns:  make(map[*conn]struct{}),
+	}
+	for _, state := range states {
+		s.states[state.EntityID] = state
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/states", s.authorized(s.handleStates))
+	mux.HandleFunc("GET /api/states/{entity_id}", s.authorized(s.handleState))
+	mux.HandleFunc("POST /api/services/{domain}/{service}", s.authorized(s.handleService))
+	mux.HandleFunc("GET /api/websocket", s.handleWebSocket)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Host returns the host:port the server listens to
+func (s *Server) Host() string {
+	return s.Listener.Addr().String()
+}
+
+// Close closes WebSocket connections and shuts down the server
+func (s *Server) Close() {
+	s.CloseConnections()
+	s.Server.Close()
+}
+
+// CloseConnections closes all WebSocket connections, simulating a network failure or a server restart
+func (s *Server) CloseConnections() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+// DropPongs leaves pings unanswered while drop is true, simulating a half-open connection
+func (s *Server) DropPongs(drop bool) {
+	s.dropPongs.Store(drop)
+}
+
+// State returns the current state of an entity
+func (s *Server) State(entityID string) (model.HassState, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.states[entityID]
+	return state, ok
+}
+
+// SetState sets the state of an entity and sends a state_changed event to subscribers
+func (s *Server) SetState(state model.HassState) {
+	now := time.Now()
+	if state.LastUpdated.IsZero() {
+		state.LastUpdated = now
+	}
+	if state.LastChanged.IsZero() {
+		state.LastChanged = state.LastUpdated
+	}
+	if state.LastReported.IsZero() {
+		state.LastReported = state.LastUpdated
+	}
+
+	s.mutex.Lock()
+	old := s.states[state.EntityID]
+	s.states[state.EntityID] = state
+	s.mutex.Unlock()
+
+	s.PushEvent(model.HassEventContent{
+		EventType: "state_changed",
+		Data: model.HassEventData{
+			EntityID: state.EntityID,
+			OldState: old,
+			NewState: state,
+		},
+	})
+}
+
+// PushEvent sends an event to clients subscribed to its type
+func (s *Server) PushEvent(event model.HassEventContent) {
+	if event.Origin == "" {
+		event.Origin = "LOCAL"
+	}
+	if event.TimeFired == "" {
+		event.TimeFired = time.Now().Format(time.RFC3339Nano)
+	}
+
+	for _, c := range s.connections() {
+		for _, id := range c.subscribed(event.EventType) {
+			c.write(model.HassEvent{ID: id, Type: "event", Event: event})
+		}
+	}
+}
+
+// ServiceCalls returns the service calls received so far, in order
+func (s *Server) ServiceCalls() []ServiceCall {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ServiceCall(nil), s.serviceCalls...)
+}
+
+func (s *Server) connections() []*conn {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// sortedStates returns all states sorted by entity id
+func (s *Server) sortedStates() []model.HassState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	states := make([]model.HassState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].EntityID < states[j].EntityID
+	})
+	return states
+}
+
+// recordServiceCall records a service call, entity_id is moved from data to the call's entities
+func (s *Server) recordServiceCall(domain, service string, target *model.HassServiceTarget, data map[string]any) {
+	call := ServiceCall{
+		Domain:  domain,
+		Service: service,
+		Data:    make(map[string]any, len(data)),
+	}
+	if target != nil {
+		call.EntityID = append(call.EntityID, target.EntityID...)
+	}
+	for k, v := range data {
+		if k == "entity_id" {
+			call.EntityID = append(call.EntityID, entityIDs(v)...)
+			continue
+		}
+		call.Data[k] = v
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.serviceCalls = append(s.serviceCalls, call)
+}
+
+// entityIDs returns the entities of an entity_id field which is either a string or a list
+func entityIDs(v any) []string {
+	switch ids := v.(type) {
+	case string:
+		return strings.Split(ids, ",")
+	case []any:
+		res := make([]string, 0, len(ids))
+		for _, id := range ids {
+			res = append(res, fmt.Sprint(id))
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+// authorized rejects REST requests without the expected token
+func (s *Server) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"message": "Unauthorized"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) handleStates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sortedStates())
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	state, ok := s.State(r.PathValue("entity_id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "Entity not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (s *Server) handleService(w http.ResponseWriter, r *http.Request) {
+	data := make(map[string]any)
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"message": "Data should be valid JSON."})
+			return
+		}
+	}
+
+	s.recordServiceCall(r.PathValue("domain"), r.PathValue("service"), nil, data)
+	writeJSON(w, http.StatusOK, []model.HassState{})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	netConn, _, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		return
+	}
+
+	c := &conn{
+		Conn:          netConn,
+		subscriptions: make(map[uint64]string),
+	}
+	s.mutex.Lock()
+	s.conns[c] = struct{}{}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, c)
+		s.mutex.Unlock()
+		c.Close()
+	}()
+
+	if err := c.write(map[string]any{"type": "auth_required", "ha_version": HassVersion}); err != nil {
+		return
+	}
+
+	authenticated := false
+	for {
+		data, err := wsutil.ReadClientText(c)
+		if err != nil {
+			return
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return
+		}
+
+		if !authenticated {
+			if msg.Type != "auth" || msg.AccessToken != s.Token {
+				c.write(map[string]any{"type": "auth_invalid", "message": "Invalid access token or password"})
+				return
+			}
+			authenticated = true
+			err = c.write(map[string]any{"type": "auth_ok", "ha_version": HassVersion})
+		} else {
+			err = s.handleCommand(c, msg)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleCommand answers a command received from an authenticated client
+func (s *Server) handleCommand(c *conn, msg message) error {
+	switch msg.Type {
+	case "ping":
+		if s.dropPongs.Load() {
+			return nil
+		}
+		return c.write(map[string]any{"id": msg.ID, "type": "pong"})
+	case "subscribe_events":
+		c.subscribe(msg.ID, msg.EventType)
+		return c.writeResult(msg.ID, nil)
+	case "unsubscribe_events":
+		if !c.unsubscribe(msg.Subscription) {
+			return c.write(map[string]any{
+				"id":      msg.ID,
+				"type":    "result",
+				"success": false,
+				"error":   map[string]any{"code": "not_found", "message": "Subscription not found."},
+			})
+		}
+		return c.writeResult(msg.ID, nil)
+	case "get_states":
+		return c.writeResult(msg.ID, s.sortedStates())
+	case "call_service":
+		s.recordServiceCall(msg.Domain, msg.Service, msg.Target, msg.ServiceData)
+		return c.writeResult(msg.ID, map[string]any{"context": model.HassContext{}})
+	default:
+		return c.write(map[string]any{
+			"id":      msg.ID,
+			"type":    "result",
+			"success": false,
+			"error":   map[string]any{"code": "unknown_command", "message": "Unknown command."},
+		})
+	}
+}
+
+func (c *conn) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.mutexWrite.Lock()
+	defer c.mutexWrite.Unlock()
+	return wsutil.WriteServerText(c, data)
+}
+
+func (c *conn) writeResult(id uint64, result any) error {
+	return c.write(map[string]any{"id": id, "type": "result", "success": true, "result": result})
+}
+
+func (c *conn) subscribe(id uint64, eventType string) {
+	c.mutexSubscriptions.Lock()
+	defer c.mutexSubscriptions.Unlock()
+	c.subscriptions[id] = eventType
+}
+
+// unsubscribe removes a subscription and returns false if it does not exist
+func (c *conn) unsubscribe(id uint64) bool {
+	c.mutexSubscriptions.Lock()
+	defer c.mutexSubscriptions.Unlock()
+	_, ok := c.subscriptions[id]
+	delete(c.subscriptions, id)
+	return ok
+}
+
+// subscribed returns the ids of the subscriptions matching eventType
+func (c *conn) subscribed(eventType string) []uint64 {
+	c.mutexSubscriptions.Lock()
+	defer c.mutexSubscriptions.Unlock()
+	ids := make([]uint64, 0)
+	for id, t := range c.subscriptions {
+		if t == "" || t == eventType {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
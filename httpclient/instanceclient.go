@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"fmt"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+// instanceClient is a SimpleClient setting the instance name of the entities it returns
+// so that they can be given back to the right instance's client afterwards
+type instanceClient struct {
+	SimpleClient
+	instance string
+}
+
+// NewInstanceClient returns a SimpleClient talking to the Home Assistant instance called instance using sc
+func NewInstanceClient(instance string, sc SimpleClient) SimpleClient {
+	return &instanceClient{
+		SimpleClient: sc,
+		instance:     instance,
+	}
+}
+
+// GetEntities godoc
+func (c *instanceClient) GetEntities(domain string, name string) ([]model.HassEntity, error) {
+	entities, err := c.SimpleClient.GetEntities(domain, name)
+	for i := range entities {
+		entities[i].Instance = c.instance
+	}
+	return entities, err
+}
+
+// GetEntity godoc
+func (c *instanceClient) GetEntity(domain string, name string) (model.HassEntity, error) {
+	entity, err := c.SimpleClient.GetEntity(domain, name)
+	if err == nil {
+		entity.Instance = c.instance
+	}
+	return entity, err
+}
+
+// unknownInstanceClient is a SimpleClient failing every call because its instance is not configured
+type unknownInstanceClient struct {
+	instance string
+}
+
+// NewUnknownInstanceClient returns a SimpleClient returning an error for every call
+// It is used for entities referencing a Home Assistant instance which is not configured
+func NewUnknownInstanceClient(instance string) SimpleClient {
+	return &unknownInstanceClient{instance: instance}
+}
+
+func (c *unknownInstanceClient) err() error {
+	if c.instance == "" {
+		return fmt.Errorf("no default Home Assistant instance configured")
+	}
+	return fmt.Errorf("Home Assistant instance %s is not configured", c.instance)
+}
+
+// GetEntities godoc
+func (c *unknownInstanceClient) GetEntities(domain string, name string) ([]model.HassEntity, error) {
+	return nil, c.err()
+}
+
+// GetEntity godoc
+func (c *unknownInstanceClient) GetEntity(domain string, name string) (model.HassEntity, error) {
+	return model.HassEntity{}, c.err()
+}
+
+// CheckServerAPIHealth godoc
+func (c *unknownInstanceClient) CheckServerAPIHealth() error {
+	return c.err()
+}
+
+// CallService godoc
+func (c *unknownInstanceClient) CallService(entity model.HassEntity, service string, extraParams map[string]interface{}) error {
+	return c.err()
+}
@@ -35,8 +35,6 @@ func NewSimpleClient(hassConfig model.HassConfig) SimpleClient {
 // GetEntities returns entities matching criteria
 // Regexp patterns can be used
 func (c *simpleClient) GetEntities(domain string, name string) ([]model.HassEntity, error) {
-	l := logging.NewLogger("SimpleClient.GetEntities")
-
 	req, err := c.HassConfig.NewHTTPRequest(http.MethodGet, "states", nil)
 	if err != nil {
 		return nil, err
@@ -59,14 +57,31 @@ func (c *simpleClient) GetEntities(domain string, name string) ([]model.HassEnti
 	}
 
 	// Getting all states
-	entities := make([]model.HassEntity, 0)
 	states := make([]model.HassState, 0)
 	err = json.Unmarshal(body, &states)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter using pattern
+	return filterEntities(states, domain, name)
+}
+
+// GetEntity retrieves one entity given its domain and its name
+// Regexp patterns can be used
+func (c *simpleClient) GetEntity(domain string, name string) (model.HassEntity, error) {
+	entities, err := c.GetEntities(domain, name)
+	if err != nil {
+		return model.HassEntity{}, err
+	}
+
+	return singleEntity(entities, domain, name)
+}
+
+// filterEntities returns entities built from states and matching domain and name
+// Regexp patterns can be used, an empty domain or name matches everything
+func filterEntities(states []model.HassState, domain string, name string) ([]model.HassEntity, error) {
+	l := logging.NewLogger("filterEntities")
+
 	patternDomain := domain
 	patternName := name
 	if domain == "" {
@@ -82,8 +97,10 @@ func (c *simpleClient) GetEntities(domain string, name string) ([]model.HassEnti
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("Cannot compile regexp pattern %s", pattern))
 	}
+
+	entities := make([]model.HassEntity, 0)
 	for _, state := range states {
-		if re.Match([]byte(state.EntityID)) {
+		if re.MatchString(state.EntityID) {
 			entity := model.NewHassEntity(state.EntityID)
 			entity.State = state
 			entities = append(entities, entity)
@@ -93,14 +110,8 @@ func (c *simpleClient) GetEntities(domain string, name string) ([]model.HassEnti
 	return entities, nil
 }
 
-// GetEntity retrieves one entity given its domain and its name
-// Regexp patterns can be used
-func (c *simpleClient) GetEntity(domain string, name string) (model.HassEntity, error) {
-	entities, err := c.GetEntities(domain, name)
-	if err != nil {
-		return model.HassEntity{}, err
-	}
-
+// singleEntity returns the only entity of the slice or an error if there is none or too many
+func singleEntity(entities []model.HassEntity, domain string, name string) (model.HassEntity, error) {
 	if len(entities) == 0 {
 		return model.HassEntity{}, fmt.Errorf("entity %s.%s not found", domain, name)
 	}
@@ -23,15 +23,20 @@ func InitSimpleClient(scheme string, hassHost, hassToken string, healthCheckEnti
 	defer mutexSimpleClient.Unlock()
 
 	l.Debug().Msg("Creating SimpleClient singleton")
-	scs = NewSimpleClient(
-		model.HassConfig{
-			URL:                 url.URL{Scheme: scheme, Host: hassHost, Path: "api"},
-			Token:               hassToken,
-			HealthCheckEntities: healthCheckEntities,
-		})
+	scs = &webSocketFirstClient{
+		SimpleClient: NewSimpleClient(
+			model.HassConfig{
+				URL:                 url.URL{Scheme: scheme, Host: hassHost, Path: "api"},
+				Token:               hassToken,
+				HealthCheckEntities: healthCheckEntities,
+			}),
+		webSocketClient: GetWebSocketClient,
+	}
 }
 
 // GetSimpleClient returns the SimpleClient singleton
+// Entities are read from the WebSocketClient's states when they are up to date and services are called
+// using the WebSocketClient when it is ready, the REST API is used otherwise
 func GetSimpleClient() SimpleClient {
 	mutexSimpleClient.Lock()
 	defer mutexSimpleClient.Unlock()
@@ -39,7 +44,7 @@ func GetSimpleClient() SimpleClient {
 }
 
 // InitWebSocketClient inits the WebSocketClient singleton
-func InitWebSocketClient(scheme, hassHost, hassToken string) {
+func InitWebSocketClient(scheme, hassHost, hassToken string, healthCheckEntities []model.HassEntity) {
 	l := logging.NewLogger("initWebSocketClient")
 
 	mutexWebSocketClient.Lock()
@@ -53,8 +58,9 @@ func InitWebSocketClient(scheme, hassHost, hassToken string) {
 	l.Debug().Msg("Creating WebSocketClient singleton")
 	wsc = NewWebSocketClient(
 		model.HassConfig{
-			URL:   url.URL{Scheme: scheme, Host: hassHost, Path: "api/websocket"},
-			Token: hassToken,
+			URL:                 url.URL{Scheme: scheme, Host: hassHost, Path: "api/websocket"},
+			Token:               hassToken,
+			HealthCheckEntities: healthCheckEntities,
 		})
 }
 
@@ -71,5 +77,5 @@ func IsConnectedAndAuthenticated() bool {
 	if wsc == nil {
 		return false
 	}
-	return wsc.Authenticated() && wsc.Connected()
+	return wsc.ConnectionState() == ConnectionReady
 }
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
+)
+
+const (
+	// stateChangedEventType is the event type fired by Home Assistant when an entity's state changes
+	stateChangedEventType = "state_changed"
+	// getStatesCommandType is the WebSocket command returning all entities' states
+	getStatesCommandType = "get_states"
+	// unsubscribeEventsCommandType is the WebSocket command cancelling an events subscription
+	unsubscribeEventsCommandType = "unsubscribe_events"
+)
+
+// StateCache is an in-memory mirror of Home Assistant entities' states
+// It is seeded using get_states and kept current using state_changed events
+type StateCache interface {
+	GetEntities(domain string, name string) ([]model.HassEntity, error)
+	GetEntity(domain string, name string) (model.HassEntity, error)
+	// IsStale returns true if the cache cannot be trusted (not seeded yet or connection lost since)
+	IsStale() bool
+	// LastUpdate returns the last time the cache received a state
+	LastUpdate() time.Time
+}
+
+type stateCache struct {
+	mutex      sync.RWMutex
+	states     map[string]model.HassState
+	synced     bool
+	lastUpdate time.Time
+}
+
+func newStateCache() *stateCache {
+	return &stateCache{
+		states: make(map[string]model.HassState),
+	}
+}
+
+// GetEntities returns entities matching criteria
+// Regexp patterns can be used
+func (s *stateCache) GetEntities(domain string, name string) ([]model.HassEntity, error) {
+	s.mutex.RLock()
+	states := make([]model.HassState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	s.mutex.RUnlock()
+
+	// Keep results in a stable order, the same way the REST API does
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].EntityID < states[j].EntityID
+	})
+
+	return filterEntities(states, domain, name)
+}
+
+// GetEntity retrieves one entity given its domain and its name
+// Regexp patterns can be used
+func (s *stateCache) GetEntity(domain string, name string) (model.HassEntity, error) {
+	entities, err := s.GetEntities(domain, name)
+	if err != nil {
+		return model.HassEntity{}, err
+	}
+
+	return singleEntity(entities, domain, name)
+}
+
+// IsStale godoc
+func (s *stateCache) IsStale() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return !s.synced
+}
+
+// LastUpdate godoc
+func (s *stateCache) LastUpdate() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.lastUpdate
+}
+
+// seed loads all states gotten from a get_states command
+// A state already known and more recent than the seeded one is kept (event received before the get_states result)
+func (s *stateCache) seed(states []model.HassState) {
+	l := logging.NewLogger("StateCache.seed")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seeded := make(map[string]model.HassState, len(states))
+	for _, state := range states {
+		if cur, ok := s.states[state.EntityID]; ok && cur.LastUpdated.After(state.LastUpdated) {
+			state = cur
+		}
+		seeded[state.EntityID] = state
+	}
+
+	s.states = seeded
+	s.synced = true
+	s.lastUpdate = time.Now()
+
+	l.Info().Int("entities", len(seeded)).Msg("State cache is synced")
+}
+
+// update updates the cache from a state_changed event
+func (s *stateCache) update(event *model.HassEvent) {
+	if event == nil || event.Event.EventType != stateChangedEventType {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entityID := event.Event.Data.EntityID
+	newState := event.Event.Data.NewState
+	if newState.EntityID == "" { // new_state is null when an entity is removed
+		delete(s.states, entityID)
+	} else {
+		s.states[entityID] = newState
+	}
+	s.lastUpdate = time.Now()
+}
+
+// invalidate marks the cache as stale, events might have been missed
+func (s *stateCache) invalidate() {
+	l := logging.NewLogger("StateCache.invalidate")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.synced {
+		l.Warn().Msg("State cache is now stale")
+	}
+	s.synced = false
+}
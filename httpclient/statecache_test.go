@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+func newStateChangedEvent(entityID string, state string, lastUpdated time.Time) *model.HassEvent {
+	evt := &model.HassEvent{
+		Type: "event",
+		Event: model.HassEventContent{
+			EventType: stateChangedEventType,
+			Data: model.HassEventData{
+				EntityID: entityID,
+			},
+		},
+	}
+	if state != "" {
+		evt.Event.Data.NewState = model.HassState{
+			EntityID:    entityID,
+			State:       state,
+			LastUpdated: lastUpdated,
+		}
+	}
+	return evt
+}
+
+func TestStateCache_GetEntities(t *testing.T) {
+	now := time.Now()
+	s := newStateCache()
+	s.seed([]model.HassState{
+		{EntityID: "light.living", State: "on", LastUpdated: now},
+		{EntityID: "light.kitchen", State: "off", LastUpdated: now},
+		{EntityID: "sensor.living_temperature", State: "19.5", LastUpdated: now},
+	})
+
+	tests := []struct {
+		name   string
+		domain string
+		entity string
+		want   []string
+	}{
+		{
+			name:   "exact",
+			domain: "light",
+			entity: "living",
+			want:   []string{"light.living"},
+		},
+		{
+			name:   "regexp",
+			domain: "light",
+			entity: ".*",
+			want:   []string{"light.kitchen", "light.living"},
+		},
+		{
+			name:   "any_domain",
+			domain: "",
+			entity: "living.*",
+			want:   []string{"light.living", "sensor.living_temperature"},
+		},
+		{
+			name:   "not_found",
+			domain: "switch",
+			entity: "living",
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetEntities(tt.domain, tt.entity)
+			if err != nil {
+				t.Fatalf("GetEntities() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetEntities() = %v, want %v", got, tt.want)
+			}
+			for i, e := range got {
+				if e.GetEntityIDFullName() != tt.want[i] {
+					t.Errorf("GetEntities()[%d] = %s, want %s", i, e.GetEntityIDFullName(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStateCache_Update(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		seed      []model.HassState
+		events    []*model.HassEvent
+		reseed    bool
+		entityID  string
+		wantState string
+		wantFound bool
+	}{
+		{
+			name:      "state_changed",
+			seed:      []model.HassState{{EntityID: "light.living", State: "off", LastUpdated: now}},
+			events:    []*model.HassEvent{newStateChangedEvent("light.living", "on", now.Add(time.Second))},
+			entityID:  "living",
+			wantState: "on",
+			wantFound: true,
+		},
+		{
+			name:      "new_entity",
+			events:    []*model.HassEvent{newStateChangedEvent("light.living", "on", now)},
+			entityID:  "living",
+			wantState: "on",
+			wantFound: true,
+		},
+		{
+			name:      "removed_entity",
+			seed:      []model.HassState{{EntityID: "light.living", State: "off", LastUpdated: now}},
+			events:    []*model.HassEvent{newStateChangedEvent("light.living", "", now)},
+			entityID:  "living",
+			wantFound: false,
+		},
+		{
+			name:      "event_before_seed_is_kept",
+			seed:      []model.HassState{{EntityID: "light.living", State: "off", LastUpdated: now}},
+			events:    []*model.HassEvent{newStateChangedEvent("light.living", "on", now.Add(time.Second))},
+			reseed:    true,
+			entityID:  "living",
+			wantState: "on",
+			wantFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStateCache()
+			s.seed(tt.seed)
+			for _, e := range tt.events {
+				s.update(e)
+			}
+			if tt.reseed {
+				s.seed(tt.seed)
+			}
+
+			got, err := s.GetEntity("light", tt.entityID)
+			if (err == nil) != tt.wantFound {
+				t.Fatalf("GetEntity() error = %v, wantFound %v", err, tt.wantFound)
+			}
+			if tt.wantFound && got.State.State != tt.wantState {
+				t.Errorf("GetEntity() state = %s, want %s", got.State.State, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestStateCache_IsStale(t *testing.T) {
+	s := newStateCache()
+	if !s.IsStale() {
+		t.Errorf("IsStale() = false before seed, want true")
+	}
+
+	s.seed(nil)
+	if s.IsStale() {
+		t.Errorf("IsStale() = true after seed, want false")
+	}
+
+	s.invalidate()
+	if !s.IsStale() {
+		t.Errorf("IsStale() = false after invalidate, want true")
+	}
+}
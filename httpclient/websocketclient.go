@@ -1,10 +1,10 @@
 package httpclient
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
-	"github.com/pkg/errors"
+	"io"
 	"net"
 	"reflect"
 	"strings"
@@ -18,6 +18,7 @@ import (
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/routines"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -25,8 +26,24 @@ const (
 	ErrorCodeIDReuse = "id_reuse"
 	// ErrorInvalidFormat is returned when the message is invalid or req id is 0
 	ErrorInvalidFormat = "invalid_format"
+	// DefaultRequestTimeout is the time to wait for a result when the caller's context has no deadline
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultHeartbeatInterval is the time between two ping messages sent to the server
+	DefaultHeartbeatInterval = 30 * time.Second
+	// DefaultPongTimeout is the time to wait for a pong before considering the connection dead
+	DefaultPongTimeout = 10 * time.Second
+	// EventsQueueSize is the number of events waiting for their callback to be called, events are dropped beyond
+	EventsQueueSize = 1024
+	// ErrorCodeConnectionLost is given to callers waiting for a result when the connection is lost
+	ErrorCodeConnectionLost = "connection_lost"
 )
 
+// errAuthInvalid is returned by readMessages when the server refuses the token
+var errAuthInvalid = errors.New("authentication refused by the server")
+
+// ErrNotConnected is returned when sending a command while the client is not connected to the server
+var ErrNotConnected = errors.New("not connected to the server")
+
 // ResponseHandlerSignature is the callback func signature when receiving a response from the server
 type ResponseHandlerSignature func(model.HassAPIObject)
 
@@ -43,6 +60,16 @@ type WebSocketClient interface {
 	SubscribeEvents(eventTypes ...string)
 	Authenticated() bool
 	Connected() bool
+	// ConnectionState returns the current state of the connection
+	ConnectionState() ConnectionState
+	// OnConnectionStateChange registers a callback called on each connection state change
+	OnConnectionStateChange(f ConnectionStateCallback)
+	// States returns the in-memory mirror of entities' states fed by this client
+	States() StateCache
+	// CallService calls a service and waits for its result
+	CallService(ctx context.Context, domain string, service string, target model.HassServiceTarget, data map[string]any) (model.HassResult, error)
+	// SendCommand sends any command to the server and waits for its result
+	SendCommand(ctx context.Context, data model.HassAPIObject) (model.HassResult, error)
 }
 
 type webSocketClient struct {
@@ -58,39 +85,69 @@ type webSocketClient struct {
 	mutexEventsSubscribed sync.Mutex
 	EventsSubscribed      map[uint64]model.HassEventSubscription
 
-	mustConnectStop      bool
-	mustConnectStopMutex sync.Mutex
+	// reconnectBackoff computes the delay between connection attempts
+	reconnectBackoff *backoff
+	// healthCheck returns an error if the server is not able to handle requests yet
+	healthCheck func() error
 
 	// requestChannel is used to share WebSocketRequest objects between go routines
 	requestChannel chan *WebSocketRequest
-	// workerRequestsHandlerStop is used to stop workerRequestsHandler routine
-	workerRequestsHandlerStop chan bool
-	// workerDaemonStop is used to stop workerDaemon routine
-	workerDaemonStop chan bool
+	// eventsChannel holds events' callback calls, run one at a time in the order events are received
+	eventsChannel chan func()
+
+	// heartbeatInterval is the time between two ping messages
+	heartbeatInterval time.Duration
+	// pongTimeout is the time to wait for a pong before forcing a reconnection
+	pongTimeout time.Duration
 
 	// requestsTracker keeps track of requests sent to the server waiting for a result
 	requestsTracker WebSocketRequestsTracker
 
-	mutexAuthenticated sync.Mutex
-	authenticated      bool
+	// states mirrors entities' states using state_changed events
+	states *stateCache
+	// statesSubscriptionID is the id of the state_changed subscription made only to feed states, 0 if none
+	// Its events must not be forwarded to the registered event callback.
+	statesSubscriptionID atomic.Uint64
 
-	mutexConnected sync.Mutex
-	connected      bool
+	// mutexTransition serializes state transitions so that callbacks are called in order
+	mutexTransition          sync.Mutex
+	mutexConnectionState     sync.RWMutex
+	connectionState          ConnectionState
+	connectionStateCallbacks []ConnectionStateCallback
 
 	// started indicates whether or not runnable is started
 	mutexStopStart sync.Mutex
 	started        bool
+	// ctx is done when Stop is called, all go routines return when it happens
+	ctx    context.Context
+	cancel context.CancelFunc
+	// workers is used by Stop to wait for go routines to return
+	workers sync.WaitGroup
 }
 
 // NewWebSocketClient returns a new NewWebSocketClient initialized
 func NewWebSocketClient(config model.HassConfig) WebSocketClient {
 	return &webSocketClient{
-		HassConfig:     config,
-		requestChannel: make(chan *WebSocketRequest, 10),
-		// important: setting size of chan bools to 1 to avoid being blocked until read when sending the stop message
-		workerRequestsHandlerStop: make(chan bool, 1),
-		workerDaemonStop:          make(chan bool, 1),
+		HassConfig:        config,
+		requestChannel:    make(chan *WebSocketRequest, 10),
+		eventsChannel:     make(chan func(), EventsQueueSize),
+		states:            newStateCache(),
+		reconnectBackoff:  newBackoff(),
+		healthCheck:       NewSimpleClient(restConfig(config)).CheckServerAPIHealth,
+		heartbeatInterval: DefaultHeartbeatInterval,
+		pongTimeout:       DefaultPongTimeout,
+	}
+}
+
+// restConfig returns the configuration to reach the REST API of the server behind a WebSocket configuration
+func restConfig(config model.HassConfig) model.HassConfig {
+	rest := config
+	rest.URL.Scheme = "http"
+	if config.URL.Scheme == "wss" {
+		rest.URL.Scheme = "https"
 	}
+	rest.URL.Path = "api"
+	return rest
 }
 
 // RegisterCallback registers a new callback given its type
@@ -112,55 +169,9 @@ func (c *webSocketClient) UnregisterCallback(hassType string) {
 	}
 }
 
-func (c *webSocketClient) mustConnect(retryEvery time.Duration) {
-	l := logging.NewLogger("WebSocketClient.mustConnect")
-	var err error
-
-	// forcing connection to close
-	c.setConnected(false)
-	c.closeConn()
-	c.SetAuthenticated(false)
-	atomic.StoreUint64(&c.id, 0)
-
-	c.mustConnectStopMutex.Lock()
-	c.mustConnectStop = false
-	c.mustConnectStopMutex.Unlock()
-	for !c.mustConnectStop {
-		l.Info().
-			Str("url", c.URL.String()).
-			Msg("Trying to connect")
-		c.mutexConn.Lock()
-		c.conn, _, _, err = ws.DefaultDialer.Dial(context.Background(), c.URL.String())
-		c.mutexConn.Unlock()
-
-		if err != nil {
-			l.Error().
-				Err(err).
-				Msg("An error occurred during connection")
-			time.Sleep(retryEvery)
-			continue
-		}
-
-		c.setConnected(true)
-		l.Info().
-			Str("url", c.URL.String()).
-			Msg("Connection established")
-		// resubscribing to registered events
-		c.mutexEventsSubscribed.Lock()
-		for _, e := range c.EventsSubscribed {
-			if !c.requestsTracker.IsInProgress(e.GetID()) {
-				c.EnqueueRequest(NewWebSocketRequest(e))
-			}
-		}
-		c.mutexEventsSubscribed.Unlock()
-		return
-	}
-}
-
 // NextMessageID returns the next usable message ID
 func (c *webSocketClient) NextMessageID() uint64 {
-	atomic.AddUint64(&c.id, 1)
-	return c.id
+	return atomic.AddUint64(&c.id, 1)
 }
 
 // Stop stops the web socket connection and free resources
@@ -171,53 +182,46 @@ func (c *webSocketClient) Stop() {
 		return
 	}
 
-	// Stop mustConnect for loop if running
-	c.mustConnectStopMutex.Lock()
-	c.mustConnectStop = true
-	c.mustConnectStopMutex.Unlock()
-	c.workerRequestsHandlerStop <- true
-
-	// Cleaning conn and force workerDaemon to get out of the blocking reading func
-	// Important to do that BEFORE sending the stop message
-	// because when failing, a reconnection occurs, so connection HAS TO be nil before it happens
-	// See workerDaemon func's error handling
-	c.closeConn()
-	c.workerDaemonStop <- true
+	if c.ctx.Err() == nil { // not already stopped by itself
+		c.setConnectionState(ConnectionStopping)
+		c.cancel()
+	}
+	c.workers.Wait()
+	c.setConnectionState(ConnectionDisconnected)
 	c.started = false
 }
 
 // Start connects, authenticates and listens to Home Assistant WebSocket API
+// Connection happens in background, use OnConnectionStateChange to know when the client is ready
 func (c *webSocketClient) Start() error {
 	c.mutexStopStart.Lock()
 	defer c.mutexStopStart.Unlock()
 
-	if c.started {
+	if c.running() {
 		return nil
 	}
+	// go routines of a client stopped by itself might still be returning
+	c.workers.Wait()
+
+	// registering default callbacks
+	c.RegisterCallback("result", c.handleResult, model.HassResult{})
+	c.RegisterCallback("auth_required", c.handleAuthRequired, model.HassResult{})
+	c.RegisterCallback("auth_ok", c.handleAuthOK, model.HassResult{})
+	c.RegisterCallback("pong", c.handlePong, model.HassResult{})
+	c.mutexEventsSubscribed.Lock()
+	c.syncStatesSubscription()
+	c.mutexEventsSubscribed.Unlock()
 
-	c.mustConnect(2 * time.Second)
-
-	defer func() {
-		// registering default callbacks
-		c.RegisterCallback("result", c.handleResult, model.HassResult{})
-		c.RegisterCallback("auth_required", c.handleAuthRequired, model.HassResult{})
-		c.RegisterCallback("auth_ok", c.handleAuthOK, model.HassResult{})
-		c.RegisterCallback("auth_invalid", c.handleAuthInvalid, model.HassResult{})
-	}()
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 
 	// 1 worker to send data to the server is enough
-	defer app.RoutinesWG.Add(1)
-	go func() {
-		defer app.RoutinesWG.Done()
-		c.workerRequestsHandler()
-	}()
-
-	// main thread handling communication with the server
-	defer app.RoutinesWG.Add(1)
-	go func() {
-		defer app.RoutinesWG.Done()
-		c.workerDaemon()
-	}()
+	c.startWorker(c.workerRequestsHandler)
+	// 1 worker to call the events' callback keeps events ordered
+	c.startWorker(c.workerEvents)
+	// main thread handling the connection and the communication with the server
+	c.startWorker(c.workerDaemon)
+	// detecting dead connections
+	c.startWorker(c.workerHeartbeat)
 
 	c.started = true
 	return nil
@@ -226,20 +230,160 @@ func (c *webSocketClient) Start() error {
 func (c *webSocketClient) IsStarted() bool {
 	c.mutexStopStart.Lock()
 	defer c.mutexStopStart.Unlock()
-	return c.started
+	return c.running()
 }
 
-func (c *webSocketClient) recoverDisconnection() {
-	l := logging.NewLogger("WebSocketClient.recoverDisconnection")
-	if r := recover(); r != nil {
-		l.Debug().
-			Interface("recover", r).
-			Msg("Panic recovered")
-		c.mustConnect(2 * time.Second)
+// running returns true if the client is started and has not stopped by itself after an authentication failure
+func (c *webSocketClient) running() bool {
+	return c.started && c.ctx.Err() == nil
+}
+
+// startWorker runs f in a go routine Stop waits for
+func (c *webSocketClient) startWorker(f func(ctx context.Context)) {
+	ctx := c.ctx
+	app.RoutinesWG.Add(1)
+	c.workers.Add(1)
+	go func() {
+		defer app.RoutinesWG.Done()
+		defer c.workers.Done()
+		f(ctx)
+	}()
+}
+
+// context returns the context used by the current run of the client
+func (c *webSocketClient) context() context.Context {
+	c.mutexStopStart.Lock()
+	defer c.mutexStopStart.Unlock()
+	if c.ctx == nil {
+		return context.Background()
 	}
+	return c.ctx
+}
+
+// connect dials the server until it succeeds, retrying with an exponential backoff
+// An error is returned only when ctx is done
+func (c *webSocketClient) connect(ctx context.Context) (net.Conn, error) {
+	l := logging.NewLogger("WebSocketClient.connect")
+
+	c.setConnectionState(ConnectionConnecting)
+	atomic.StoreUint64(&c.id, 0)
+
+	for {
+		l.Info().
+			Str("url", c.URL.String()).
+			Msg("Trying to connect")
+
+		var conn net.Conn
+		var br *bufio.Reader
+		err := c.healthCheck()
+		if err == nil {
+			conn, br, _, err = ws.DefaultDialer.Dial(ctx, c.URL.String())
+		}
+		if err == nil {
+			if br != nil { // the server already sent frames (auth_required) with the handshake
+				conn = bufferedConn{Conn: conn, r: br}
+			}
+			c.reconnectBackoff.Reset()
+			l.Info().
+				Str("url", c.URL.String()).
+				Msg("Connection established")
+			return conn, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		retryIn := c.reconnectBackoff.Next()
+		l.Error().
+			Err(err).
+			Str("retry_in", retryIn.String()).
+			Msg("An error occurred during connection")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryIn):
+		}
+	}
+}
+
+// bufferedConn is a net.Conn reading data buffered during the handshake before reading from the connection
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+// Read godoc
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// disconnect closes the connection and cleans everything related to it
+func (c *webSocketClient) disconnect() {
+	c.setConnectionState(ConnectionDisconnected)
+	c.closeConn()
+	c.states.invalidate()
+	c.failPendingRequests()
+}
+
+// failPendingRequests notifies callers waiting for a result that it will never come
+func (c *webSocketClient) failPendingRequests() {
+	for _, req := range c.requestsTracker.RemoveAll() {
+		result := model.HassResult{
+			ID:   req.Data.GetID(),
+			Type: "result",
+		}
+		result.Error.Code = ErrorCodeConnectionLost
+		result.Error.Message = "connection lost before receiving a result"
+		req.deliver(result)
+	}
+}
+
+// ConnectionState godoc
+func (c *webSocketClient) ConnectionState() ConnectionState {
+	c.mutexConnectionState.RLock()
+	defer c.mutexConnectionState.RUnlock()
+	return c.connectionState
+}
+
+// OnConnectionStateChange registers a callback called on each connection state change
+// Callbacks are called in order from the go routine changing the state, they must not block
+// nor start or stop the client
+func (c *webSocketClient) OnConnectionStateChange(f ConnectionStateCallback) {
+	c.mutexTransition.Lock()
+	defer c.mutexTransition.Unlock()
+	c.connectionStateCallbacks = append(c.connectionStateCallbacks, f)
+}
+
+// setConnectionState changes the connection state and calls the registered callbacks
+// It returns false if the transition is not allowed from the current state
+func (c *webSocketClient) setConnectionState(state ConnectionState) bool {
+	l := logging.NewLogger("WebSocketClient.setConnectionState")
+
+	c.mutexTransition.Lock()
+	defer c.mutexTransition.Unlock()
+
+	c.mutexConnectionState.Lock()
+	previous := c.connectionState
+	if !previous.canTransitionTo(state) {
+		c.mutexConnectionState.Unlock()
+		return false
+	}
+	c.connectionState = state
+	c.mutexConnectionState.Unlock()
+
+	l.Debug().
+		Stringer("previous", previous).
+		Stringer("current", state).
+		Msg("Connection state changed")
+	for _, f := range c.connectionStateCallbacks {
+		f(previous, state)
+	}
+	return true
 }
 
 // SubscribeEvents subscribes to Home Assistant event bus
+// An empty event type subscribes to all events
 func (c *webSocketClient) SubscribeEvents(eventTypes ...string) {
 	c.mutexEventsSubscribed.Lock()
 	defer c.mutexEventsSubscribed.Unlock()
@@ -247,43 +391,173 @@ func (c *webSocketClient) SubscribeEvents(eventTypes ...string) {
 	l := logging.NewLogger("webSocketClient.SubscribeEvents")
 	l.Trace().Strs("event_types", eventTypes).Msg("Subscribing to events")
 
+	for _, eventType := range eventTypes {
+		c.subscribe(eventType)
+	}
+	c.syncStatesSubscription()
+}
+
+// subscribe registers a subscription to eventType and sends it if the connection is ready
+// mutexEventsSubscribed must be held
+func (c *webSocketClient) subscribe(eventType string) model.HassEventSubscription {
 	if c.EventsSubscribed == nil {
 		c.EventsSubscribed = make(map[uint64]model.HassEventSubscription, 0)
 	}
 
-	for _, eventType := range eventTypes {
-		sub := model.HassEventSubscription{
-			ID:        c.NextMessageID(),
-			EventType: eventType,
-			Type:      "subscribe_events",
+	sub := model.HassEventSubscription{
+		ID:        c.NextMessageID(),
+		EventType: eventType,
+		Type:      "subscribe_events",
+	}
+	c.EventsSubscribed[sub.GetID()] = sub
+	if c.ConnectionState() == ConnectionReady { // Don't enqueue if not ready because resubscribeEvents will do it
+		c.EnqueueRequest(NewWebSocketRequest(sub))
+	}
+	return sub
+}
+
+// resubscribeEvents subscribes again to all registered events using fresh ids
+// Ids from a previous connection are meaningless to the server after a reconnection
+func (c *webSocketClient) resubscribeEvents() {
+	c.mutexEventsSubscribed.Lock()
+	defer c.mutexEventsSubscribed.Unlock()
+
+	// The states subscription is made again below only if still needed
+	statesID := c.statesSubscriptionID.Swap(0)
+	subs := make(map[uint64]model.HassEventSubscription, len(c.EventsSubscribed))
+	for id, sub := range c.EventsSubscribed {
+		if id == statesID {
+			continue
+		}
+		sub.ID = c.NextMessageID()
+		subs[sub.GetID()] = sub
+		c.EnqueueRequest(NewWebSocketRequest(sub))
+	}
+	c.EventsSubscribed = subs
+	c.syncStatesSubscription()
+}
+
+// syncStatesSubscription ensures state_changed events are received to keep states up to date
+// A subscription of its own is made only when no other subscription receives them, and removed once one does
+// so that events are not received twice. mutexEventsSubscribed must be held.
+func (c *webSocketClient) syncStatesSubscription() {
+	statesID := c.statesSubscriptionID.Load()
+	covered := false
+	for id, sub := range c.EventsSubscribed {
+		if id != statesID && (sub.EventType == stateChangedEventType || sub.EventType == "") {
+			covered = true
+			break
 		}
+	}
+
+	switch {
+	case covered && statesID != 0:
+		delete(c.EventsSubscribed, statesID)
+		c.statesSubscriptionID.Store(0)
+		if c.ConnectionState() == ConnectionReady {
+			unsub := model.NewHassCommand(unsubscribeEventsCommandType, map[string]any{"subscription": statesID})
+			c.EnqueueRequest(NewWebSocketRequest(unsub.Duplicate(c.NextMessageID())))
+		}
+	case !covered && statesID == 0:
+		c.statesSubscriptionID.Store(c.subscribe(stateChangedEventType).GetID())
+	}
+}
+
+// syncStates requests all states to the server to seed the states cache
+func (c *webSocketClient) syncStates() {
+	l := logging.NewLogger("WebSocketClient.syncStates")
+
+	result, err := c.SendCommand(context.Background(), model.NewHassCommand(getStatesCommandType, nil))
+	if err != nil {
+		l.Error().Err(err).Msg("Unable to get states from server")
+		return
+	}
 
-		c.EventsSubscribed[sub.GetID()] = sub
-		if c.Connected() { // Don't enqueue if not connected because mustConnect will do it
-			c.EnqueueRequest(NewWebSocketRequest(sub))
+	states := make([]model.HassState, 0)
+	if err := result.DecodeResult(&states); err != nil {
+		l.Error().Err(err).Msg("Unable to unmarshal states")
+		return
+	}
+	c.states.seed(states)
+}
+
+// updateStates updates states from an event and returns true if the event has to be forwarded to callbacks
+// obj is the event already decoded for the callback, recv is decoded only if obj is not a *model.HassEvent
+func (c *webSocketClient) updateStates(recv []byte, obj model.HassAPIObject) bool {
+	event, ok := obj.(*model.HassEvent)
+	if !ok {
+		event = new(model.HassEvent)
+		if err := json.Unmarshal(recv, event); err != nil {
+			return true // let the callback deal with it
 		}
 	}
+
+	c.states.update(event)
+	return event.GetID() != c.statesSubscriptionID.Load()
+}
+
+// States godoc
+func (c *webSocketClient) States() StateCache {
+	return c.states
 }
 
-// CallService is a generic function to call any service
-// Deprecated: Might not work, use at your own risk
-// TODO to be debugged
-func (c *webSocketClient) CallService(entity model.HassEntity, service string) {
+// CallService calls a service and waits for its result
+// The result is returned along with an ErrorResult if the call is not successful
+// DefaultRequestTimeout applies if ctx has no deadline
+func (c *webSocketClient) CallService(ctx context.Context, domain string, service string, target model.HassServiceTarget, data map[string]any) (model.HassResult, error) {
 	l := logging.NewLogger("WebSocketClient.CallService")
 	d := model.HassService{
-		ID:      c.NextMessageID(),
-		Type:    "call_service",
-		Domain:  entity.Domain,
-		Service: service,
-		ServiceData: model.HassServiceData{
-			EntityID: entity.GetEntityIDFullName(),
-		},
+		Type:        "call_service",
+		Domain:      domain,
+		Service:     service,
+		ServiceData: data,
+		Target:      &target,
 	}
 
-	l.Trace().
-		EmbedObject(d).
+	l.Debug().
+		Str("domain", domain).
+		Str("service", service).
+		Strs("entity_id", target.EntityID).
 		Msg("Calling service")
-	c.EnqueueRequest(NewWebSocketRequest(d))
+	return c.SendCommand(ctx, d)
+}
+
+// SendCommand sends data to the server and waits for the corresponding result
+// A new message id is given to data before sending it
+// The result is returned along with an ErrorResult if the command is not successful
+// For subscription commands (render_template...), only the first result is returned,
+// subsequent messages are received as events
+// DefaultRequestTimeout applies if ctx has no deadline
+// ErrNotConnected is returned if the client is not connected to the server
+func (c *webSocketClient) SendCommand(ctx context.Context, data model.HassAPIObject) (model.HassResult, error) {
+	if !c.Connected() {
+		return model.HassResult{}, errors.Wrapf(ErrNotConnected, "unable to send %s request", data.GetType())
+	}
+
+	data = data.Duplicate(c.NextMessageID())
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	req := newAwaitedWebSocketRequest(ctx, data)
+	select {
+	case c.requestChannel <- req:
+	case <-ctx.Done():
+		return model.HassResult{}, errors.Wrapf(ctx.Err(), "unable to queue %s request", data.GetType())
+	}
+
+	select {
+	case result := <-req.result:
+		if !result.Success {
+			return result, NewErrorResult(result)
+		}
+		return result, nil
+	case <-ctx.Done():
+		c.requestsTracker.Remove(req)
+		return model.HassResult{}, errors.Wrapf(ctx.Err(), "no result received for %s request", data.GetType())
+	}
 }
 
 // EnqueueRequest queues a request to be sent to the server
@@ -291,25 +565,32 @@ func (c *webSocketClient) EnqueueRequest(request *WebSocketRequest) {
 	c.requestChannel <- request
 }
 
-// requeueRequest requeues a request after a while
-func (c *webSocketClient) requeueRequest(req *WebSocketRequest, after time.Duration) {
+// requeueRequest requeues a request after a while unless ctx is done
+func (c *webSocketClient) requeueRequest(ctx context.Context, req *WebSocketRequest, after time.Duration) {
 	app.RoutinesWG.Add(1)
 	go func() {
 		defer app.RoutinesWG.Done()
-		time.Sleep(after)
-		//req.Data = req.Data.Duplicate(c.NextMessageID())
-		c.EnqueueRequest(req)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(after):
+		}
+
+		select {
+		case <-ctx.Done():
+		case c.requestChannel <- req:
+		}
 	}()
 }
 
 // workerRequestsHandler handles requests from channel and effectively sends them to the server
-func (c *webSocketClient) workerRequestsHandler() {
+func (c *webSocketClient) workerRequestsHandler(ctx context.Context) {
 	funcLogger := logging.NewLogger("WebSocketClient.workerRequestsHandler")
 
 loop:
 	for {
 		select {
-		case <-c.workerRequestsHandlerStop:
+		case <-ctx.Done():
 			funcLogger.Trace().Msg("Stopping workerRequestsHandler routine")
 			break loop
 		case request := <-c.requestChannel:
@@ -319,47 +600,45 @@ loop:
 				Str("type", request.Data.GetType()).
 				Logger()
 
-			// Track the request
-			request.LastUpdateTime = time.Now()
-			c.requestsTracker.InProgress(request)
-
-			if err := GetSimpleClient().CheckServerAPIHealth(); err != nil {
-				var eNotOK *ErrorStatusNotOK
-				if errors.As(err, &eNotOK) {
-					l.Error().Err(err).
-						Int("status", eNotOK.Status).
-						Msg("Status was not ok when getting health check entities, requeuing")
-				} else {
-					l.Warn().Msg("Server is unavailable, requeuing")
-				}
-				c.requeueRequest(request, 2*time.Second)
+			if request.isAbandoned() {
+				l.Debug().Msg("Request has been abandoned by the caller, dropping it")
 				continue
 			}
 
-			if !c.Authenticated() && !strings.HasPrefix(request.Data.GetType(), "auth") {
+			isAuth := strings.HasPrefix(request.Data.GetType(), "auth")
+			state := c.ConnectionState()
+			if state != ConnectionReady && !isAuth {
 				// not authenticated yet, requeue
 				l.Debug().Msg("Not authenticated yet, requeuing request")
-				c.requeueRequest(request, 1*time.Second)
+				c.requeueRequest(ctx, request, 1*time.Second)
 				continue
-			} else if c.Authenticated() && strings.HasPrefix(request.Data.GetType(), "auth") {
-				// We are already authenticated, no need to auth again or it will fail. Ignoring this message
-				l.Warn().Msg("A message to auth is about to be sent but we are already authenticated, ignoring.")
+			} else if state != ConnectionAuthenticating && isAuth {
+				// We are already authenticated or the connection is gone, sending it would fail. Ignoring this message
+				l.Warn().
+					Stringer("state", state).
+					Msg("A message to auth is about to be sent but the client is not authenticating, ignoring.")
 				continue
 			}
 
-			// if c.conn is nil, something is wrong with the object (reloading config ?)
-			if c.conn == nil {
-				// ignoring message
+			c.mutexConn.Lock()
+			conn := c.conn
+			c.mutexConn.Unlock()
+			if conn == nil { // connection has been lost in between
+				c.requeueRequest(ctx, request, 1*time.Second)
 				continue
 			}
 
+			// Track the request
+			request.LastUpdateTime = time.Now()
+			c.requestsTracker.InProgress(request)
+
 			l.Info().Msg("Processing request")
 			data, _ := json.Marshal(request.Data)
 			l.Trace().Bytes("data", data).Msg("Sending request to the websocket server")
-			err := wsutil.WriteServerMessage(c.conn, ws.OpText, data)
-			if err != nil {
+			if err := wsutil.WriteClientMessage(conn, ws.OpText, data); err != nil {
 				l.Error().Err(err).Msg("Error sending request to the server, requeuing")
-				c.EnqueueRequest(request)
+				c.requestsTracker.Remove(request)
+				c.requeueRequest(ctx, request, 1*time.Second)
 				continue
 			}
 		}
@@ -368,43 +647,54 @@ loop:
 	funcLogger.Trace().Msg("workerRequestsHandler stopped")
 }
 
-func (c *webSocketClient) workerDaemon() {
+// workerDaemon drives the connection: it connects, reads messages until the connection is lost and reconnects
+// until ctx is done
+func (c *webSocketClient) workerDaemon(ctx context.Context) {
 	l := logging.NewLogger("WebSocketClient.workerDaemon")
 
-	defer c.recoverDisconnection() // calling that when wsutil.ReadServerData panics
-loop:
 	for {
-		select {
-		case <-c.workerDaemonStop:
-			l.Trace().Msg("Stopping workerDaemon routine")
-			break loop
-		default: // Avoid vscode complaining about unreachable code below
+		conn, err := c.connect(ctx)
+		if err != nil {
+			break
 		}
 
+		c.mutexConn.Lock()
+		c.conn = conn
+		c.mutexConn.Unlock()
+		c.setConnectionState(ConnectionAuthenticating)
+
+		// Closing the connection is the only way to get out of the blocking read
+		stopClosing := context.AfterFunc(ctx, func() { conn.Close() })
+		err = c.readMessages(conn)
+		stopClosing()
+		if errors.Is(err, errAuthInvalid) { // retrying with the same token would fail again
+			c.setConnectionState(ConnectionStopping)
+			c.cancel()
+		}
+		c.disconnect()
+
+		if ctx.Err() != nil {
+			break
+		}
+		l.Error().Err(err).Msg("Connection lost, reconnecting")
+	}
+
+	l.Trace().Msg("workerDaemon stopped")
+}
+
+// readMessages reads messages from conn and dispatches them to callbacks until an error occurs
+func (c *webSocketClient) readMessages(conn net.Conn) error {
+	l := logging.NewLogger("WebSocketClient.readMessages")
+
+	for {
 		var msg struct {
 			Type string `json:"type"`
 		}
 
-		// ReadServerData is a blocking func
-		// As such, the only way to force it to return is to close the connection and force it to nil
-		// A mutex is used so that when stop is called from another go routine, only one go routine
-		// can change conn object at a time.
-		// Otherwise, when testing if conn == nil, it might or might not be the case yet...
 		l.Trace().Msg("Waiting for a message from the server...")
-		recv, _, err := wsutil.ReadServerData(c.conn)
+		recv, _, err := wsutil.ReadServerData(conn)
 		if err != nil {
-			c.mutexConn.Lock()
-			connIsNil := c.conn == nil
-			c.mutexConn.Unlock()
-			if connIsNil { // Killed via stop function using a nasty workaround: closing conn and setting it to nil...
-				l.Trace().Msg("Stop func has been called")
-				continue
-			}
-
-			l.Error().Err(err).Msg("Error reading from server")
-			c.mustConnect(2 * time.Second)
-			// abort current loop
-			continue
+			return err
 		}
 
 		l.Trace().
@@ -417,14 +707,43 @@ loop:
 			continue
 		}
 
+		if msg.Type == "auth_invalid" {
+			var result model.HassResult
+			json.Unmarshal(recv, &result)
+			l.Error().
+				Err(errors.New(result.Message)).
+				Str("type", result.GetType()).
+				Msg("Message received from server, cannot continue")
+			return errAuthInvalid
+		}
+
+		// Creating obj from the one passed with RegisterCallback and converting it to model.HassAPIObject
+		// Calling callback with this interface
+		var obj model.HassAPIObject
+		cb, ok := c.callbacks[msg.Type]
+		if ok && cb.F != nil {
+			obj = reflect.New(reflect.TypeOf(cb.ConcreteType)).Interface().(model.HassAPIObject)
+			if err := json.Unmarshal(recv, &obj); err != nil {
+				l.Error().Err(err).Msg("Unable to unmarshal data")
+				obj = nil
+			}
+		}
+
+		if msg.Type == "event" && !c.updateStates(recv, obj) {
+			continue
+		}
+
 		// Calling callback if registered
-		if cb, ok := c.callbacks[msg.Type]; ok {
-			// Creating obj from the one passed with RegisterCallback and converting it to model.HassAPIObject
-			// Calling callback with this interface
-			obj := reflect.New(reflect.TypeOf(cb.ConcreteType)).Interface().(model.HassAPIObject)
-			if cb.F != nil {
-				if err := json.Unmarshal(recv, &obj); err != nil {
-					l.Error().Err(err).Msg("Unable to unmarshal data")
+		if ok {
+			if obj != nil {
+				if msg.Type == "event" {
+					select {
+					case c.eventsChannel <- func() { cb.F(obj) }:
+					default:
+						l.Warn().
+							Int("queue_size", EventsQueueSize).
+							Msg("Events queue is full, dropping event")
+					}
 				} else {
 					app.RoutinesWG.Add(1)
 					go func() {
@@ -440,8 +759,66 @@ loop:
 				Msg("No handler defined")
 		}
 	}
+}
 
-	l.Trace().Msg("workerDaemon stopped")
+// workerEvents calls events' callback one event at a time until ctx is done
+func (c *webSocketClient) workerEvents(ctx context.Context) {
+	l := logging.NewLogger("WebSocketClient.workerEvents")
+	for {
+		select {
+		case <-ctx.Done():
+			l.Trace().Msg("workerEvents stopped")
+			return
+		case f := <-c.eventsChannel:
+			f()
+		}
+	}
+}
+
+// workerHeartbeat sends ping messages at a regular interval and forces a reconnection when no pong is received in time
+// Without it, a half-open connection is only detected when reading from it fails, which can take very long
+func (c *webSocketClient) workerHeartbeat(ctx context.Context) {
+	l := logging.NewLogger("WebSocketClient.workerHeartbeat")
+	ticker := time.NewTicker(c.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.Trace().Msg("workerHeartbeat stopped")
+			return
+		case <-ticker.C:
+			if c.ConnectionState() != ConnectionReady {
+				continue
+			}
+
+			pingCtx, cancel := context.WithTimeout(ctx, c.pongTimeout)
+			_, err := c.SendCommand(pingCtx, model.NewHassCommand("ping", nil))
+			cancel()
+			var eResult *ErrorResult
+			if ctx.Err() != nil || errors.Is(err, ErrNotConnected) ||
+				(errors.As(err, &eResult) && eResult.Code == ErrorCodeConnectionLost) {
+				continue // already handled
+			}
+			if err != nil {
+				l.Warn().Err(err).
+					Str("pong_timeout", c.pongTimeout.String()).
+					Msg("No pong received from server, forcing reconnection")
+				c.forceReconnect()
+				continue
+			}
+			l.Trace().Msg("Pong received")
+		}
+	}
+}
+
+// forceReconnect closes the connection so that reading from it fails and workerDaemon reconnects
+func (c *webSocketClient) forceReconnect() {
+	c.mutexConn.Lock()
+	defer c.mutexConn.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+	}
 }
 
 func (c *webSocketClient) closeConn() {
@@ -460,6 +837,15 @@ func (c *webSocketClient) handleResult(data model.HassAPIObject) {
 	result := data.(*model.HassResult)
 	req := c.requestsTracker.Done(result.GetID())
 
+	retry := result.Error.Code == ErrorCodeIDReuse || result.Error.Code == ErrorInvalidFormat
+	if req != nil && (result.Success || !retry) && req.deliver(*result) {
+		l.Debug().
+			Uint64("id", result.GetID()).
+			Bool("success", result.Success).
+			Msg("Result delivered to caller")
+		return
+	}
+
 	if result.Success {
 		l.Debug().
 			Uint64("id", result.GetID()).
@@ -474,7 +860,7 @@ func (c *webSocketClient) handleResult(data model.HassAPIObject) {
 
 	// Not successful
 	after := 3 * time.Second
-	if result.Error.Code == ErrorCodeIDReuse || result.Error.Code == ErrorInvalidFormat {
+	if retry {
 		after = 10 * time.Millisecond // retry sooner than later
 		req.Data = req.Data.Duplicate(c.NextMessageID())
 	}
@@ -485,7 +871,20 @@ func (c *webSocketClient) handleResult(data model.HassAPIObject) {
 		Str("error_message", result.Error.Message).
 		Msgf("Failed result received for request, requeuing in %s", after.String())
 
-	c.requeueRequest(req, after)
+	c.requeueRequest(c.context(), req, after)
+}
+
+// handlePong handles the answer to a ping message
+func (c *webSocketClient) handlePong(data model.HassAPIObject) {
+	result := data.(*model.HassResult)
+	req := c.requestsTracker.Done(result.GetID())
+	if req == nil { // pong received too late, the caller is not waiting anymore
+		return
+	}
+
+	// pong messages don't have a success field
+	result.Success = true
+	req.deliver(*result)
 }
 
 func (c *webSocketClient) handleAuthRequired(data model.HassAPIObject) {
@@ -501,43 +900,22 @@ func (c *webSocketClient) handleAuthOK(data model.HassAPIObject) {
 	l.Info().
 		Str("type", data.GetType()).
 		Msgf("Message received from server")
-	c.SetAuthenticated(true)
-}
-
-func (c *webSocketClient) handleAuthInvalid(data model.HassAPIObject) {
-	l := logging.NewLogger("WebSocketClient.handleAuthInvalid")
-	result := data.(*model.HassResult)
-	l.Error().
-		Err(fmt.Errorf(result.Message)).
-		Str("type", result.GetType()).
-		Msgf("Message received from server, cannot continue")
-	c.SetAuthenticated(false)
-	c.Stop()
+	if !c.setConnectionState(ConnectionReady) {
+		return // connection lost or stopping in between
+	}
+	c.resubscribeEvents()
+	c.syncStates()
 }
 
 // Authenticated returns true if already authenticated, false otherwise
 func (c *webSocketClient) Authenticated() bool {
-	c.mutexAuthenticated.Lock()
-	defer c.mutexAuthenticated.Unlock()
-	return c.authenticated
-}
-
-func (c *webSocketClient) SetAuthenticated(b bool) {
-	c.mutexAuthenticated.Lock()
-	defer c.mutexAuthenticated.Unlock()
-	c.authenticated = b
+	return c.ConnectionState() == ConnectionReady
 }
 
+// Connected returns true if a connection to the server is established, false otherwise
 func (c *webSocketClient) Connected() bool {
-	c.mutexConnected.Lock()
-	defer c.mutexConnected.Unlock()
-	return c.connected
-}
-
-func (c *webSocketClient) setConnected(b bool) {
-	c.mutexConnected.Lock()
-	defer c.mutexConnected.Unlock()
-	c.connected = b
+	state := c.ConnectionState()
+	return state == ConnectionAuthenticating || state == ConnectionReady
 }
 
 // GetName returns the name of this runnable object
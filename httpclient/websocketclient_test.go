@@ -0,0 +1,274 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+)
+
+// newTestWebSocketClient returns a client connecting to srv and a channel receiving its state transitions
+func newTestWebSocketClient(srv *hasstest.Server, token string) (*webSocketClient, <-chan ConnectionState) {
+	c := NewWebSocketClient(model.HassConfig{
+		URL:   url.URL{Scheme: "ws", Host: srv.Host(), Path: "api/websocket"},
+		Token: token,
+	}).(*webSocketClient)
+	c.healthCheck = func() error { return nil }
+
+	transitions := make(chan ConnectionState, 16)
+	c.OnConnectionStateChange(func(previous, current ConnectionState) {
+		transitions <- current
+	})
+	return c, transitions
+}
+
+func assertTransitions(t *testing.T, transitions <-chan ConnectionState, want ...ConnectionState) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-transitions:
+			if got != w {
+				t.Fatalf("transition to %s, want %s", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no transition received, want %s", w)
+		}
+	}
+}
+
+func TestWebSocketClient_ConnectionState(t *testing.T) {
+	srv := hasstest.NewServer()
+	defer srv.Close()
+	c, transitions := newTestWebSocketClient(srv, srv.Token)
+
+	tests := []struct {
+		name   string
+		action func()
+		want   []ConnectionState
+	}{
+		{
+			name:   "start",
+			action: func() { c.Start() },
+			want:   []ConnectionState{ConnectionConnecting, ConnectionAuthenticating, ConnectionReady},
+		},
+		{
+			name:   "connection_lost",
+			action: srv.CloseConnections,
+			want:   []ConnectionState{ConnectionDisconnected, ConnectionConnecting, ConnectionAuthenticating, ConnectionReady},
+		},
+		{
+			name:   "stop",
+			action: c.Stop,
+			want:   []ConnectionState{ConnectionStopping, ConnectionDisconnected},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.action()
+			assertTransitions(t, transitions, tt.want...)
+			if got := c.ConnectionState(); got != tt.want[len(tt.want)-1] {
+				t.Errorf("ConnectionState() = %s, want %s", got, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
+
+func TestWebSocketClient_AuthInvalid(t *testing.T) {
+	srv := hasstest.NewServer()
+	defer srv.Close()
+	c, transitions := newTestWebSocketClient(srv, "wrong-token")
+
+	c.Start()
+	assertTransitions(t, transitions,
+		ConnectionConnecting, ConnectionAuthenticating, ConnectionStopping, ConnectionDisconnected)
+	if c.IsStarted() {
+		t.Errorf("IsStarted() = true, want false after an invalid authentication")
+	}
+}
+
+func TestWebSocketClient_SendCommandNotSent(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   ConnectionState
+		wantErr error
+	}{
+		{
+			name:    "not_connected",
+			state:   ConnectionDisconnected,
+			wantErr: ErrNotConnected,
+		},
+		{
+			name:    "queue_full",
+			state:   ConnectionReady,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Client not started, nothing reads the requests queue
+			c := NewWebSocketClient(model.HassConfig{}).(*webSocketClient)
+			c.connectionState = tt.state
+			for i := 0; i < cap(c.requestChannel); i++ {
+				c.EnqueueRequest(NewWebSocketRequest(model.NewHassCommand("ping", nil)))
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() {
+				_, err := c.SendCommand(ctx, model.NewHassCommand("ping", nil))
+				errc <- err
+			}()
+
+			select {
+			case err := <-errc:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("SendCommand() error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("SendCommand() is still blocked")
+			}
+		})
+	}
+}
+
+func TestWebSocketClient_Heartbeat(t *testing.T) {
+	srv := hasstest.NewServer()
+	defer srv.Close()
+	c, transitions := newTestWebSocketClient(srv, srv.Token)
+	c.heartbeatInterval = 50 * time.Millisecond
+	c.pongTimeout = 50 * time.Millisecond
+
+	c.Start()
+	defer c.Stop()
+	assertTransitions(t, transitions, ConnectionConnecting, ConnectionAuthenticating, ConnectionReady)
+
+	// No pong received, the connection is considered dead
+	srv.DropPongs(true)
+	assertTransitions(t, transitions, ConnectionDisconnected)
+	srv.DropPongs(false)
+	assertTransitions(t, transitions, ConnectionConnecting, ConnectionAuthenticating, ConnectionReady)
+}
+
+func TestWebSocketClient_BackoffReset(t *testing.T) {
+	srv := hasstest.NewServer()
+	defer srv.Close()
+	c, transitions := newTestWebSocketClient(srv, srv.Token)
+	c.reconnectBackoff.Min = time.Millisecond
+	c.reconnectBackoff.Max = 10 * time.Millisecond
+
+	// The server is not available for the first attempts
+	failures := 3
+	c.healthCheck = func() error {
+		if failures > 0 {
+			failures--
+			return errors.New("server not ready")
+		}
+		return nil
+	}
+
+	c.Start()
+	defer c.Stop()
+	assertTransitions(t, transitions, ConnectionConnecting, ConnectionAuthenticating, ConnectionReady)
+	if failures != 0 {
+		t.Errorf("%d attempts left, want all of them done", failures)
+	}
+	if got := c.reconnectBackoff.attempt; got != 0 {
+		t.Errorf("backoff attempt = %d after a successful connection, want 0", got)
+	}
+}
+
+func TestWebSocketClient_StatesSubscription(t *testing.T) {
+	tests := []struct {
+		name string
+		// subscribed before starting the client
+		subscribed []string
+		// subscribed once the client is ready
+		subscribedLater []string
+		want            int
+	}{
+		{
+			name: "states_only",
+			want: 0,
+		},
+		{
+			name:       "state_changed",
+			subscribed: []string{"state_changed"},
+			want:       1,
+		},
+		{
+			name:            "state_changed_once_ready",
+			subscribedLater: []string{"state_changed"},
+			want:            1,
+		},
+		{
+			name:            "all_events_once_ready",
+			subscribedLater: []string{""},
+			want:            1,
+		},
+		{
+			name:            "other_events_once_ready",
+			subscribedLater: []string{"roku_command"},
+			want:            0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := hasstest.NewServer(model.HassState{EntityID: "light.living", State: model.StateOFF})
+			defer srv.Close()
+			c, transitions := newTestWebSocketClient(srv, srv.Token)
+
+			events := make(chan *model.HassEvent, 16)
+			c.RegisterCallback("event", func(data model.HassAPIObject) {
+				events <- data.(*model.HassEvent)
+			}, model.HassEvent{})
+			c.SubscribeEvents(tt.subscribed...)
+
+			c.Start()
+			defer c.Stop()
+			assertTransitions(t, transitions, ConnectionConnecting, ConnectionAuthenticating, ConnectionReady)
+			waitFor(t, func() bool { return !c.States().IsStale() })
+
+			c.SubscribeEvents(tt.subscribedLater...)
+			// Requests are handled in order, subscriptions are done once the ping's result is received
+			if _, err := c.SendCommand(context.Background(), model.NewHassCommand("ping", nil)); err != nil {
+				t.Fatalf("ping failed, err=%v", err)
+			}
+
+			srv.SetState(model.HassState{EntityID: "light.living", State: model.StateON})
+			waitFor(t, func() bool {
+				entity, err := c.States().GetEntity("light", "living")
+				return err == nil && entity.State.IsON()
+			})
+
+			got := 0
+			for done := false; !done; {
+				select {
+				case <-events:
+					got++
+				case <-time.After(200 * time.Millisecond):
+					done = true
+				}
+			}
+			if got != tt.want {
+				t.Errorf("events forwarded = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// waitFor fails the test if cond is not true within 5 seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"context"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+// webSocketFirstClient is a SimpleClient using the WebSocketClient when it is ready
+// and falling back to the REST API otherwise
+type webSocketFirstClient struct {
+	SimpleClient
+	// webSocketClient returns the WebSocketClient to use, if any
+	webSocketClient func() WebSocketClient
+}
+
+// NewWebSocketFirstClient returns a SimpleClient reading states and calling services using wsc when it is ready
+// and using sc otherwise. wsc can be nil.
+func NewWebSocketFirstClient(sc SimpleClient, wsc WebSocketClient) SimpleClient {
+	return &webSocketFirstClient{
+		SimpleClient:    sc,
+		webSocketClient: func() WebSocketClient { return wsc },
+	}
+}
+
+// readyWebSocketClient returns the WebSocketClient if it is ready, nil otherwise
+func (c *webSocketFirstClient) readyWebSocketClient() WebSocketClient {
+	wsc := c.webSocketClient()
+	if wsc == nil || wsc.ConnectionState() != ConnectionReady {
+		return nil
+	}
+	return wsc
+}
+
+// freshStates returns the WebSocketClient's states if they can be trusted, nil otherwise
+func (c *webSocketFirstClient) freshStates() StateCache {
+	wsc := c.readyWebSocketClient()
+	if wsc == nil || wsc.States().IsStale() {
+		return nil
+	}
+	return wsc.States()
+}
+
+// GetEntities godoc
+func (c *webSocketFirstClient) GetEntities(domain string, name string) ([]model.HassEntity, error) {
+	if states := c.freshStates(); states != nil {
+		return states.GetEntities(domain, name)
+	}
+	return c.SimpleClient.GetEntities(domain, name)
+}
+
+// GetEntity godoc
+func (c *webSocketFirstClient) GetEntity(domain string, name string) (model.HassEntity, error) {
+	if states := c.freshStates(); states != nil {
+		return states.GetEntity(domain, name)
+	}
+	return c.SimpleClient.GetEntity(domain, name)
+}
+
+// CallService godoc
+func (c *webSocketFirstClient) CallService(entity model.HassEntity, service string, extraParams map[string]interface{}) error {
+	wsc := c.readyWebSocketClient()
+	if wsc == nil {
+		return c.SimpleClient.CallService(entity, service, extraParams)
+	}
+
+	_, err := wsc.CallService(context.Background(), entity.Domain, service, model.NewHassServiceTarget(entity), extraParams)
+	return err
+}
@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"context"
 	"time"
 
 	"github.com/nmaupu/gotomation/model"
@@ -12,6 +13,10 @@ type WebSocketRequest struct {
 	Data           model.HassAPIObject
 	CreationTime   time.Time
 	LastUpdateTime time.Time
+	// ctx is used to abandon the request, a request not sent yet is dropped once ctx is done
+	ctx context.Context
+	// result receives the result of this request when the caller is waiting for it
+	result chan model.HassResult
 }
 
 // NewWebSocketRequest creates a new WebSocketRequest
@@ -22,6 +27,33 @@ func NewWebSocketRequest(data model.HassAPIObject) *WebSocketRequest {
 	}
 }
 
+// newAwaitedWebSocketRequest creates a new WebSocketRequest whose result is sent back to the caller
+func newAwaitedWebSocketRequest(ctx context.Context, data model.HassAPIObject) *WebSocketRequest {
+	req := NewWebSocketRequest(data)
+	req.ctx = ctx
+	req.result = make(chan model.HassResult, 1)
+	return req
+}
+
+// isAbandoned returns true if the caller is not waiting for this request anymore
+func (r *WebSocketRequest) isAbandoned() bool {
+	return r.ctx != nil && r.ctx.Err() != nil
+}
+
+// deliver sends the result to the caller waiting for it, if any
+// It returns true if the request was awaited
+func (r *WebSocketRequest) deliver(result model.HassResult) bool {
+	if r.result == nil {
+		return false
+	}
+
+	select {
+	case r.result <- result:
+	default: // a result has already been delivered
+	}
+	return true
+}
+
 // MarshalZerologObject godoc
 func (r WebSocketRequest) MarshalZerologObject(event *zerolog.Event) {
 	event.
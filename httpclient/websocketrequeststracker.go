@@ -2,7 +2,8 @@ package httpclient
 
 import "sync"
 
-// WebSocketRequestsTracker tracks in progress WebSocketRequest objects
+// WebSocketRequestsTracker tracks in progress WebSocketRequest objects by id
+// so that a result can be routed to the channel of the caller waiting for it
 type WebSocketRequestsTracker struct {
 	requests map[uint64]*WebSocketRequest
 	mutex    sync.Mutex
@@ -40,3 +41,26 @@ func (t *WebSocketRequestsTracker) IsInProgress(id uint64) bool {
 	_, ok := t.requests[id]
 	return ok
 }
+
+// Remove deletes a previously stored WebSocketRequest whatever its current id
+func (t *WebSocketRequestsTracker) Remove(request *WebSocketRequest) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for id, req := range t.requests {
+		if req == request {
+			delete(t.requests, id)
+		}
+	}
+}
+
+// RemoveAll deletes all stored WebSocketRequest objects and returns them
+func (t *WebSocketRequestsTracker) RemoveAll() []*WebSocketRequest {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	requests := make([]*WebSocketRequest, 0, len(t.requests))
+	for _, req := range t.requests {
+		requests = append(requests, req)
+	}
+	t.requests = nil
+	return requests
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -9,24 +10,46 @@ import (
 	"github.com/nmaupu/gotomation/model"
 )
 
+var errCoordinatesNotAvailable = errors.New("coordinates are not available")
+
 // CoordsHandler godoc
 func CoordsHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, core.Coords())
+	coords := core.Coords()
+	if coords == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(errCoordinatesNotAvailable))
+		return
+	}
+	c.JSON(http.StatusOK, coords)
 }
 
-// SunriseSunsetHandler sunrise/sunset godoc
+// SunriseSunsetHandler returns the sun's events of the day given by the date query parameter (YYYY-MM-DD), today if not set,
+// and the sun's current position
 func SunriseSunsetHandler(c *gin.Context) {
-	sunrise, sunset, err := core.Coords().GetSunriseSunset()
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, model.NewAPIError(err))
+	coords := core.Coords()
+	if coords == nil {
+		c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(errCoordinatesNotAvailable))
 		return
 	}
 
+	now := time.Now().In(coords.GetLocation())
+	date := now
+	if d := c.Query("date"); d != "" {
+		var err error
+		date, err = time.ParseInLocation("2006-01-02", d, now.Location())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, model.NewAPIError(err))
+			return
+		}
+	}
+
+	elevation, azimuth := coords.GetSunPosition(now)
 	c.JSON(http.StatusOK, struct {
-		Sunrise time.Time
-		Sunset  time.Time
+		core.SunInfo
+		Elevation float64
+		Azimuth   float64
 	}{
-		Sunrise: sunrise,
-		Sunset:  sunset,
+		SunInfo:   coords.GetSunInfo(date),
+		Elevation: elevation,
+		Azimuth:   azimuth,
 	})
 }
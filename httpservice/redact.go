@@ -0,0 +1,30 @@
+package httpservice
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nmaupu/gotomation/logging"
+)
+
+// redactResponseWriter redacts secrets from responses' body
+type redactResponseWriter struct {
+	gin.ResponseWriter
+}
+
+// Write godoc
+func (w redactResponseWriter) Write(data []byte) (int, error) {
+	if _, err := w.ResponseWriter.Write(logging.Redact(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// WriteString godoc
+func (w redactResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// RedactSecrets is a gin middleware redacting the configuration's secrets from responses
+func RedactSecrets(c *gin.Context) {
+	c.Writer = redactResponseWriter{ResponseWriter: c.Writer}
+	c.Next()
+}
@@ -25,10 +25,11 @@ var (
 )
 
 // InitLogger inits the main logger
+// Registered secrets are redacted from everything written to w
 func InitLogger(w io.Writer) {
-	writer := w
+	writer := NewRedactWriter(w)
 	if w == nil {
-		writer = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+		writer = zerolog.ConsoleWriter{Out: NewRedactWriter(os.Stderr), TimeFormat: time.RFC3339}
 	}
 
 	l := zerolog.New(writer).With().Timestamp().Logger()
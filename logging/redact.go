@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// RedactedValue replaces secrets in logs and HTTP responses
+const RedactedValue = "**REDACTED**"
+
+var (
+	// secrets are sorted from the longest to the shortest so that a secret containing another one is fully redacted
+	secrets      []string
+	mutexSecrets sync.RWMutex
+)
+
+// AddSecrets registers values to redact from logs and HTTP responses
+func AddSecrets(values ...string) {
+	mutexSecrets.Lock()
+	defer mutexSecrets.Unlock()
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		addSecret(value)
+		// Secrets are escaped when they are written as JSON strings
+		if escaped, err := json.Marshal(value); err == nil {
+			addSecret(string(escaped[1 : len(escaped)-1]))
+		}
+	}
+}
+
+// addSecret inserts secret keeping secrets sorted, mutexSecrets must be held
+func addSecret(secret string) {
+	idx := sort.Search(len(secrets), func(i int) bool {
+		return len(secrets[i]) < len(secret) || (len(secrets[i]) == len(secret) && secrets[i] >= secret)
+	})
+	if idx < len(secrets) && secrets[idx] == secret {
+		return
+	}
+	secrets = append(secrets, "")
+	copy(secrets[idx+1:], secrets[idx:])
+	secrets[idx] = secret
+}
+
+// Redact returns p with all the registered secrets replaced by RedactedValue
+func Redact(p []byte) []byte {
+	mutexSecrets.RLock()
+	defer mutexSecrets.RUnlock()
+	for _, secret := range secrets {
+		p = bytes.ReplaceAll(p, []byte(secret), []byte(RedactedValue))
+	}
+	return p
+}
+
+// redactWriter redacts secrets before writing to an underlying writer
+type redactWriter struct {
+	io.Writer
+}
+
+// NewRedactWriter returns a writer redacting all the registered secrets before writing to w
+func NewRedactWriter(w io.Writer) io.Writer {
+	return redactWriter{Writer: w}
+}
+
+// Write godoc
+func (w redactWriter) Write(p []byte) (int, error) {
+	if _, err := w.Writer.Write(Redact(p)); err != nil {
+		return 0, err
+	}
+	// Callers expect the length of what they gave
+	return len(p), nil
+}
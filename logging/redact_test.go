@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRedact(t *testing.T) {
+	AddSecrets("s3cr3t", `quo"ted`, "", "s3cr3t-and-more", "s3cr3t")
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "no_secret",
+			data: "nothing to hide",
+			want: "nothing to hide",
+		},
+		{
+			name: "secret",
+			data: "token=s3cr3t and again s3cr3t",
+			want: "token=" + RedactedValue + " and again " + RedactedValue,
+		},
+		{
+			name: "secret_containing_another_one",
+			data: "token=s3cr3t-and-more",
+			want: "token=" + RedactedValue,
+		},
+		{
+			name: "json_escaped_secret",
+			data: `{"password":"quo\"ted"}`,
+			want: `{"password":"` + RedactedValue + `"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Redact([]byte(tt.data))); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRedactWriter(t *testing.T) {
+	AddSecrets("l0gged-t0ken")
+
+	var buf bytes.Buffer
+	l := zerolog.New(NewRedactWriter(&buf))
+	l.Info().Str("token", "l0gged-t0ken").Msg("Connecting")
+
+	if bytes.Contains(buf.Bytes(), []byte("l0gged-t0ken")) {
+		t.Errorf("secret written to the log: %s", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"token":"`+RedactedValue+`"`)) {
+		t.Errorf("redacted secret not written to the log: %s", buf.String())
+	}
+}
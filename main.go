@@ -3,10 +3,12 @@ package main
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -39,9 +41,20 @@ func main() {
 		os.Exit(0)
 	}
 
-	gotoConfig := config.Gotomation{}
-	if gotoFlags.HassToken != "" {
-		gotoConfig.HomeAssistant.Token = gotoFlags.HassToken
+	if flag.Arg(0) == "schema" {
+		os.Exit(printSchema(flag.Arg(1)))
+	}
+
+	// HASS_TOKEN takes precedence over the configuration file, itself taking precedence over --token
+	gotoConfig := config.Gotomation{
+		DefaultToken:  gotoFlags.HassToken,
+		TokenOverride: os.Getenv("HASS_TOKEN"),
+	}
+	logging.AddSecrets(gotoConfig.DefaultToken, gotoConfig.TokenOverride)
+
+	// Sender configs can reference the secrets file next to the configuration file
+	if err := config.LoadSecrets(filepath.Join(filepath.Dir(gotoFlags.ConfigFile), config.SecretsFileName)); err != nil {
+		l.Fatal().Err(err).Msg("Unable to load secrets")
 	}
 
 	// Loading sender configs from env if any and add them to the ones provided with the corresponding flag
@@ -49,22 +62,28 @@ func main() {
 	if senderConfigsFromEnv != "" {
 		gotoFlags.SenderConfig = append(gotoFlags.SenderConfig, strings.Split(senderConfigsFromEnv, `,`)...)
 	}
-	for _, senderConfigJSONb64 := range gotoFlags.SenderConfig {
+	// Sender configs are not logged as they hold credentials
+	for idx, senderConfigJSONb64 := range gotoFlags.SenderConfig {
 		senderCfg := config.SenderConfig{}
 		senderConfigJSON, err := base64.StdEncoding.DecodeString(senderConfigJSONb64)
 		if err != nil {
 			l.Error().
 				Err(err).
-				Str("config", senderConfigJSONb64).
+				Int("index", idx).
 				Msg("An error occurred parsing --senderConfig")
 			continue
 		}
 
-		err = json.Unmarshal(senderConfigJSON, &senderCfg)
+		// Decoding with mapstructure to resolve secret references
+		senderConfigData := make(map[string]any)
+		err = json.Unmarshal(senderConfigJSON, &senderConfigData)
+		if err == nil {
+			err = config.NewMapstructureDecoder(&senderCfg).Decode(senderConfigData)
+		}
 		if err != nil {
 			l.Error().
 				Err(err).
-				Str("config", string(senderConfigJSON)).
+				Int("index", idx).
 				Msg("An error occurred parsing --senderConfig")
 			continue
 		}
@@ -76,30 +95,51 @@ func main() {
 	vi.SetConfigType("yaml")
 	vi.SetConfigName(filepath.Base(gotoFlags.ConfigFile))
 	vi.AddConfigPath(filepath.Dir(gotoFlags.ConfigFile))
-	vi.WatchConfig()
 
 	// Binding some env var to config keys
-	vi.BindEnv("home_assistant.token", "HASS_TOKEN")
 	vi.BindEnv("open_mqtt_gateway.mqtt.username", "OMG_MQTT_USERNAME")
 	vi.BindEnv("open_mqtt_gateway.mqtt.password", "OMG_MQTT_PASSWORD")
 	vi.BindEnv("open_mqtt_gateway.mqtt.broker", "OMG_MQTT_BROKER")
 	vi.BindEnv("open_mqtt_gateway.mqtt.prefix", "OMG_MQTT_PREFIX")
 
-	vi.OnConfigChange(func(e fsnotify.Event) {
+	if flag.Arg(0) == "validate" {
+		os.Exit(validateConfig(vi, gotoConfig))
+	}
+
+	// Reloading when the configuration file or one of its included files changes
+	var (
+		reloadMutex     sync.Mutex
+		includesWatcher *config.IncludesWatcher
+	)
+	loadConfig := func(config config.Gotomation) error {
+		includesWatcher.Watch(config.IncludePatterns(vi.ConfigFileUsed()))
+		return smarthome.Init(config)
+	}
+	reload := func(e fsnotify.Event) {
 		l := logging.NewLogger("OnConfigChange")
 		l.Info().Str("config", e.Name).Msg("Reloading configuration")
 
-		_ = configChange(vi, gotoConfig, func(config config.Gotomation) error {
-			smarthome.StopAndWait()
-			return loadConfig(config)
-		})
-	})
+		// Only what changed is restarted by smarthome.Init
+		reloadMutex.Lock()
+		defer reloadMutex.Unlock()
+		_ = configChange(vi, gotoConfig, loadConfig)
+	}
+	includesWatcher, err := config.NewIncludesWatcher(reload)
+	if err != nil {
+		l.Fatal().Err(err).Msg("Unable to watch included files")
+	}
+	defer includesWatcher.Stop()
+
+	vi.WatchConfig()
+	vi.OnConfigChange(reload)
 
 	// Display binary information
 	displayVersionInfo(l)
 
 	// Load config when starting
-	err := configChange(vi, gotoConfig, loadConfig)
+	reloadMutex.Lock()
+	err = configChange(vi, gotoConfig, loadConfig)
+	reloadMutex.Unlock()
 	if err != nil {
 		l.Fatal().Err(err).Msg("Unable to load config")
 	}
@@ -131,8 +171,51 @@ func configChange(vi *viper.Viper, config config.Gotomation, loadFunc func(confi
 	return err
 }
 
-func loadConfig(config config.Gotomation) error {
-	return smarthome.Init(config)
+// validateConfig checks the configuration file and prints all the errors found
+// It returns the exit code to use
+func validateConfig(vi *viper.Viper, gotoConfig config.Gotomation) int {
+	gotoConfig, err := gotoConfig.DecodeConfigFile(vi, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	errs := smarthome.Validate(gotoConfig)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d error(s) found\n", vi.ConfigFileUsed(), len(errs))
+		return 1
+	}
+
+	fmt.Printf("%s: configuration is valid\n", vi.ConfigFileUsed())
+	return 0
+}
+
+// printSchema prints the JSON Schema of the configuration file, or of the included files if kind is fragment,
+// or of the heater schedules files if kind is heater
+// It returns the exit code to use
+func printSchema(kind string) int {
+	schema := smarthome.ConfigSchema()
+	switch kind {
+	case "":
+	case "fragment":
+		schema = smarthome.FragmentSchema()
+	case "heater":
+		schema = smarthome.HeaterSchedulesSchema()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown schema %q, available: fragment, heater\n", kind)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
 func displayVersionInfo(logger zerolog.Logger) {
@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/mitchellh/mapstructure"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/templating"
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
@@ -17,6 +21,10 @@ const (
 // Gotomation is the struct to unmarshal configuration
 // It is using mapstructure for a compatibility with Viper config files
 type Gotomation struct {
+	// Include lists configuration files to merge into this one, as files or globs relative to the configuration file
+	// The yaml files of the conf.d directory next to the configuration file are always included
+	Include []string `mapstructure:"include"`
+
 	// LogLevel is the log level configured
 	LogLevel string `mapstructure:"log_level"`
 	// Google is used to authenticate to Google's API
@@ -24,12 +32,23 @@ type Gotomation struct {
 		CredentialsFile string `mapstructure:"creds_file"`
 	} `mapstructure:"google"`
 
-	// HomeAssistant server related options
-	HomeAssistant HomeAssistantConfig `mapstructure:"home_assistant"`
+	// HomeAssistant configures all Home Assistant instances
+	// A single instance can be given instead of a list
+	HomeAssistant []HomeAssistantConfig `mapstructure:"home_assistant"`
+	// DefaultToken is the token of the default Home Assistant instance if its configuration does not provide one
+	DefaultToken string `mapstructure:"-"`
+	// TokenOverride is the token of the default Home Assistant instance taking precedence over its configuration
+	TokenOverride string `mapstructure:"-"`
+
+	// Location gives the home's coordinates, the default Home Assistant instance's home zone is used if not set
+	Location LocationConfig `mapstructure:"location"`
 
 	// OMG related config
 	OpenMQTTGateway OpenMQTTGatewayConfig `mapstructure:"open_mqtt_gateway"`
 
+	// Dispatcher configures how events are dispatched to triggers
+	Dispatcher DispatcherConfig `mapstructure:"dispatcher"`
+
 	// Senders configures all sender configuration
 	Senders []SenderConfig `mapstructure:"senders"`
 
@@ -43,26 +62,96 @@ type Gotomation struct {
 	Crons []any `mapstructure:"crons"`
 }
 
-// Validate indicates whether or not the config is valid for gotomation to run
-func (g Gotomation) Validate() bool {
-	return !g.HomeAssistant.Enabled || (g.HomeAssistant.Host != "" && g.HomeAssistant.Token != "")
+// DispatcherConfig configures how events are dispatched to triggers
+type DispatcherConfig struct {
+	// Workers is the number of triggers run at the same time
+	Workers int `mapstructure:"workers"`
+	// QueueDepth is the number of events waiting for each trigger, events are dropped beyond
+	QueueDepth int `mapstructure:"queue_depth"`
 }
 
-// ReadConfigFromFile loads or reloads config from viper's config file
-func (g Gotomation) ReadConfigFromFile(vi *viper.Viper, loadConfig func(config Gotomation) error) error {
-	l := logging.NewLogger("Gotomation.LoadConfig").With().Str("config_file", vi.ConfigFileUsed()).Logger()
+// Validate returns an error if the config is not valid for gotomation to run
+func (g Gotomation) Validate() error {
+	names := make(map[string]bool, len(g.HomeAssistant))
+	for _, hass := range g.HomeAssistant {
+		if names[hass.Name] {
+			if hass.Name == "" {
+				return fmt.Errorf("only one Home Assistant instance can be unnamed")
+			}
+			return fmt.Errorf("Home Assistant instance %s is configured more than once", hass.Name)
+		}
+		names[hass.Name] = true
 
-	if err := vi.ReadInConfig(); err != nil {
-		return errors.Wrap(err, "unable to read config file")
+		if strings.ContainsAny(hass.Name, model.InstanceSeparator+".") {
+			return fmt.Errorf("Home Assistant instance name %s must not contain %q or %q", hass.Name, model.InstanceSeparator, ".")
+		}
+		if hass.Enabled && (hass.Host == "" || hass.Token == "") {
+			return fmt.Errorf("Home Assistant host and token must be specified for instance %q", hass.Name)
+		}
+	}
+	return nil
+}
+
+// GetHomeAssistant returns the configuration of the Home Assistant instance called name
+// The default instance is the unnamed one
+func (g Gotomation) GetHomeAssistant(name string) (HomeAssistantConfig, bool) {
+	for _, hass := range g.HomeAssistant {
+		if hass.Name == name {
+			return hass, true
+		}
+	}
+	return HomeAssistantConfig{}, false
+}
+
+// DecodeConfigFile reads viper's config file and decodes it on top of g
+// Secret references are resolved using the secrets file next to the config file
+// If strict is true, unknown keys are reported as errors
+func (g Gotomation) DecodeConfigFile(vi *viper.Viper, strict bool) (Gotomation, error) {
+	if err := readInConfig(vi); err != nil {
+		return g, errors.Wrap(err, "unable to read config file")
+	}
+	if err := LoadSecrets(filepath.Join(filepath.Dir(vi.ConfigFileUsed()), SecretsFileName)); err != nil {
+		return g, err
 	}
 
 	decodeHooks := viper.DecodeHook(MapstructureDecodeHookFunc())
-	if err := vi.Unmarshal(&g, decodeHooks); err != nil {
-		return errors.Wrap(err, "unable to unmarshal config file")
+	unmarshal := vi.Unmarshal
+	if strict {
+		unmarshal = vi.UnmarshalExact
+	}
+	if err := unmarshal(&g, decodeHooks); err != nil {
+		return g, errors.Wrap(err, "unable to unmarshal config file")
+	}
+	if err := g.mergeFragments(vi.ConfigFileUsed(), strict); err != nil {
+		return g, err
 	}
 
-	if !g.Validate() { // On some systems (rpi), reload succeeds but returns an empty object for obscure reasons...
-		return fmt.Errorf("config is not valid: Home Assistant host and token must be specified")
+	for i := range g.HomeAssistant {
+		if g.HomeAssistant[i].Name != "" {
+			continue
+		}
+		if g.TokenOverride != "" {
+			g.HomeAssistant[i].Token = g.TokenOverride
+		} else if g.HomeAssistant[i].Token == "" {
+			g.HomeAssistant[i].Token = g.DefaultToken
+		}
+	}
+
+	return g, nil
+}
+
+// ReadConfigFromFile loads or reloads config from viper's config file
+func (g Gotomation) ReadConfigFromFile(vi *viper.Viper, loadConfig func(config Gotomation) error) error {
+	l := logging.NewLogger("Gotomation.LoadConfig").With().Str("config_file", vi.ConfigFileUsed()).Logger()
+
+	g, err := g.DecodeConfigFile(vi, false)
+	if err != nil {
+		return err
+	}
+
+	// On some systems (rpi), reload succeeds but returns an empty object for obscure reasons...
+	if err := g.Validate(); err != nil {
+		return errors.Wrap(err, "config is not valid")
 	}
 
 	if g.LogLevel != "" {
@@ -81,10 +170,13 @@ func (g Gotomation) ReadConfigFromFile(vi *viper.Viper, loadConfig func(config G
 // MapstructureDecodeHookFunc returns a mapstructure decode hook func to handle Gotomation configuration objects
 func MapstructureDecodeHookFunc() mapstructure.DecodeHookFunc {
 	return mapstructure.ComposeDecodeHookFunc(
+		SecretDecodeHookFunc(),
 		mapstructure.StringToTimeDurationHookFunc(),
 		mapstructure.StringToTimeHookFunc(TimeLayout),
 		model.StringToHassEntityDecodeHookFunc(),
 		model.StringToDayMonthDateDecodeHookFunc(),
+		model.StringToDateRangeDecodeHookFunc(),
+		templating.StringToTemplateDecodeHookFunc(),
 	)
 }
 
@@ -97,3 +189,15 @@ func NewMapstructureDecoder(result any) *mapstructure.Decoder {
 	decoder, _ := mapstructure.NewDecoder(decoderConfig)
 	return decoder
 }
+
+// NewStrictMapstructureDecoder returns a new mapstructure.Decoder like NewMapstructureDecoder
+// returning an error when a key does not correspond to any field
+func NewStrictMapstructureDecoder(result any) *mapstructure.Decoder {
+	decoderConfig := &mapstructure.DecoderConfig{
+		DecodeHook:  MapstructureDecodeHookFunc(),
+		ErrorUnused: true,
+		Result:      result,
+	}
+	decoder, _ := mapstructure.NewDecoder(decoderConfig)
+	return decoder
+}
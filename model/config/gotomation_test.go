@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGotomation_DecodeConfigFile_token(t *testing.T) {
+	tests := []struct {
+		name string
+		// defaultInstance is the configuration of the default instance, a named one is always configured
+		defaultInstance string
+		defaultToken    string
+		tokenOverride   string
+		want            string
+	}{
+		{
+			name:            "file",
+			defaultInstance: "token: fileToken",
+			want:            "fileToken",
+		},
+		{
+			name:            "file_over_flag",
+			defaultInstance: "token: fileToken",
+			defaultToken:    "flagToken",
+			want:            "fileToken",
+		},
+		{
+			name:            "flag_without_file",
+			defaultInstance: "host: localhost",
+			defaultToken:    "flagToken",
+			want:            "flagToken",
+		},
+		{
+			name:            "env_over_file",
+			defaultInstance: "token: placeholder",
+			defaultToken:    "flagToken",
+			tokenOverride:   "envToken",
+			want:            "envToken",
+		},
+		{
+			name:            "env_without_file",
+			defaultInstance: "host: localhost",
+			defaultToken:    "flagToken",
+			tokenOverride:   "envToken",
+			want:            "envToken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "gotomation.yaml")
+			config := "home_assistant:\n  - " + tt.defaultInstance + "\n  - name: garage\n    token: garageToken\n"
+			if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			vi := viper.New()
+			vi.SetConfigFile(configFile)
+			g, err := Gotomation{DefaultToken: tt.defaultToken, TokenOverride: tt.tokenOverride}.DecodeConfigFile(vi, true)
+			if err != nil {
+				t.Fatalf("DecodeConfigFile() error = %v", err)
+			}
+
+			if hass, _ := g.GetHomeAssistant(""); hass.Token != tt.want {
+				t.Errorf("default instance token = %q, want %q", hass.Token, tt.want)
+			}
+			// Named instances always use their own token
+			if hass, _ := g.GetHomeAssistant("garage"); hass.Token != "garageToken" {
+				t.Errorf("garage instance token = %q, want %q", hass.Token, "garageToken")
+			}
+		})
+	}
+}
@@ -2,7 +2,11 @@ package config
 
 import "github.com/nmaupu/gotomation/model"
 
+// HomeAssistantConfig configures a Home Assistant instance
 type HomeAssistantConfig struct {
+	// Name identifies the instance, entities of a named instance are prefixed with it (name:domain.entity_id)
+	// The unnamed instance is the default one
+	Name                string             `mapstructure:"name"`
 	Enabled             bool               `mapstructure:"enabled"`
 	Host                string             `mapstructure:"host"`
 	Token               string             `mapstructure:"token"`
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConfDirName is the directory next to the configuration file whose yaml files are always included
+	ConfDirName = "conf.d"
+)
+
+// Fragment is a configuration file included by the main one
+// Its entries are appended to the main configuration's ones
+type Fragment struct {
+	Senders  []SenderConfig   `mapstructure:"senders"`
+	Modules  []map[string]any `mapstructure:"modules"`
+	Triggers []map[string]any `mapstructure:"triggers"`
+	Crons    []any            `mapstructure:"crons"`
+}
+
+// IncludePatterns returns the absolute globs of the fragments included by configFile
+// They are made of the include entries and of the conf.d directory's yaml files
+func (g Gotomation) IncludePatterns(configFile string) []string {
+	if abs, err := filepath.Abs(configFile); err == nil {
+		configFile = abs
+	}
+	dir := filepath.Dir(configFile)
+	patterns := make([]string, 0, len(g.Include)+2)
+	for _, include := range g.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(dir, include)
+		}
+		patterns = append(patterns, filepath.Clean(include))
+	}
+	return append(patterns,
+		filepath.Join(dir, ConfDirName, "*.yaml"),
+		filepath.Join(dir, ConfDirName, "*.yml"))
+}
+
+// includeFiles returns the files matched by patterns, sorted and without duplicates
+// The configuration and secrets files are never included
+// A pattern which is not a glob has to match an existing file
+func includeFiles(configFile string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	if abs, err := filepath.Abs(configFile); err == nil {
+		seen[abs] = true
+		seen[filepath.Join(filepath.Dir(abs), SecretsFileName)] = true
+	}
+	files := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid include %s", pattern)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, `*?[\`) {
+			return nil, errors.Errorf("included file %s does not exist", pattern)
+		}
+
+		sort.Strings(matches)
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// mergeFragments appends the entries of all the fragments included by configFile to g
+func (g *Gotomation) mergeFragments(configFile string, strict bool) error {
+	l := logging.NewLogger("Gotomation.mergeFragments")
+
+	files, err := includeFiles(configFile, g.IncludePatterns(configFile))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		vi := viper.New()
+		vi.SetConfigFile(file)
+		if err := readInConfig(vi); err != nil {
+			return errors.Wrapf(err, "unable to read included file %s", file)
+		}
+
+		fragment := Fragment{}
+		decodeHooks := viper.DecodeHook(MapstructureDecodeHookFunc())
+		unmarshal := vi.Unmarshal
+		if strict {
+			unmarshal = vi.UnmarshalExact
+		}
+		if err := unmarshal(&fragment, decodeHooks); err != nil {
+			return errors.Wrapf(err, "unable to unmarshal included file %s", file)
+		}
+
+		l.Debug().
+			Str("file", file).
+			Int("senders", len(fragment.Senders)).
+			Int("modules", len(fragment.Modules)).
+			Int("triggers", len(fragment.Triggers)).
+			Int("crons", len(fragment.Crons)).
+			Msg("Merging included file")
+		g.Senders = append(g.Senders, fragment.Senders...)
+		g.Modules = append(g.Modules, fragment.Modules...)
+		g.Triggers = append(g.Triggers, fragment.Triggers...)
+		g.Crons = append(g.Crons, fragment.Crons...)
+	}
+
+	return nil
+}
+
+// IncludesWatcher calls a function when a file matching the included patterns is modified
+type IncludesWatcher struct {
+	*fsnotify.Watcher
+	mutex    sync.Mutex
+	patterns []string
+	dirs     map[string]bool
+	onChange func(event fsnotify.Event)
+}
+
+// NewIncludesWatcher returns a started IncludesWatcher calling onChange for each modification
+func NewIncludesWatcher(onChange func(event fsnotify.Event)) (*IncludesWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create an includes watcher")
+	}
+
+	w := &IncludesWatcher{
+		Watcher:  watcher,
+		dirs:     make(map[string]bool),
+		onChange: onChange,
+	}
+	go w.run()
+	return w, nil
+}
+
+// Watch replaces the watched patterns
+// Directories are watched instead of files to be notified of files created after the call
+func (w *IncludesWatcher) Watch(patterns []string) {
+	l := logging.NewLogger("IncludesWatcher.Watch")
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.patterns = patterns
+	dirs := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		dirs[filepath.Dir(pattern)] = true
+	}
+
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			_ = w.Watcher.Remove(dir)
+		}
+	}
+	for dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.Watcher.Add(dir); err != nil {
+			l.Debug().Err(err).Str("dir", dir).Msg("Unable to watch included files' directory")
+			delete(dirs, dir)
+		}
+	}
+	w.dirs = dirs
+}
+
+// Stop stops the watcher
+func (w *IncludesWatcher) Stop() {
+	_ = w.Watcher.Close()
+}
+
+func (w *IncludesWatcher) run() {
+	l := logging.NewLogger("IncludesWatcher.run")
+	for {
+		select {
+		case event, ok := <-w.Watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 || !w.matches(event.Name) {
+				continue
+			}
+			l.Trace().
+				Str("event", event.Name).
+				Str("event_op", event.Op.String()).
+				Msg("Included file modified")
+			w.onChange(event)
+		case err, ok := <-w.Watcher.Errors:
+			if !ok {
+				return
+			}
+			l.Error().Err(err).Msg("An error occurred watching included files")
+		}
+	}
+}
+
+// matches returns true if file matches one of the watched patterns
+func (w *IncludesWatcher) matches(file string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, pattern := range w.patterns {
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGotomation_DecodeConfigFile_include(t *testing.T) {
+	internetChecker := func(name string) string {
+		return "modules:\n  - internetChecker:\n      name: " + name + "\n"
+	}
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		strict  bool
+		modules []string
+		wantErr bool
+	}{
+		{
+			name: "no_include",
+			files: map[string]string{
+				"gotomation.yaml": internetChecker("main"),
+			},
+			modules: []string{"main"},
+		},
+		{
+			name: "include_glob_and_conf_d",
+			files: map[string]string{
+				"gotomation.yaml":      "include:\n  - modules/*.yaml\n" + internetChecker("main"),
+				"modules/b.yaml":       internetChecker("b"),
+				"modules/a.yaml":       internetChecker("a"),
+				"conf.d/internet.yaml": internetChecker("conf.d"),
+				"conf.d/README.md":     "not included",
+			},
+			modules: []string{"main", "a", "b", "conf.d"},
+		},
+		{
+			name: "missing_include",
+			files: map[string]string{
+				"gotomation.yaml": "include:\n  - missing.yaml\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "strict_unknown_key",
+			files: map[string]string{
+				"gotomation.yaml": "include:\n  - fragment.yaml\n",
+				"fragment.yaml":   "home_assistant:\n  host: localhost\n",
+			},
+			strict:  true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				file := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			vi := viper.New()
+			vi.SetConfigFile(filepath.Join(dir, "gotomation.yaml"))
+			g, err := Gotomation{}.DecodeConfigFile(vi, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(g.Modules) != len(tt.modules) {
+				t.Fatalf("modules = %v, want %v", g.Modules, tt.modules)
+			}
+			for i, module := range g.Modules {
+				name := module["internetchecker"].(map[string]any)["name"]
+				if name != tt.modules[i] {
+					t.Errorf("modules[%d] = %v, want %s", i, name, tt.modules[i])
+				}
+			}
+		})
+	}
+}
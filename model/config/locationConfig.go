@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// LocationConfig gives the home's coordinates instead of getting them from a Home Assistant zone
+type LocationConfig struct {
+	// Latitude and Longitude are in degrees, longitude being positive east of Greenwich
+	Latitude  *float64 `mapstructure:"latitude"`
+	Longitude *float64 `mapstructure:"longitude"`
+	// Timezone is an IANA time zone such as Europe/Paris, the local one if not set
+	Timezone string `mapstructure:"timezone"`
+}
+
+// IsSet returns true if coordinates are configured
+func (c LocationConfig) IsSet() bool {
+	return c.Latitude != nil || c.Longitude != nil
+}
+
+// Validate returns an error if coordinates or timezone are not usable
+func (c LocationConfig) Validate() error {
+	if c.IsSet() && (c.Latitude == nil || c.Longitude == nil) {
+		return fmt.Errorf("latitude and longitude have to be set together")
+	}
+	if c.Latitude != nil && (*c.Latitude < -90 || *c.Latitude > 90) {
+		return fmt.Errorf("latitude %v is not between -90 and 90", *c.Latitude)
+	}
+	if c.Longitude != nil && (*c.Longitude < -180 || *c.Longitude > 180) {
+		return fmt.Errorf("longitude %v is not between -180 and 180", *c.Longitude)
+	}
+	if _, err := c.GetLocation(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetLocation returns the time zone to compute sun's events in
+func (c LocationConfig) GetLocation() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %s: %w", c.Timezone, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestLocationConfig_Validate(t *testing.T) {
+	float := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name    string
+		config  LocationConfig
+		wantErr bool
+	}{
+		{
+			name: "not_set",
+		},
+		{
+			name:   "set",
+			config: LocationConfig{Latitude: float(48.8566), Longitude: float(2.3522), Timezone: "Europe/Paris"},
+		},
+		{
+			name:    "latitude_only",
+			config:  LocationConfig{Latitude: float(48.8566)},
+			wantErr: true,
+		},
+		{
+			name:    "latitude_out_of_range",
+			config:  LocationConfig{Latitude: float(98.8566), Longitude: float(2.3522)},
+			wantErr: true,
+		},
+		{
+			name:    "longitude_out_of_range",
+			config:  LocationConfig{Latitude: float(48.8566), Longitude: float(-182.3522)},
+			wantErr: true,
+		},
+		{
+			name:    "unknown_timezone",
+			config:  LocationConfig{Latitude: float(48.8566), Longitude: float(2.3522), Timezone: "Europe/Nowhere"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
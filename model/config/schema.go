@@ -0,0 +1,177 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/templating"
+)
+
+const (
+	// SchemaDraft is the JSON Schema version of the schemas built by SchemaReflector
+	SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// Schema is a JSON Schema
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 any                `json:"type,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	PropertyNames        *Schema            `json:"propertyNames,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// SchemaReflector builds JSON Schemas of types decoded with mapstructure
+type SchemaReflector struct {
+	overrides map[reflect.Type]*Schema
+}
+
+// NewSchemaReflector returns a SchemaReflector knowing the types converted by MapstructureDecodeHookFunc
+func NewSchemaReflector() *SchemaReflector {
+	r := &SchemaReflector{
+		overrides: make(map[reflect.Type]*Schema),
+	}
+	r.Override(reflect.TypeOf(time.Duration(0)), &Schema{
+		Type:        "string",
+		Pattern:     `^(-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+|0)$`,
+		Description: "Duration such as 1h30m or 250ms",
+	})
+	r.Override(reflect.TypeOf(time.Time{}), &Schema{
+		Type:        "string",
+		Pattern:     `^([01][0-9]|2[0-3]):[0-5][0-9]:[0-5][0-9]$`,
+		Description: "Time of day formatted as " + TimeLayout,
+	})
+	r.Override(reflect.TypeOf(model.HassEntity{}), &Schema{
+		Type:        "string",
+		Pattern:     `^([^:.]+` + model.InstanceSeparator + `)?[^:.]+\..+$`,
+		Description: "Entity such as light.living, prefixed by its instance name if not on the default one (garage:light.living)",
+	})
+	r.Override(reflect.TypeOf(model.DayMonthDate{}), &Schema{
+		Type:        "string",
+		Pattern:     `^[0-3][0-9][/-][01][0-9]$`,
+		Description: "Date formatted as day/month or day-month",
+	})
+	r.Override(reflect.TypeOf(model.DateRange{}), &Schema{
+		Type:        "string",
+		Pattern:     `^\d{4}-\d{2}-\d{2}(\.\.\d{4}-\d{2}-\d{2})?$`,
+		Description: "Date formatted as year-month-day or date range formatted as first..last",
+	})
+	r.Override(reflect.TypeOf(templating.Template{}), &Schema{
+		Type:        "string",
+		Description: "Go text/template, see the templating package for the available functions",
+	})
+	return r
+}
+
+// Override sets the schema to use for t
+// When t is a map's key type, schema constrains the map's keys
+func (r *SchemaReflector) Override(t reflect.Type, schema *Schema) {
+	r.overrides[t] = schema
+}
+
+// Reflect returns the schema of t
+func (r *SchemaReflector) Reflect(t reflect.Type) *Schema {
+	if schema, ok := r.overrides[t]; ok {
+		dup := *schema
+		return &dup
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return r.Reflect(t.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		minimum := 0.0
+		return &Schema{Type: "integer", Minimum: &minimum}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.Reflect(t.Elem())}
+	case reflect.Map:
+		schema := &Schema{Type: "object"}
+		if keySchema, ok := r.overrides[t.Key()]; ok {
+			schema.PropertyNames = keySchema
+		}
+		if t.Elem().Kind() != reflect.Interface {
+			schema.AdditionalProperties = r.Reflect(t.Elem())
+		}
+		return schema
+	case reflect.Struct:
+		schema := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: false,
+		}
+		r.reflectFields(t, schema)
+		return schema
+	default: // any value is accepted
+		return &Schema{}
+	}
+}
+
+// reflectFields adds the properties of struct t to schema following mapstructure's rules
+func (r *SchemaReflector) reflectFields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && strings.Contains(opts, "squash") {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			r.reflectFields(fieldType, schema)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = r.Reflect(field.Type)
+	}
+}
+
+// CaseInsensitivePattern returns a pattern matching s whatever its case
+// JSON Schema's regular expressions do not support flags such as (?i)
+func CaseInsensitivePattern(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		lower, upper := strings.ToLower(string(c)), strings.ToUpper(string(c))
+		if lower == upper {
+			b.WriteString(regexpQuote(string(c)))
+			continue
+		}
+		b.WriteString("[" + lower + upper + "]")
+	}
+	return b.String()
+}
+
+// regexpQuote escapes s for the ECMA 262 regular expressions used by JSON Schema
+func regexpQuote(s string) string {
+	if strings.ContainsAny(s, `\^$.|?*+()[]{}/-`) {
+		return `\` + s
+	}
+	return s
+}
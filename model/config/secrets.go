@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// SecretsFileName is the file next to the configuration file storing the secrets referenced with !secret
+	SecretsFileName = "secrets.yaml"
+	// SecretTag references a secret of the secrets file in yaml files: !secret name
+	SecretTag = "!secret"
+)
+
+var (
+	// secretRefRegexp matches secret references: ${secret:name}, ${file:/run/secrets/name} or ${env:NAME}
+	secretRefRegexp = regexp.MustCompile(`\$\{(secret|file|env):([^}]+)\}`)
+
+	secretsFile  = make(map[string]string)
+	mutexSecrets sync.RWMutex
+)
+
+// LoadSecrets loads the secrets file referenced by ${secret:name} and !secret name
+// A missing file is not an error, no secret is available in that case
+func LoadSecrets(filename string) error {
+	loaded := make(map[string]string)
+	if _, err := os.Stat(filename); err == nil {
+		vi := viper.New()
+		vi.SetConfigFile(filename)
+		if err := vi.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "unable to read secrets file %s", filename)
+		}
+		for _, key := range vi.AllKeys() {
+			loaded[key] = vi.GetString(key)
+		}
+	}
+
+	mutexSecrets.Lock()
+	defer mutexSecrets.Unlock()
+	secretsFile = loaded
+	return nil
+}
+
+// ResolveSecrets replaces all the secret references of s by their values
+// Resolved values are redacted from logs and HTTP responses
+func ResolveSecrets(s string) (string, error) {
+	var resolveErr error
+	resolved := secretRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		match := secretRefRegexp.FindStringSubmatch(ref)
+		value, err := resolveSecret(match[1], match[2])
+		if err != nil {
+			resolveErr = err
+			return ref
+		}
+		logging.AddSecrets(value)
+		return value
+	})
+	return resolved, resolveErr
+}
+
+// resolveSecret returns the value of the secret name from source
+func resolveSecret(source, name string) (string, error) {
+	switch source {
+	case "secret":
+		mutexSecrets.RLock()
+		defer mutexSecrets.RUnlock()
+		value, ok := secretsFile[strings.ToLower(name)]
+		if !ok {
+			return "", errors.Errorf("secret %s is not defined in %s", name, SecretsFileName)
+		}
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", errors.Wrapf(err, "unable to read secret file %s", name)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	return "", errors.Errorf("unknown secret source %s", source)
+}
+
+// SecretDecodeHookFunc returns a mapstructure decode hook func resolving secret references in strings
+func SecretDecodeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+		return ResolveSecrets(reflect.ValueOf(data).String())
+	}
+}
+
+// readInConfig reads viper's config file replacing !secret tags by ${secret:name} references
+// as tags are lost when the file is parsed
+func readInConfig(vi *viper.Viper) error {
+	if err := vi.ReadInConfig(); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(vi.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(data, []byte(SecretTag)) {
+		return nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	replaceSecretTags(&root)
+	if data, err = yaml.Marshal(&root); err != nil {
+		return err
+	}
+	return vi.ReadConfig(bytes.NewReader(data))
+}
+
+// replaceSecretTags replaces recursively !secret name scalars of node by ${secret:name}
+func replaceSecretTags(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.Tag == SecretTag {
+		node.Tag = "!!str"
+		node.Value = "${secret:" + node.Value + "}"
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		replaceSecretTags(child)
+	}
+}
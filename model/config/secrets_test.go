@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/spf13/viper"
+)
+
+func TestGotomation_DecodeConfigFile_secrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "mqtt_password")
+	if err := os.WriteFile(secretFile, []byte("filePassword\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOTOMATION_TEST_TOKEN", "envToken")
+
+	tests := []struct {
+		name      string
+		config    string
+		secrets   string
+		wantToken string
+		wantMQTT  string
+		wantErr   bool
+	}{
+		{
+			name:      "plain",
+			config:    "home_assistant:\n  token: plainToken\n",
+			wantToken: "plainToken",
+		},
+		{
+			name:      "secret_tag",
+			config:    "home_assistant:\n  token: !secret hass_token\n",
+			secrets:   "hass_token: secretToken\n",
+			wantToken: "secretToken",
+		},
+		{
+			name:      "secret_reference",
+			config:    "home_assistant:\n  token: ${secret:hass_token}\n",
+			secrets:   "hass_token: secretToken\n",
+			wantToken: "secretToken",
+		},
+		{
+			name:      "env_and_file",
+			config:    "home_assistant:\n  token: ${env:GOTOMATION_TEST_TOKEN}\nopen_mqtt_gateway:\n  mqtt:\n    password: pre-${file:" + secretFile + "}\n",
+			wantToken: "envToken",
+			wantMQTT:  "pre-filePassword",
+		},
+		{
+			name:    "unknown_secret",
+			config:  "home_assistant:\n  token: !secret unknown\n",
+			wantErr: true,
+		},
+		{
+			name:    "unset_env",
+			config:  "home_assistant:\n  token: ${env:GOTOMATION_TEST_UNSET}\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			configFile := filepath.Join(dir, "gotomation.yaml")
+			if err := os.WriteFile(configFile, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.secrets != "" {
+				if err := os.WriteFile(filepath.Join(dir, SecretsFileName), []byte(tt.secrets), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			vi := viper.New()
+			vi.SetConfigFile(configFile)
+			g, err := Gotomation{}.DecodeConfigFile(vi, true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := g.HomeAssistant[0].Token; got != tt.wantToken {
+				t.Errorf("token = %s, want %s", got, tt.wantToken)
+			}
+			if got := g.OpenMQTTGateway.MQTT.Password; got != tt.wantMQTT {
+				t.Errorf("mqtt password = %s, want %s", got, tt.wantMQTT)
+			}
+			if tt.wantToken != "plainToken" {
+				if got := string(logging.Redact([]byte("token=" + tt.wantToken))); strings.Contains(got, tt.wantToken) {
+					t.Errorf("resolved secret is not redacted: %s", got)
+				}
+			}
+		})
+	}
+}
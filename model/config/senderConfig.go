@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 
+	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/smarthome/messaging"
 	"github.com/rs/zerolog"
 )
@@ -17,7 +18,8 @@ type SenderConfig struct {
 }
 
 // GetSender gets the Sender interface depending on what field is set
-func (s *SenderConfig) GetSender() (messaging.Sender, error) {
+// client is used by senders driving Home Assistant entities
+func (s *SenderConfig) GetSender(client httpclient.SimpleClient) (messaging.Sender, error) {
 	if s.Telegram != nil {
 		if s.Telegram.Token == "" || s.Telegram.ChatID == 0 {
 			return nil, fmt.Errorf("error creating Telegram config, token or char_id is unspecified for %s", s.Name)
@@ -27,6 +29,7 @@ func (s *SenderConfig) GetSender() (messaging.Sender, error) {
 	}
 
 	if s.StatusLed != nil {
+		s.StatusLed.SetClient(client)
 		return s.StatusLed, nil
 	}
 
@@ -37,9 +40,7 @@ func (s SenderConfig) MarshalZerologObject(event *zerolog.Event) {
 	event.
 		Str("name", s.Name)
 	if s.Telegram != nil {
-		event.
-			Int64("telegram_chat_id", s.Telegram.ChatID).
-			Str("telegram_token", s.Telegram.Token)
+		event.Int64("telegram_chat_id", s.Telegram.ChatID)
 	}
 	if s.StatusLed != nil {
 		event.Object("status_led_", s.StatusLed.Entity)
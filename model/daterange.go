@@ -0,0 +1,81 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// DateLayout is the layout of the dates of a DateRange
+	DateLayout = "2006-01-02"
+	// DateRangeSeparator separates the first and the last days of a DateRange
+	DateRangeSeparator = ".."
+)
+
+// DateRange represents the days from From to To included, given as 2006-01-02 or 2006-01-02..2006-01-09
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDateRange parses a single date or two dates separated by ..
+func ParseDateRange(s string) (DateRange, error) {
+	fromStr, toStr, found := strings.Cut(strings.TrimSpace(s), DateRangeSeparator)
+	if !found {
+		toStr = fromStr
+	}
+
+	from, err := time.Parse(DateLayout, strings.TrimSpace(fromStr))
+	if err != nil {
+		return DateRange{}, err
+	}
+	to, err := time.Parse(DateLayout, strings.TrimSpace(toStr))
+	if err != nil {
+		return DateRange{}, err
+	}
+	if to.Before(from) {
+		return DateRange{}, fmt.Errorf("date range %s ends before it begins", s)
+	}
+	return DateRange{From: from, To: to}, nil
+}
+
+// StringToDateRangeDecodeHookFunc returns a func to decode a string into a DateRange
+// A time.Time is decoded as a single day, yaml decoding unquoted dates as such.
+func StringToDateRangeDecodeHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != reflect.TypeOf(DateRange{}) {
+			return data, nil
+		}
+		switch v := data.(type) {
+		case string:
+			return ParseDateRange(v)
+		case time.Time:
+			day := time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+			return DateRange{From: day, To: day}, nil
+		}
+		return data, nil
+	}
+}
+
+// Contains returns true if t's day, in t's location, is one of the range's days
+func (d DateRange) Contains(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(d.From) && !day.After(d.To)
+}
+
+// String godoc
+func (d DateRange) String() string {
+	if d.From.Equal(d.To) {
+		return d.From.Format(DateLayout)
+	}
+	return d.From.Format(DateLayout) + DateRangeSeparator + d.To.Format(DateLayout)
+}
+
+// MarshalText godoc
+func (d DateRange) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
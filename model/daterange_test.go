@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateRange_Contains(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		t       time.Time
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "single_day",
+			s:    "2026-12-31",
+			t:    time.Date(2026, 12, 31, 23, 59, 0, 0, time.Local),
+			want: true,
+		},
+		{
+			name: "single_day_next_day",
+			s:    "2026-12-31",
+			t:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name: "range_first_day",
+			s:    "2026-12-20..2026-12-27",
+			t:    time.Date(2026, 12, 20, 0, 0, 0, 0, time.Local),
+			want: true,
+		},
+		{
+			name: "range_last_day",
+			s:    "2026-12-20 .. 2026-12-27",
+			t:    time.Date(2026, 12, 27, 18, 0, 0, 0, time.Local),
+			want: true,
+		},
+		{
+			name: "range_day_before",
+			s:    "2026-12-20..2026-12-27",
+			t:    time.Date(2026, 12, 19, 23, 0, 0, 0, time.Local),
+		},
+		{
+			name:    "range_reversed",
+			s:       "2026-12-27..2026-12-20",
+			wantErr: true,
+		},
+		{
+			name:    "not_a_date",
+			s:       "20/12",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDateRange(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDateRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := d.Contains(tt.t); got != tt.want {
+				t.Errorf("DateRange.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
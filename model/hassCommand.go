@@ -0,0 +1,51 @@
+package model
+
+import "encoding/json"
+
+var (
+	_ HassAPIObject = (*HassCommand)(nil)
+)
+
+// HassCommand is a generic command sent to the WebSocket API (get_states, get_config, render_template...)
+// Data holds the command's parameters, they are sent alongside id and type
+type HassCommand struct {
+	ID   uint64
+	Type string
+	Data map[string]any
+}
+
+// NewHassCommand returns a new HassCommand object of the given type with optional parameters
+func NewHassCommand(commandType string, data map[string]any) HassCommand {
+	return HassCommand{
+		Type: commandType,
+		Data: data,
+	}
+}
+
+// GetID godoc
+func (c HassCommand) GetID() uint64 {
+	return c.ID
+}
+
+// GetType godoc
+func (c HassCommand) GetType() string {
+	return c.Type
+}
+
+// Duplicate godoc
+func (c HassCommand) Duplicate(id uint64) HassAPIObject {
+	dup := c
+	dup.ID = id
+	return dup
+}
+
+// MarshalJSON flattens parameters with id and type
+func (c HassCommand) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(c.Data)+2)
+	for k, v := range c.Data {
+		m[k] = v
+	}
+	m["id"] = c.ID
+	m["type"] = c.Type
+	return json.Marshal(m)
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHassCommand_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  HassCommand
+		want string
+	}{
+		{
+			name: "no_data",
+			cmd:  HassCommand{ID: 1, Type: "get_states"},
+			want: `{"id":1,"type":"get_states"}`,
+		},
+		{
+			name: "with_data",
+			cmd: HassCommand{ID: 2, Type: "render_template", Data: map[string]any{
+				"template": "{{ states('sun.sun') }}",
+			}},
+			want: `{"id":2,"template":"{{ states('sun.sun') }}","type":"render_template"}`,
+		},
+		{
+			name: "data_cannot_override_id_and_type",
+			cmd: HassCommand{ID: 3, Type: "get_config", Data: map[string]any{
+				"id":   42,
+				"type": "foo",
+			}},
+			want: `{"id":3,"type":"get_config"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.cmd)
+			if err != nil {
+				t.Fatalf("MarshalJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
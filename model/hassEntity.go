@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/nmaupu/gotomation/logging"
@@ -13,10 +14,20 @@ import (
 
 var (
 	_ zerolog.LogObjectMarshaler = (*HassEntity)(nil)
+
+	// patterns caches compiled entity_id patterns by expression
+	patterns sync.Map
+)
+
+const (
+	// InstanceSeparator separates the Home Assistant instance name from the entity_id
+	InstanceSeparator = ":"
 )
 
 // HassEntity represents a Home Assistant entity
 type HassEntity struct {
+	// Instance is the name of the Home Assistant instance the entity belongs to, empty for the default one
+	Instance string `json:",omitempty"`
 	EntityID string
 	Domain   string
 	State    HassState `json:"-"`
@@ -31,9 +42,32 @@ func (e HassEntity) GetEntityIDFullName() string {
 	return fmt.Sprintf("%s.%s", e.Domain, e.EntityID)
 }
 
+// GetFullName returns the entity_id in the form instance:domain.entity_id
+// or domain.entity_id if the entity belongs to the default instance
+func (e HassEntity) GetFullName() string {
+	if e.Instance == "" {
+		return e.GetEntityIDFullName()
+	}
+
+	return e.Instance + InstanceSeparator + e.GetEntityIDFullName()
+}
+
+// splitInstance splits a full name such as garage:light.living into its instance and entity_id parts
+// A prefix is an instance only if it comes before the domain and has no regexp special characters,
+// patterns such as sensor.(?:a|b) are left untouched
+func splitInstance(fullName string) (string, string) {
+	instance, entityID, found := strings.Cut(fullName, InstanceSeparator)
+	if !found || strings.Contains(instance, ".") || regexp.QuoteMeta(instance) != instance {
+		return "", fullName
+	}
+	return instance, entityID
+}
+
 // NewHassEntity returns a new HassEntity from a full name such as light.living
 // where Domain=light and EntityID=living
+// The name can be prefixed by an instance name such as garage:light.living
 func NewHassEntity(entityID string) HassEntity {
+	instance, entityID := splitInstance(entityID)
 	vals := strings.Split(entityID, ".")
 
 	if len(vals) < 2 {
@@ -41,29 +75,53 @@ func NewHassEntity(entityID string) HassEntity {
 	}
 
 	return HassEntity{
+		Instance: instance,
 		Domain:   vals[0],
 		EntityID: strings.Join(vals[1:], "."),
 	}
 }
 
-// Equals returns true if both entities are equals (same domain and entity_id), false otherwise
-// regexp are supported for entity_id
+// IsPattern returns true if EntityID contains regexp special characters
+func (e HassEntity) IsPattern() bool {
+	return regexp.QuoteMeta(e.EntityID) != e.EntityID
+}
+
+// compiledPattern is a compiled entity_id pattern or the error compiling it
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// CompilePattern compiles expr, patterns are compiled only once and then cached
+func CompilePattern(expr string) (*regexp.Regexp, error) {
+	if p, ok := patterns.Load(expr); ok {
+		return p.(compiledPattern).re, p.(compiledPattern).err
+	}
+	re, err := regexp.Compile(expr)
+	patterns.Store(expr, compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// Equals returns true if both entities are equals (same instance and domain, matching entity_id), false otherwise
+// entity's entity_id is a regexp matched anywhere in e's entity_id
 func (e HassEntity) Equals(entity HassEntity) bool {
 	l := logging.NewLogger("HassEntity.Equals")
 
-	if e.Domain != entity.Domain {
+	if e.Instance != entity.Instance || e.Domain != entity.Domain {
 		return false
 	}
 
-	re, err := regexp.Compile(entity.EntityID)
-	if err != nil {
-		return false
+	var res bool
+	if !entity.IsPattern() {
+		// Same result as the regexp without compiling it
+		res = strings.Contains(e.EntityID, entity.EntityID)
+	} else if re, err := CompilePattern(entity.EntityID); err == nil {
+		res = re.MatchString(e.EntityID)
 	}
 
-	res := re.MatchString(e.EntityID)
 	l.Trace().
-		Str("entity", e.GetEntityIDFullName()).
-		Str("candidate", entity.GetEntityIDFullName()).
+		Str("entity", e.GetFullName()).
+		Str("candidate", entity.GetFullName()).
 		Bool("response", res).
 		Send()
 
@@ -83,6 +141,9 @@ func (e HassEntity) IsContained(entities []HassEntity) bool {
 
 // MarshalZerologObject godoc
 func (e HassEntity) MarshalZerologObject(event *zerolog.Event) {
+	if e.Instance != "" {
+		event.Str("instance", e.Instance)
+	}
 	event.
 		Str("entity_id", e.EntityID).
 		Str("domain", e.Domain).
@@ -92,10 +153,12 @@ func (e HassEntity) MarshalZerologObject(event *zerolog.Event) {
 func (e *HassEntity) UnmarshalJSON(data []byte) error {
 	dataStr := string(data)
 	dataStr = strings.Trim(dataStr, `"`)
-	toks := strings.Split(dataStr, ".")
+	instance, entityID := splitInstance(dataStr)
+	toks := strings.Split(entityID, ".")
 	if len(toks) < 2 {
 		return fmt.Errorf("invalid entity %s", dataStr)
 	}
+	e.Instance = instance
 	e.Domain = toks[0]
 	e.EntityID = strings.Join(toks[1:], "")
 	return nil
@@ -116,12 +179,14 @@ func StringToHassEntityDecodeHookFunc() mapstructure.DecodeHookFunc {
 		}
 
 		// Convert it
-		toks := strings.Split(data.(string), ".")
+		instance, entityID := splitInstance(data.(string))
+		toks := strings.Split(entityID, ".")
 		if len(toks) < 2 {
 			return nil, fmt.Errorf("unable to parse entity %s", data.(string))
 		}
 
 		return HassEntity{
+			Instance: instance,
 			Domain:   toks[0],
 			EntityID: strings.Join(toks[1:], ""),
 		}, nil
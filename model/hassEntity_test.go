@@ -1,6 +1,9 @@
 package model
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestJoinEntities(t *testing.T) {
 	entities := []HassEntity{
@@ -61,3 +64,118 @@ func TestJoinEntities(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHassEntity(t *testing.T) {
+	tests := []struct {
+		name     string
+		entityID string
+		want     HassEntity
+	}{
+		{
+			name:     "default_instance",
+			entityID: "light.living",
+			want:     HassEntity{Domain: "light", EntityID: "living"},
+		},
+		{
+			name:     "named_instance",
+			entityID: "garage:light.living",
+			want:     HassEntity{Instance: "garage", Domain: "light", EntityID: "living"},
+		},
+		{
+			name:     "regexp",
+			entityID: "garage:light.living_.*",
+			want:     HassEntity{Instance: "garage", Domain: "light", EntityID: "living_.*"},
+		},
+		{
+			name:     "non_capturing_group",
+			entityID: "sensor.(?:living|kitchen)_temperature",
+			want:     HassEntity{Domain: "sensor", EntityID: "(?:living|kitchen)_temperature"},
+		},
+		{
+			name:     "named_instance_and_flags_group",
+			entityID: "garage:sensor.(?i:Temperature_.*)",
+			want:     HassEntity{Instance: "garage", Domain: "sensor", EntityID: "(?i:Temperature_.*)"},
+		},
+		{
+			name:     "invalid",
+			entityID: "garage:light",
+			want:     HassEntity{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewHassEntity(tt.entityID)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewHassEntity() = %+v, want %+v", got, tt.want)
+			}
+			if tt.want.Domain != "" && got.GetFullName() != tt.entityID {
+				t.Errorf("GetFullName() = %v, want %v", got.GetFullName(), tt.entityID)
+			}
+		})
+	}
+}
+
+func TestHassEntity_Equals(t *testing.T) {
+	tests := []struct {
+		name      string
+		entity    string
+		candidate string
+		want      bool
+	}{
+		{
+			name:      "same_default_instance",
+			entity:    "light.living",
+			candidate: "light.living",
+			want:      true,
+		},
+		{
+			name:      "same_named_instance",
+			entity:    "garage:light.living",
+			candidate: "garage:light.liv.*",
+			want:      true,
+		},
+		{
+			name:      "literal_matches_anywhere",
+			entity:    "light.living_room",
+			candidate: "light.living",
+			want:      true,
+		},
+		{
+			name:      "invalid_pattern",
+			entity:    "light.living",
+			candidate: "light.([",
+			want:      false,
+		},
+		{
+			name:      "literal_not_contained",
+			entity:    "light.living",
+			candidate: "light.living_room",
+			want:      false,
+		},
+		{
+			name:      "non_capturing_group",
+			entity:    "sensor.kitchen_temperature",
+			candidate: "sensor.(?:living|kitchen)_temperature",
+			want:      true,
+		},
+		{
+			name:      "named_and_default_instances",
+			entity:    "garage:light.living",
+			candidate: "light.living",
+			want:      false,
+		},
+		{
+			name:      "different_named_instances",
+			entity:    "garage:light.living",
+			candidate: "house:light.living",
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewHassEntity(tt.entity).Equals(NewHassEntity(tt.candidate)); got != tt.want {
+				t.Errorf("Equals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
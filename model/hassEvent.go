@@ -19,6 +19,8 @@ type HassEvent struct {
 	ID    uint64           `json:"id"`
 	Type  string           `json:"type"`
 	Event HassEventContent `json:"event,omitempty"`
+	// Instance is the name of the Home Assistant instance which sent the event, empty for the default one
+	Instance string `json:"-"`
 }
 
 // HassEventContent godoc
@@ -82,6 +84,7 @@ func (e HassEvent) OppositeState() bool {
 func (e HassEvent) MarshalZerologObject(event *zerolog.Event) {
 	event.
 		Uint64("id", e.ID).
+		Str("instance", e.Instance).
 		Str("type", e.Type).
 		Str("event.event_type", e.Event.EventType).
 		Str("event.data.entity_id", e.Event.Data.EntityID).
@@ -89,6 +92,15 @@ func (e HassEvent) MarshalZerologObject(event *zerolog.Event) {
 		Str("event.data.new_state", e.Event.Data.NewState.State)
 }
 
+// GetEntity returns the entity the event is about, in the instance which sent the event
+func (e HassEvent) GetEntity() HassEntity {
+	entity := NewHassEntity(e.Event.Data.EntityID)
+	if entity.Domain != "" {
+		entity.Instance = e.Instance
+	}
+	return entity
+}
+
 func (e HassEvent) IsDummy() bool {
 	return e.Event.EventType == "dummy"
 }
@@ -6,9 +6,10 @@ var (
 
 // HassEventSubscription is the data sent to subscribe to events
 type HassEventSubscription struct {
-	ID        uint64 `json:"id"`
-	Type      string `json:"type"`
-	EventType string `json:"event_type"`
+	ID   uint64 `json:"id"`
+	Type string `json:"type"`
+	// EventType is the type of events subscribed, all events if empty
+	EventType string `json:"event_type,omitempty"`
 }
 
 // GetID godoc
@@ -1,17 +1,22 @@
 package model
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 var (
 	_ HassAPIObject = (*HassResult)(nil)
 )
 
 // HassResult represents a Home Assistant message
 type HassResult struct {
-	ID          uint64      `json:"id"`
-	Type        string      `json:"type"`
-	HassVersion string      `json:"ha_version,omitempty"`
-	Message     string      `json:"message,omitempty"`
-	Success     bool        `json:"success,omitempty"`
-	Result      interface{} `json:"result,omitempty"`
+	ID          uint64          `json:"id"`
+	Type        string          `json:"type"`
+	HassVersion string          `json:"ha_version,omitempty"`
+	Message     string          `json:"message,omitempty"`
+	Success     bool            `json:"success,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
 	Error       struct {
 		Code    string `json:"code,omitempty"`
 		Message string `json:"message,omitempty"`
@@ -34,3 +39,11 @@ func (r HassResult) Duplicate(id uint64) HassAPIObject {
 	dup.ID = id
 	return dup
 }
+
+// DecodeResult unmarshals the result's payload into v
+func (r HassResult) DecodeResult(v any) error {
+	if len(r.Result) == 0 {
+		return fmt.Errorf("result %d has no payload", r.ID)
+	}
+	return json.Unmarshal(r.Result, v)
+}
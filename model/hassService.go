@@ -9,16 +9,30 @@ var (
 
 // HassService is used to call the HASS service API
 type HassService struct {
-	ID          uint64          `json:"id"`
-	Type        string          `json:"type"`
-	Domain      string          `json:"domain"`
-	Service     string          `json:"service"`
-	ServiceData HassServiceData `json:"service_data"`
+	ID          uint64             `json:"id"`
+	Type        string             `json:"type"`
+	Domain      string             `json:"domain"`
+	Service     string             `json:"service"`
+	ServiceData map[string]any     `json:"service_data,omitempty"`
+	Target      *HassServiceTarget `json:"target,omitempty"`
 }
 
-// HassServiceData godoc
-type HassServiceData struct {
-	EntityID string `json:"entity_id"`
+// HassServiceTarget is the target of a service call
+type HassServiceTarget struct {
+	EntityID []string `json:"entity_id,omitempty"`
+	DeviceID []string `json:"device_id,omitempty"`
+	AreaID   []string `json:"area_id,omitempty"`
+}
+
+// NewHassServiceTarget returns a HassServiceTarget targeting the given entities
+func NewHassServiceTarget(entities ...HassEntity) HassServiceTarget {
+	target := HassServiceTarget{
+		EntityID: make([]string, 0, len(entities)),
+	}
+	for _, e := range entities {
+		target.EntityID = append(target.EntityID, e.GetEntityIDFullName())
+	}
+	return target
 }
 
 // GetID godoc
@@ -44,6 +58,11 @@ func (s HassService) MarshalZerologObject(event *zerolog.Event) {
 		Uint64("id", s.ID).
 		Str("type", s.Type).
 		Str("domain", s.Domain).
-		Str("service", s.Service).
-		Str("entity_id", s.ServiceData.EntityID)
+		Str("service", s.Service)
+	if s.Target != nil {
+		event.
+			Strs("entity_id", s.Target.EntityID).
+			Strs("device_id", s.Target.DeviceID).
+			Strs("area_id", s.Target.AreaID)
+	}
 }
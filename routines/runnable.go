@@ -28,6 +28,26 @@ func AddRunnable(r ...Runnable) {
 	runnables = append(runnables, r...)
 }
 
+// RemoveRunnable removes Runnable objects from the list, they have to be stopped by the caller
+func RemoveRunnable(r ...Runnable) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	kept := make([]Runnable, 0, len(runnables))
+	for _, runnable := range runnables {
+		removed := false
+		for _, toRemove := range r {
+			if runnable == toRemove {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, runnable)
+		}
+	}
+	runnables = kept
+}
+
 // ResetRunnablesList empties Runnable objects' list
 func ResetRunnablesList() {
 	mutex.Lock()
@@ -45,8 +65,8 @@ func StartAllRunnables() {
 			continue
 		}
 
-		if r.IsStarted() {
-			l.Warn().
+		if r.IsStarted() { // kept running across a config reload
+			l.Debug().
 				Str("runnable", r.GetName()).
 				Msg("Runnable already started")
 			continue
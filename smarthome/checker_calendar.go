@@ -7,7 +7,6 @@ import (
 
 	"github.com/nmaupu/gotomation/core"
 	"github.com/nmaupu/gotomation/logging"
-	"github.com/nmaupu/gotomation/thirdparty"
 	"google.golang.org/api/calendar/v3"
 )
 
@@ -28,7 +27,13 @@ type CalendarChecker struct {
 func (c *CalendarChecker) Check() {
 	l := logging.NewLogger("CalendarLights.Check")
 
-	client, err := thirdparty.GetGoogleConfig().GetClient()
+	googleConfig := c.GetRuntime().GoogleConfig
+	if googleConfig == nil {
+		l.Error().Msg("Google is not configured")
+		return
+	}
+
+	client, err := googleConfig.GetClient()
 	if err != nil {
 		l.Error().Err(err).Msg("Unable to get google's API client")
 		return
@@ -73,5 +78,5 @@ func (c *CalendarChecker) Check() {
 }
 
 func (c *CalendarChecker) GinHandler(ctx *gin.Context) {
-	ctx.JSON(http.StatusOK, *c)
+	ctx.JSON(http.StatusOK, c)
 }
@@ -1,15 +1,12 @@
 package smarthome
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/smarthome/messaging"
+	"github.com/nmaupu/gotomation/templating"
 	"net/http"
-	"strings"
-	"text/template"
 	"time"
 
 	"github.com/nmaupu/gotomation/core"
@@ -17,7 +14,8 @@ import (
 )
 
 var (
-	_ core.Modular = (*FreshnessChecker)(nil)
+	_ core.Modular     = (*FreshnessChecker)(nil)
+	_ core.Validatable = (*FreshnessChecker)(nil)
 )
 
 const (
@@ -25,6 +23,10 @@ const (
 	DefaultFreshnessCheckerTemplateString = `The following entities have not been seen since {{ .Checker.Freshness }}: {{ JoinEntities .Entities ", " }}`
 )
 
+var (
+	defaultFreshnessCheckerTemplate = templating.MustParse(DefaultFreshnessCheckerTemplateString)
+)
+
 // FreshnessChecker checks freshness of devices against a duration (using last_seen property (Zigbee2mqtt) or from last_reported value)
 type FreshnessChecker struct {
 	core.Module `mapstructure:",squash"`
@@ -37,7 +39,7 @@ type FreshnessChecker struct {
 	// TimeFormat sets the time format if different from default - only when reading from State
 	TimeFormat string `mapstructure:"time_format"`
 	// Template to use for sending message to the sender
-	Template string `mapstructure:"template"`
+	Template templating.Template `mapstructure:"template"`
 	// ReadFromLastReported reads from the last_reported value of the entity instead of directly from State
 	ReadFromLastReported bool `mapstructure:"read_from_last_reported"`
 }
@@ -57,7 +59,7 @@ func (c *FreshnessChecker) Check() {
 	notFreshEntities := make([]model.HassEntity, 0)
 
 	for _, entity := range c.Entities {
-		hassEntity, err := httpclient.GetSimpleClient().GetEntity(entity.Domain, entity.EntityID)
+		hassEntity, err := c.GetRuntime().Client(entity).GetEntity(entity.Domain, entity.EntityID)
 		if err != nil {
 			l.Error().
 				Err(err).
@@ -108,40 +110,24 @@ func (c *FreshnessChecker) Check() {
 	}
 
 	// Prepare warning message to send
-	sender := GetSender(c.Sender)
-	if c.Template == "" {
-		c.Template = DefaultFreshnessCheckerTemplateString
-	}
-	tmpl, err := template.New("messageSender").
-		Funcs(template.FuncMap{"JoinEntities": model.JoinEntities}).
-		Parse(c.Template)
-	if err != nil {
-		l.Error().
-			Err(err).
-			Str("template", c.Template).
-			Msg("an error occurred compiling template")
-		if err := sender.Send(c.getErrorMessage(err), nil); err != nil {
-			l.Error().Err(err).
-				Str("sender", c.Sender).
-				Msg("unable to send message to sender")
-		}
-		return
+	sender := c.GetRuntime().GetSender(c.Sender)
+	tmpl := c.Template
+	if tmpl.IsZero() {
+		tmpl = defaultFreshnessCheckerTemplate
 	}
-
-	buf := bytes.NewBufferString("")
-	err = tmpl.Execute(buf, struct {
-		Checker  FreshnessChecker
+	msg, err := tmpl.Execute(c.GetRuntime(), struct {
+		Checker  *FreshnessChecker
 		Entities []model.HassEntity
 	}{
-		Checker:  *c,
+		Checker:  c,
 		Entities: notFreshEntities,
 	})
 	if err != nil {
 		l.Error().
 			Err(err).
-			Str("template", c.Template).
+			Stringer("template", tmpl).
 			Msg("an error occurred executing template")
-		if err := sender.Send(c.getErrorMessage(err), nil); err != nil {
+		if err := sender.Send(c.getErrorMessage(tmpl, err), nil); err != nil {
 			l.Error().Err(err).
 				Str("sender", c.Sender).
 				Msg("unable to send message to sender")
@@ -149,7 +135,6 @@ func (c *FreshnessChecker) Check() {
 		return
 	}
 
-	msg := strings.Trim(buf.String(), " ")
 	l.Debug().
 		Str("msg", msg).
 		Str("sender", c.Sender).
@@ -169,12 +154,21 @@ func (c *FreshnessChecker) Check() {
 
 }
 
-func (c *FreshnessChecker) getErrorMessage(err error) messaging.Message {
+func (c *FreshnessChecker) getErrorMessage(tmpl templating.Template, err error) messaging.Message {
 	return messaging.Message{
-		Content: fmt.Sprintf("FreshnessChecker: cannot compile template %s, err=%s", c.Template, err.Error()),
+		Content: fmt.Sprintf("FreshnessChecker: cannot execute template %s, err=%s", tmpl, err.Error()),
 	}
 }
 
 func (c *FreshnessChecker) GinHandler(ctx *gin.Context) {
-	ctx.JSON(http.StatusOK, *c)
+	ctx.JSON(http.StatusOK, c)
+}
+
+// Validate checks that the sender exists, the template is compiled when decoded
+func (c *FreshnessChecker) Validate() []error {
+	var errs []error
+	if c.GetRuntime().GetSender(c.Sender) == nil {
+		errs = append(errs, fmt.Errorf("sender: %q is not configured", c.Sender))
+	}
+	return errs
 }
@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/model/config"
@@ -23,24 +23,51 @@ const (
 	setTemperatureService    = "set_temperature"
 	climateTurnOffService    = "turn_off"
 	climateTurnOnService     = "turn_on"
+
+	// currentTemperatureAttributeName is the temperature measured by a climate
+	currentTemperatureAttributeName = "current_temperature"
 )
 
 var (
-	_ core.Modular = (*HeaterChecker)(nil)
+	_ core.Modular      = (*HeaterChecker)(nil)
+	_ core.Validatable  = (*HeaterChecker)(nil)
+	_ core.EventWatcher = (*HeaterChecker)(nil)
 )
 
 // HeaterChecker sets the heater's thermostat based on schedules
 type HeaterChecker struct {
 	core.Module   `mapstructure:",squash"`
 	SchedulesFile string `mapstructure:"schedules_file"`
+	// HolidaysFile lists exceptions taking precedence over the schedules, it can be shared by several heaters
+	HolidaysFile string `mapstructure:"holidays_file"`
+	// Preheat starts comfort schedules early enough to reach their temperature when they begin
+	Preheat core.PreheatConfig `mapstructure:"preheat"`
+
+	configMutex         sync.Mutex
+	configFileWatcher   config.FileWatcher
+	holidaysFileWatcher config.FileWatcher
+	schedules           *core.HeaterSchedules
+	holidays            []core.HeaterException
+	preheater           *core.Preheater
+	windowDetector      core.WindowDetector
+	// watched is read by Watches without waiting for a check holding configMutex to be done
+	watched atomic.Pointer[heaterWatched]
+}
 
-	configMutex       sync.Mutex
-	configFileWatcher config.FileWatcher
-	schedules         *core.HeaterSchedules
+// heaterWatched are the entities whose changes have to be checked right away to detect open windows
+type heaterWatched struct {
+	windowSensors []model.HassEntity
+	// thermostat's current temperature is watched if temperature drops are detected, nil otherwise
+	thermostat *model.HassEntity
 }
 
 // Check runs a single check
 func (h *HeaterChecker) Check() {
+	h.check(time.Now())
+}
+
+// check runs a single check at now
+func (h *HeaterChecker) check(now time.Time) {
 	l := logging.NewLogger("Heater.Check").With().Str("module", h.GetName()).Logger()
 
 	// Initial configuration and config change handling
@@ -67,10 +94,8 @@ func (h *HeaterChecker) Check() {
 		return
 	}
 
-	now := time.Now()
-
 	// Getting climate entity
-	climateEntity, err := httpclient.GetSimpleClient().GetEntity(h.schedules.Thermostat.Domain, h.schedules.Thermostat.EntityID)
+	climateEntity, err := h.GetRuntime().Client(h.schedules.Thermostat).GetEntity(h.schedules.Thermostat.Domain, h.schedules.Thermostat.EntityID)
 	if err != nil {
 		l.Error().Err(err).Msg("Unable to get current thermostat temperature")
 		return
@@ -78,7 +103,7 @@ func (h *HeaterChecker) Check() {
 
 	// Getting last seen entity for this climate
 	if h.schedules.LastSeen.Enabled {
-		lastSeenEntity, err := httpclient.GetSimpleClient().GetEntity(h.schedules.LastSeen.Entity.Domain, h.schedules.LastSeen.Entity.EntityID)
+		lastSeenEntity, err := h.GetRuntime().Client(h.schedules.LastSeen.Entity).GetEntity(h.schedules.LastSeen.Entity.Domain, h.schedules.LastSeen.Entity.EntityID)
 		if err != nil {
 			l.Error().Err(err).
 				Str("entity", h.schedules.LastSeen.Entity.GetEntityIDFullName()).
@@ -103,7 +128,7 @@ func (h *HeaterChecker) Check() {
 				Dur("duration", h.schedules.LastSeen.OfflineAfter).
 				Str("entity", h.schedules.LastSeen.Entity.GetEntityIDFullName()).
 				Msg("Entity has not been seen, setting it to off")
-			if err := httpclient.GetSimpleClient().CallService(climateEntity, climateTurnOffService, map[string]interface{}{}); err != nil {
+			if err := h.GetRuntime().Client(climateEntity).CallService(climateEntity, climateTurnOffService, map[string]interface{}{}); err != nil {
 				l.Error().Err(err).
 					Str("entity", climateEntity.GetEntityIDFullName()).
 					Msg("Cannot turn off climate")
@@ -118,7 +143,7 @@ func (h *HeaterChecker) Check() {
 	}
 
 	// Getting manual override status
-	overrideEntity, err := httpclient.GetSimpleClient().GetEntity(h.schedules.ManualOverride.Domain, h.schedules.ManualOverride.EntityID)
+	overrideEntity, err := h.GetRuntime().Client(h.schedules.ManualOverride).GetEntity(h.schedules.ManualOverride.Domain, h.schedules.ManualOverride.EntityID)
 	if err != nil {
 		l.Warn().Err(err).Msg("Error getting manual_override entity from Home Assistant")
 	}
@@ -127,6 +152,11 @@ func (h *HeaterChecker) Check() {
 		return
 	}
 
+	// Suspending schedules while a window is open
+	if h.schedules.HasWindowDetection() && h.checkWindows(now, climateEntity) {
+		return
+	}
+
 	// Checking for dates first
 	if h.schedules.DateBegin.After(now) && h.schedules.DateEnd.Before(now) {
 		l.Debug().
@@ -135,7 +165,7 @@ func (h *HeaterChecker) Check() {
 			Time("end_date", time.Time(h.schedules.DateEnd)).
 			Msg("Current date is NOT between begin and end, nothing to do")
 		// Ensuring heater climate is off
-		if err := httpclient.GetSimpleClient().CallService(climateEntity, climateTurnOffService, map[string]interface{}{}); err != nil {
+		if err := h.GetRuntime().Client(climateEntity).CallService(climateEntity, climateTurnOffService, map[string]interface{}{}); err != nil {
 			l.Warn().Err(err).
 				Str("entity", climateEntity.GetEntityIDFullName()).
 				Msg("Cannot turn off climate")
@@ -150,7 +180,7 @@ func (h *HeaterChecker) Check() {
 	}
 
 	// Ensuring climate is on
-	if err := httpclient.GetSimpleClient().CallService(climateEntity, climateTurnOnService, map[string]interface{}{}); err != nil {
+	if err := h.GetRuntime().Client(climateEntity).CallService(climateEntity, climateTurnOnService, map[string]interface{}{}); err != nil {
 		l.Warn().Err(err).
 			Str("entity", climateEntity.GetEntityIDFullName()).
 			Msg("Cannot turn on climate, continuing anyway")
@@ -158,6 +188,9 @@ func (h *HeaterChecker) Check() {
 
 	// Computing correct temperature depending on time
 	tempToSet := h.schedules.GetTemperatureToSet(now)
+	if h.Preheat.Enabled {
+		tempToSet = h.preheat(now, climateEntity, tempToSet)
+	}
 	currentTemp, ok := (climateEntity.State.Attributes[temperatureAttributeName]).(float64)
 
 	l = l.With().
@@ -166,7 +199,7 @@ func (h *HeaterChecker) Check() {
 		Float64("cur_temp", currentTemp).Logger()
 
 	if !ok || tempToSet != currentTemp {
-		err := httpclient.GetSimpleClient().CallService(
+		err := h.GetRuntime().Client(climateEntity).CallService(
 			climateEntity,
 			setTemperatureService,
 			map[string]interface{}{
@@ -183,6 +216,114 @@ func (h *HeaterChecker) Check() {
 	}
 }
 
+// preheat returns the comfort temperature of the next schedule if heating has to start now to reach it in time,
+// tempToSet otherwise. It learns how fast the thermostat warms up from the temperatures set.
+func (h *HeaterChecker) preheat(now time.Time, climateEntity model.HassEntity, tempToSet float64) float64 {
+	l := logging.NewLogger("Heater.preheat").With().
+		Str("module", h.GetName()).
+		Str("climate", h.schedules.Thermostat.GetEntityIDFullName()).
+		Logger()
+
+	currentTemp, ok := climateEntity.State.Attributes[currentTemperatureAttributeName].(float64)
+	if !ok {
+		l.Warn().Msg("Current temperature is not available, cannot preheat")
+		return tempToSet
+	}
+
+	thermostat := h.schedules.Thermostat.GetEntityIDFullName()
+	if h.preheater == nil || h.preheater.GetThermostat() != thermostat {
+		h.preheater = core.NewPreheater(h.Preheat, thermostat)
+	}
+
+	if beg, comfort, ok := h.schedules.NextComfort(now, h.preheater.GetMaxLead()); ok && comfort > tempToSet {
+		lead := h.preheater.Lead(currentTemp, comfort)
+		if !now.Before(beg.Add(-lead)) {
+			l.Info().
+				Time("beg", beg).
+				Dur("lead", lead).
+				Float64("comfort", comfort).
+				Float64("cur_temp", currentTemp).
+				Msg("Preheating for the next comfort schedule")
+			tempToSet = comfort
+		}
+	}
+
+	h.preheater.Observe(now, currentTemp, tempToSet)
+	return tempToSet
+}
+
+// checkWindows returns true if schedules are suspended because a window is open, in which case
+// the climate is set to the window open temperature or turned off
+func (h *HeaterChecker) checkWindows(now time.Time, climateEntity model.HassEntity) bool {
+	l := logging.NewLogger("Heater.checkWindows").With().
+		Str("module", h.GetName()).
+		Str("climate", h.schedules.Thermostat.GetEntityIDFullName()).
+		Logger()
+
+	var openSensors []model.HassEntity
+	for _, sensor := range h.schedules.WindowSensors {
+		entity, err := h.GetRuntime().Client(sensor).GetEntity(sensor.Domain, sensor.EntityID)
+		if err != nil {
+			l.Warn().Err(err).
+				Str("entity", sensor.GetEntityIDFullName()).
+				Msg("Unable to get window sensor, considering it closed")
+			continue
+		}
+		if entity.State.IsON() {
+			openSensors = append(openSensors, sensor)
+		}
+	}
+
+	var temperature *float64
+	if currentTemp, ok := climateEntity.State.Attributes[currentTemperatureAttributeName].(float64); ok {
+		temperature = &currentTemp
+	}
+
+	wasSuspended := h.windowDetector.GetState().Suspended
+	state := h.windowDetector.Update(now, h.schedules, openSensors, temperature)
+	if !state.Suspended {
+		if wasSuspended {
+			l.Info().Msg("Windows have been closed long enough, resuming schedules")
+		}
+		return false
+	}
+
+	l = l.With().Str("reason", state.Reason).Time("since", state.Since).Logger()
+	if !wasSuspended {
+		l.Info().Msg("Window open, suspending schedules")
+	}
+
+	if h.schedules.WindowOpenTemperature == nil {
+		if err := h.GetRuntime().Client(climateEntity).CallService(climateEntity, climateTurnOffService, map[string]interface{}{}); err != nil {
+			l.Error().Err(err).Msg("Cannot turn off climate")
+		}
+		return true
+	}
+
+	// The window open temperature is useless if the climate is off
+	if climateEntity.State.State == model.StateOFF {
+		if err := h.GetRuntime().Client(climateEntity).CallService(climateEntity, climateTurnOnService, map[string]interface{}{}); err != nil {
+			l.Warn().Err(err).Msg("Cannot turn on climate, continuing anyway")
+		}
+	}
+
+	tempToSet := *h.schedules.WindowOpenTemperature
+	if currentTemp, ok := climateEntity.State.Attributes[temperatureAttributeName].(float64); ok && currentTemp == tempToSet {
+		l.Debug().Msg("Window open temperature already set, nothing to do")
+		return true
+	}
+	err := h.GetRuntime().Client(climateEntity).CallService(
+		climateEntity,
+		setTemperatureService,
+		map[string]interface{}{
+			temperatureAttributeName: tempToSet,
+		})
+	if err != nil {
+		l.Error().Err(err).Float64("temp", tempToSet).Msg("Unable to set window open temperature for climate")
+	}
+	return true
+}
+
 func (h *HeaterChecker) initSchedulesConfig() error {
 	if h.schedules != nil {
 		return nil
@@ -199,15 +340,46 @@ func (h *HeaterChecker) initSchedulesConfig() error {
 		if err == nil {
 			h.configMutex.Lock()
 			h.schedules = data.(*core.HeaterSchedules)
+			h.schedules.Holidays = h.holidays
 			defer h.configMutex.Unlock()
+			h.setWatched(h.schedules)
 			h.printDebugSchedules()
 		}
 	})
 
 	routines.AddRunnable(h.configFileWatcher)
+	if err := h.initHolidaysConfig(); err != nil {
+		return err
+	}
 	return h.configFileWatcher.Start()
 }
 
+func (h *HeaterChecker) initHolidaysConfig() error {
+	if h.HolidaysFile == "" || h.holidaysFileWatcher != nil {
+		return nil
+	}
+
+	l := logging.NewLogger("Heater.initHolidaysConfig")
+	l.Info().Str("filename", h.HolidaysFile).Msg("Configuring heater holidays")
+
+	h.holidaysFileWatcher = config.NewFileWatcher(h.HolidaysFile, func() interface{} {
+		return &core.HeaterHolidays{}
+	})
+	h.holidaysFileWatcher.AddOnReloadCallbacks(func(data interface{}, err error) {
+		if err == nil {
+			h.configMutex.Lock()
+			defer h.configMutex.Unlock()
+			h.holidays = data.(*core.HeaterHolidays).Exceptions
+			if h.schedules != nil {
+				h.schedules.Holidays = h.holidays
+			}
+		}
+	})
+
+	routines.AddRunnable(h.holidaysFileWatcher)
+	return h.holidaysFileWatcher.Start()
+}
+
 func (h *HeaterChecker) printDebugSchedules() {
 	l := logging.NewLogger("Heater.printDebugSchedules").With().Str("filename", h.SchedulesFile).Logger()
 	l.Debug().EmbedObject(h.schedules).Msg("Reloading heater's config")
@@ -247,18 +419,73 @@ func (h *HeaterChecker) GetManualOverrideEntity() (model.HassEntity, error) {
 	return h.schedules.ManualOverride, nil
 }
 
+// setWatched sets the entities watched for schedules
+func (h *HeaterChecker) setWatched(schedules *core.HeaterSchedules) {
+	watched := &heaterWatched{windowSensors: schedules.WindowSensors}
+	if schedules.TemperatureDrop.Degrees > 0 {
+		thermostat := schedules.Thermostat
+		watched.thermostat = &thermostat
+	}
+	h.watched.Store(watched)
+}
+
+// Watches returns true if e is a window sensor changing or the thermostat's current temperature changing
+// when temperature drops are detected, windows are then checked without waiting for the next interval
+func (h *HeaterChecker) Watches(e *model.HassEvent) bool {
+	watched := h.watched.Load()
+	if watched == nil {
+		return false
+	}
+
+	entity := e.GetEntity()
+	if len(watched.windowSensors) > 0 && entity.IsContained(watched.windowSensors) {
+		return e.Event.Data.OldState.State != e.Event.Data.NewState.State
+	}
+	if watched.thermostat != nil && entity.Equals(*watched.thermostat) {
+		return stateValue(e.Event.Data.OldState, currentTemperatureAttributeName) !=
+			stateValue(e.Event.Data.NewState, currentTemperatureAttributeName)
+	}
+	return false
+}
+
+// Validate checks the preheat configuration
+func (h *HeaterChecker) Validate() []error {
+	var errs []error
+	if err := h.Preheat.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
 // GinHandler godoc
 func (h *HeaterChecker) GinHandler(c *gin.Context) {
+	h.configMutex.Lock()
+	defer h.configMutex.Unlock()
+
+	var preheatState *core.PreheatState
+	if h.preheater != nil {
+		state := h.preheater.GetState()
+		preheatState = &state
+	}
+
 	obj := struct {
 		*core.Module
 		Name          string
 		SchedulesFile string
+		HolidaysFile  string
 		Schedules     *core.HeaterSchedules
+		Preheat       core.PreheatConfig
+		PreheatState  *core.PreheatState
+		Window        core.WindowState
 	}{
 		Module:        &h.Module,
 		Name:          h.Name,
 		SchedulesFile: h.SchedulesFile,
+		HolidaysFile:  h.HolidaysFile,
 		Schedules:     h.schedules,
+		Preheat:       h.Preheat,
+		PreheatState:  preheatState,
+		Window:        h.windowDetector.GetState(),
 	}
 
 	c.JSON(http.StatusOK, obj)
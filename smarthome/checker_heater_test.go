@@ -0,0 +1,230 @@
+package smarthome
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+)
+
+func TestHeaterChecker_Check(t *testing.T) {
+	turnOn := hasstest.ServiceCall{Domain: "climate", Service: "turn_on", EntityID: []string{"climate.living"}, Data: map[string]any{}}
+	turnOff := hasstest.ServiceCall{Domain: "climate", Service: "turn_off", EntityID: []string{"climate.living"}, Data: map[string]any{}}
+	setTemperature := hasstest.ServiceCall{Domain: "climate", Service: "set_temperature", EntityID: []string{"climate.living"}, Data: map[string]any{"temperature": float64(16)}}
+
+	preheatTemperature := hasstest.ServiceCall{Domain: "climate", Service: "set_temperature", EntityID: []string{"climate.living"}, Data: map[string]any{"temperature": float64(20)}}
+	frostTemperature := hasstest.ServiceCall{Domain: "climate", Service: "set_temperature", EntityID: []string{"climate.living"}, Data: map[string]any{"temperature": float64(7)}}
+	frost := float64(7)
+
+	tests := []struct {
+		name           string
+		climateState   string
+		temperature    float64
+		manualOverride string
+		lastSeen       time.Duration
+		preheat        core.PreheatConfig
+		window         string
+		windowOpenTemp *float64
+		want           []hasstest.ServiceCall
+	}{
+		{
+			name:           "set_temperature",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			want:           []hasstest.ServiceCall{turnOn, setTemperature},
+		},
+		{
+			name:           "temperature_already_set",
+			temperature:    16,
+			manualOverride: model.StateOFF,
+			want:           []hasstest.ServiceCall{turnOn},
+		},
+		{
+			name:           "manual_override",
+			temperature:    19,
+			manualOverride: model.StateON,
+		},
+		{
+			name:           "seen_recently",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			lastSeen:       10 * time.Minute,
+			want:           []hasstest.ServiceCall{turnOn, setTemperature},
+		},
+		{
+			name:           "not_seen_for_too_long",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			lastSeen:       2 * time.Hour,
+			want:           []hasstest.ServiceCall{turnOff},
+		},
+		{
+			name:           "preheat",
+			temperature:    16,
+			manualOverride: model.StateOFF,
+			preheat:        core.PreheatConfig{Enabled: true},
+			want:           []hasstest.ServiceCall{turnOn, preheatTemperature},
+		},
+		{
+			name:           "preheat_too_early",
+			temperature:    16,
+			manualOverride: model.StateOFF,
+			preheat:        core.PreheatConfig{Enabled: true, MaxLead: 10 * time.Minute},
+			want:           []hasstest.ServiceCall{turnOn},
+		},
+		{
+			name:           "window_closed",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			window:         model.StateOFF,
+			want:           []hasstest.ServiceCall{turnOn, setTemperature},
+		},
+		{
+			name:           "window_open",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			window:         model.StateON,
+			want:           []hasstest.ServiceCall{turnOff},
+		},
+		{
+			name:           "window_open_frost_temperature",
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			window:         model.StateON,
+			windowOpenTemp: &frost,
+			want:           []hasstest.ServiceCall{frostTemperature},
+		},
+		{
+			name:           "window_open_frost_temperature_climate_off",
+			climateState:   model.StateOFF,
+			temperature:    19,
+			manualOverride: model.StateOFF,
+			window:         model.StateON,
+			windowOpenTemp: &frost,
+			want:           []hasstest.ServiceCall{turnOn, frostTemperature},
+		},
+	}
+	// Fixed time so that the comfort schedule never spans over midnight
+	now := time.Date(2024, time.January, 10, 10, 0, 0, 0, time.Local)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			climateState := tt.climateState
+			if climateState == "" {
+				climateState = "heat"
+			}
+			srv := newFakeHass(t,
+				model.HassState{EntityID: "climate.living", State: climateState, Attributes: map[string]any{"temperature": tt.temperature, "current_temperature": 15.0}},
+				model.HassState{EntityID: "input_boolean.heater_override", State: tt.manualOverride},
+				model.HassState{EntityID: "sensor.heater_last_seen", State: now.Add(-tt.lastSeen).Format(time.RFC3339)},
+				model.HassState{EntityID: "binary_sensor.living_window", State: tt.window},
+			)
+
+			// Comfort begins in 20 minutes, it takes 75 minutes to warm up from 15 to 20 degrees at the default rate
+			beg := now.Add(20 * time.Minute)
+			h := new(HeaterChecker)
+			h.Bind(newFakeHassRuntime(srv))
+			h.Preheat = tt.preheat
+			h.schedules = &core.HeaterSchedules{
+				Scheds: map[core.SchedulesDays][]core.HeaterSchedule{
+					"week,weekend": {{Beg: beg, End: beg.Add(time.Hour), Comfort: 20, Eco: 16}},
+				},
+				DefaultEco:     16,
+				Thermostat:     model.NewHassEntity("climate.living"),
+				ManualOverride: model.NewHassEntity("input_boolean.heater_override"),
+			}
+			if tt.lastSeen > 0 {
+				h.schedules.LastSeen.Enabled = true
+				h.schedules.LastSeen.Entity = model.NewHassEntity("sensor.heater_last_seen")
+				h.schedules.LastSeen.OfflineAfter = time.Hour
+			}
+			if tt.window != "" {
+				h.schedules.WindowSensors = []model.HassEntity{model.NewHassEntity("binary_sensor.living_window")}
+				h.schedules.WindowOpenTemperature = tt.windowOpenTemp
+			}
+
+			h.check(now)
+
+			if got := srv.ServiceCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServiceCalls() = %+v, want %+v", got, tt.want)
+			}
+			if got, want := h.windowDetector.GetState().Suspended, tt.window == model.StateON; got != want {
+				t.Errorf("Suspended = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHeaterChecker_Watches(t *testing.T) {
+	event := func(entityID string, oldState, newState model.HassState) *model.HassEvent {
+		evt := &model.HassEvent{}
+		evt.Event.EventType = "state_changed"
+		evt.Event.Data.EntityID = entityID
+		evt.Event.Data.OldState = oldState
+		evt.Event.Data.NewState = newState
+		return evt
+	}
+	climate := func(currentTemperature float64) model.HassState {
+		return model.HassState{State: "heat", Attributes: map[string]any{"current_temperature": currentTemperature}}
+	}
+
+	tests := []struct {
+		name            string
+		notLoaded       bool
+		temperatureDrop float64
+		event           *model.HassEvent
+		want            bool
+	}{
+		{
+			name:  "window_opened",
+			event: event("binary_sensor.living_window", model.HassState{State: model.StateOFF}, model.HassState{State: model.StateON}),
+			want:  true,
+		},
+		{
+			name:  "window_attributes_only",
+			event: event("binary_sensor.living_window", model.HassState{State: model.StateON}, model.HassState{State: model.StateON}),
+		},
+		{
+			name:      "schedules_not_loaded",
+			notLoaded: true,
+			event:     event("binary_sensor.living_window", model.HassState{State: model.StateOFF}, model.HassState{State: model.StateON}),
+		},
+		{
+			name:            "current_temperature_changed",
+			temperatureDrop: 2,
+			event:           event("climate.living", climate(19), climate(18.5)),
+			want:            true,
+		},
+		{
+			name:            "current_temperature_unchanged",
+			temperatureDrop: 2,
+			event:           event("climate.living", climate(19), climate(19)),
+		},
+		{
+			name:  "current_temperature_without_temperature_drop",
+			event: event("climate.living", climate(19), climate(18.5)),
+		},
+		{
+			name:  "other_entity",
+			event: event("binary_sensor.kitchen_window", model.HassState{State: model.StateOFF}, model.HassState{State: model.StateON}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := new(HeaterChecker)
+			if !tt.notLoaded {
+				h.setWatched(&core.HeaterSchedules{
+					Thermostat:      model.NewHassEntity("climate.living"),
+					WindowSensors:   []model.HassEntity{model.NewHassEntity("binary_sensor.living_window")},
+					TemperatureDrop: core.TemperatureDrop{Degrees: tt.temperatureDrop},
+				})
+			}
+
+			if got := h.Watches(tt.event); got != tt.want {
+				t.Errorf("Watches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-ping/ping"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 )
@@ -68,9 +67,9 @@ func (c *InternetChecker) Check() {
 		l.Error().Err(errors.New("connection failed")).
 			Msg("100% packet lost, rebooting router")
 		// Rebooting
-		httpclient.GetSimpleClient().CallService(c.RestartEntity, "turn_off", nil)
+		c.GetRuntime().Client(c.RestartEntity).CallService(c.RestartEntity, "turn_off", nil)
 		time.Sleep(1 * time.Second)
-		httpclient.GetSimpleClient().CallService(c.RestartEntity, "turn_on", nil)
+		c.GetRuntime().Client(c.RestartEntity).CallService(c.RestartEntity, "turn_on", nil)
 		c.lastReboot = time.Now()
 	} else if !isTimeBetweenRebootOK {
 		l.Warn().
@@ -81,5 +80,5 @@ func (c *InternetChecker) Check() {
 
 // GinHandler godoc
 func (c *InternetChecker) GinHandler(ctx *gin.Context) {
-	ctx.JSON(http.StatusOK, *c)
+	ctx.JSON(http.StatusOK, c)
 }
@@ -104,5 +104,5 @@ func (c *OpenMQTTGatewayWBListChecker) publishConfig(client mqtt.Client, prefix
 
 // GinHandler godoc
 func (c *OpenMQTTGatewayWBListChecker) GinHandler(ctx *gin.Context) {
-	ctx.JSON(http.StatusOK, *c)
+	ctx.JSON(http.StatusOK, c)
 }
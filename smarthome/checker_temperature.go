@@ -1,21 +1,19 @@
 package smarthome
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/smarthome/messaging"
+	"github.com/nmaupu/gotomation/templating"
 	"strconv"
-	"strings"
-	"text/template"
 	"time"
 )
 
 var (
-	_ core.Modular = (*TemperatureChecker)(nil)
+	_ core.Modular     = (*TemperatureChecker)(nil)
+	_ core.Validatable = (*TemperatureChecker)(nil)
 )
 
 const (
@@ -24,6 +22,10 @@ const (
 	DefaultTemperatureCheckerTemplate            = `The following sensors have exceeded the temperature threshold: {{ JoinEntities .Entities ", " }}`
 )
 
+var (
+	defaultTemperatureCheckerTemplate = templating.MustParse(DefaultTemperatureCheckerTemplate)
+)
+
 type TemperatureChecker struct {
 	core.Module `mapstructure:",squash"`
 	// Sensors are the entities to check for temperature threshold
@@ -31,11 +33,11 @@ type TemperatureChecker struct {
 		Entity        model.HassEntity `mapstructure:"entity"`
 		TempThreshold float64          `mapstructure:"temp_threshold"`
 	} `mapstructure:"sensors"`
-	DateBegin           model.DayMonthDate `mapstructure:"date_begin"`
-	DateEnd             model.DayMonthDate `mapstructure:"date_end"`
-	Sender              string             `mapstructure:"sender"`
-	SendMessageInterval time.Duration      `mapstructure:"send_message_interval"`
-	Template            string             `mapstructure:"template"`
+	DateBegin           model.DayMonthDate  `mapstructure:"date_begin"`
+	DateEnd             model.DayMonthDate  `mapstructure:"date_end"`
+	Sender              string              `mapstructure:"sender"`
+	SendMessageInterval time.Duration       `mapstructure:"send_message_interval"`
+	Template            templating.Template `mapstructure:"template"`
 
 	lastMessageSentTime map[string]time.Time
 }
@@ -72,7 +74,7 @@ func (c *TemperatureChecker) Check() {
 			sendMsgInterval = DefaultTemperatureCheckerSendMessageInterval
 		}
 
-		hassEntity, err := httpclient.GetSimpleClient().GetEntity(entity.Domain, entity.EntityID)
+		hassEntity, err := c.GetRuntime().Client(entity).GetEntity(entity.Domain, entity.EntityID)
 		if err != nil {
 			l.Error().
 				Err(err).
@@ -113,40 +115,24 @@ func (c *TemperatureChecker) Check() {
 		c.lastMessageSentTime[e.GetEntityIDFullName()] = now
 	}
 
-	sender := GetSender(c.Sender)
-	if c.Template == "" {
-		c.Template = DefaultTemperatureCheckerTemplate
-	}
-	tmpl, err := template.New("messageSender").
-		Funcs(template.FuncMap{"JoinEntities": model.JoinEntities}).
-		Parse(c.Template)
-	if err != nil {
-		l.Error().
-			Err(err).
-			Str("template", c.Template).
-			Msg("an error occurred compiling template")
-		if err := sender.Send(c.getErrorMessage(err), nil); err != nil {
-			l.Error().Err(err).
-				Str("sender", c.Sender).
-				Msg("unable to send message to sender")
-		}
-		return
+	sender := c.GetRuntime().GetSender(c.Sender)
+	tmpl := c.Template
+	if tmpl.IsZero() {
+		tmpl = defaultTemperatureCheckerTemplate
 	}
-
-	buf := bytes.NewBufferString("")
-	err = tmpl.Execute(buf, struct {
-		Checker  TemperatureChecker
+	msg, err := tmpl.Execute(c.GetRuntime(), struct {
+		Checker  *TemperatureChecker
 		Entities []model.HassEntity
 	}{
-		Checker:  *c,
+		Checker:  c,
 		Entities: problematicEntities,
 	})
 	if err != nil {
 		l.Error().
 			Err(err).
-			Str("template", c.Template).
+			Stringer("template", tmpl).
 			Msg("an error occurred executing template")
-		if err := sender.Send(c.getErrorMessage(err), nil); err != nil {
+		if err := sender.Send(c.getErrorMessage(tmpl, err), nil); err != nil {
 			l.Error().Err(err).
 				Str("sender", c.Sender).
 				Msg("unable to send message to sender")
@@ -154,7 +140,6 @@ func (c *TemperatureChecker) Check() {
 		return
 	}
 
-	msg := strings.Trim(buf.String(), " ")
 	l.Debug().
 		Str("msg", msg).
 		Str("sender", c.Sender).
@@ -174,8 +159,17 @@ func (c *TemperatureChecker) Check() {
 
 }
 
-func (c *TemperatureChecker) getErrorMessage(err error) messaging.Message {
+func (c *TemperatureChecker) getErrorMessage(tmpl templating.Template, err error) messaging.Message {
 	return messaging.Message{
-		Content: fmt.Sprintf("TemperatureChecker: cannot compile template %s, err=%s", c.Template, err.Error()),
+		Content: fmt.Sprintf("TemperatureChecker: cannot execute template %s, err=%s", tmpl, err.Error()),
+	}
+}
+
+// Validate checks that the sender exists, the template is compiled when decoded
+func (c *TemperatureChecker) Validate() []error {
+	var errs []error
+	if c.GetRuntime().GetSender(c.Sender) == nil {
+		errs = append(errs, fmt.Errorf("sender: %q is not configured", c.Sender))
 	}
+	return errs
 }
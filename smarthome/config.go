@@ -3,7 +3,9 @@ package smarthome
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -47,6 +49,8 @@ const (
 	TriggerRandomLights = "randomlights"
 	// TriggerAlertBool is a module to send alerts to a specific sender depending on binary entity state change
 	TriggerAlertBool = "alert"
+	// TriggerRule runs actions described in the configuration when events match and conditions are met
+	TriggerRule = "rule"
 )
 
 var (
@@ -90,43 +94,67 @@ var (
 		TriggerAlertBool: func() core.Actionable {
 			return new(AlertTriggerBool)
 		},
+		TriggerRule: func() core.Actionable {
+			return new(RuleTrigger)
+		},
 	}
 )
 
 var (
 	// mutex is used to lock maps' access by one goroutine only
-	mutex      sync.RWMutex
-	mCheckers  map[string][]core.Checkable
-	mTriggers  map[string][]core.Triggerable
+	mutex     sync.RWMutex
+	mCheckers map[string][]core.Checkable
+	mTriggers map[string][]core.Triggerable
+	// mRouting gives the triggers to call for an event
+	mRouting   *core.RoutingIndex
 	crontab    core.Crontab
 	mSenders   map[string]messaging.Sender
 	mOMGConfig *config.OpenMQTTGatewayConfig
+	// mInstances are the named Home Assistant instances
+	mInstances map[string]core.HassInstance
+	// eventPool runs triggers when events are received
+	eventPool *core.WorkerPool
 )
 
 // Init inits checkers from configuration
+// When a configuration is already running, only the triggers, checkers, crons and senders
+// whose configuration changed are restarted. Everything is restarted if Home Assistant's configuration
+// or the home's coordinates changed.
 func Init(config config.Gotomation) error {
 	l := logging.NewLogger("Init")
+
+	if needsFullReload(&config) {
+		StopAndWait()
+	}
+
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	routines.ResetRunnablesList()
-	initHTTPClients(&config)
+	fullReload := running == nil
+	if fullReload {
+		routines.ResetRunnablesList()
+		initEventPool(&config)
+		initHTTPClients(&config)
 
-	if err := initZone(&config); err != nil {
-		l.Error().Err(err).
-			Str("zone_name", config.HomeAssistant.HomeZoneName).
-			Msg("Unable to get coordinates from zone name")
-		return err
+		if err := initCoordinates(&config); err != nil {
+			l.Error().Err(err).Msg("Unable to get coordinates")
+			return err
+		}
+
+		initHTTPServer(&config)
 	}
 
-	initHTTPServer(&config)
-	initGoogle(&config)
-	initSenderConfigs(&config)
-	initTriggers(&config)
-	initCheckers(&config)
-	initCrons(&config)
+	if fullReload || !reflect.DeepEqual(running.Google, config.Google) {
+		initGoogle(&config)
+	}
+	runtime := newRuntime(&config)
+	initSenderConfigs(&config, runtime)
+	initTriggers(&config, runtime)
+	initCheckers(&config, runtime)
+	initCrons(&config, runtime)
 	initOMGConfig(&config)
 	routines.StartAllRunnables()
+	running = &config
 	return nil
 }
 
@@ -135,32 +163,96 @@ func StopAndWait() {
 	l := logging.NewLogger("Stop")
 
 	l.Info().Msg("Stopping services")
+	// Releasing first to cancel the actions still running, workers and routines would wait for them otherwise
+	mutex.Lock()
+	for _, trigger := range configuredTriggers {
+		trigger.item.Release()
+	}
+	for _, ce := range configuredCrons {
+		ce.item.Release()
+	}
+	mutex.Unlock()
+
 	routines.StopAllRunnables()
 
 	app.RoutinesWG.Wait()
 	routines.ResetRunnablesList()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	running = nil
+	eventPool = nil
+	mRouting = nil
+	configuredTriggers = nil
+	configuredCheckers = nil
+	configuredCrons = nil
+	configuredSenders = nil
+	crontab = nil
 	l.Debug().Msg("All go routines terminated")
 }
 
+func initEventPool(config *config.Gotomation) {
+	eventPool = core.NewWorkerPool(config.Dispatcher.Workers, config.Dispatcher.QueueDepth)
+	routines.AddRunnable(eventPool)
+}
+
 func initHTTPClients(config *config.Gotomation) {
-	simpleClientScheme := "https"
-	if !config.HomeAssistant.TLSEnabled {
-		simpleClientScheme = "http"
-	}
-	httpclient.InitSimpleClient(simpleClientScheme, config.HomeAssistant.Host, config.HomeAssistant.Token, config.HomeAssistant.HealthCheckEntities)
+	l := logging.NewLogger("initHTTPClients")
 
-	if config.HomeAssistant.Enabled {
-		websocketClientScheme := "wss"
-		if !config.HomeAssistant.TLSEnabled {
-			websocketClientScheme = "ws"
-		}
-		httpclient.InitWebSocketClient(websocketClientScheme, config.HomeAssistant.Host, config.HomeAssistant.Token)
+	// Default instance, used by entities without instance prefix
+	hass, _ := config.GetHomeAssistant("")
+	simpleClientScheme, websocketClientScheme := hassSchemes(hass)
+	httpclient.InitSimpleClient(simpleClientScheme, hass.Host, hass.Token, hass.HealthCheckEntities)
+
+	if hass.Enabled {
+		httpclient.InitWebSocketClient(websocketClientScheme, hass.Host, hass.Token, hass.HealthCheckEntities)
 		routines.AddRunnable(httpclient.GetWebSocketClient())
 
 		// Adding callbacks for server communication, start and subscribe to events
 		httpclient.GetWebSocketClient().RegisterCallback("event", EventCallback, model.HassEvent{})
-		httpclient.GetWebSocketClient().SubscribeEvents(config.HomeAssistant.SubscribeEvents...)
+		httpclient.GetWebSocketClient().SubscribeEvents(hass.SubscribeEvents...)
 	}
+
+	// Named instances
+	mInstances = make(map[string]core.HassInstance, 0)
+	for _, hass := range config.HomeAssistant {
+		if hass.Name == "" {
+			continue
+		}
+		if !hass.Enabled {
+			l.Warn().Str("instance", hass.Name).Msg("Home Assistant instance is disabled, skipping.")
+			continue
+		}
+
+		l.Info().Str("instance", hass.Name).Msg("Initializing Home Assistant instance")
+		simpleClientScheme, websocketClientScheme := hassSchemes(hass)
+		wsc := httpclient.NewWebSocketClient(model.HassConfig{
+			URL:                 url.URL{Scheme: websocketClientScheme, Host: hass.Host, Path: "api/websocket"},
+			Token:               hass.Token,
+			HealthCheckEntities: hass.HealthCheckEntities,
+		})
+		routines.AddRunnable(wsc)
+		wsc.RegisterCallback("event", instanceEventCallback(hass.Name), model.HassEvent{})
+		wsc.SubscribeEvents(hass.SubscribeEvents...)
+
+		sc := httpclient.NewSimpleClient(model.HassConfig{
+			URL:                 url.URL{Scheme: simpleClientScheme, Host: hass.Host, Path: "api"},
+			Token:               hass.Token,
+			HealthCheckEntities: hass.HealthCheckEntities,
+		})
+		mInstances[hass.Name] = core.HassInstance{
+			SimpleClient:    httpclient.NewInstanceClient(hass.Name, httpclient.NewWebSocketFirstClient(sc, wsc)),
+			WebSocketClient: wsc,
+		}
+	}
+}
+
+// hassSchemes returns the schemes to use to reach hass' REST and WebSocket APIs
+func hassSchemes(hass config.HomeAssistantConfig) (string, string) {
+	if !hass.TLSEnabled {
+		return "http", "ws"
+	}
+	return "https", "wss"
 }
 
 func initGoogle(config *config.Gotomation) {
@@ -181,27 +273,73 @@ func initGoogle(config *config.Gotomation) {
 	}
 }
 
-func initSenderConfigs(config *config.Gotomation) {
-	var err error
+// initSenderConfigs builds the senders using runtime's client and sets them to runtime
+func initSenderConfigs(config *config.Gotomation, runtime *core.Runtime) {
 	l := logging.NewLogger("initSenderConfigs")
 
 	// (Re)init sender configs map
+	olds := configuredSenders
+	configuredSenders = make([]configured[messaging.Sender], 0, len(config.Senders))
 	mSenders = make(map[string]messaging.Sender, 0)
 
 	for _, senderConfig := range config.Senders {
-		mSenders[senderConfig.Name], err = senderConfig.GetSender()
+		key := senderConfigKey(senderConfig)
+		if old, ok := reuse(&olds, senderConfig.Name, key); ok {
+			configuredSenders = append(configuredSenders, old)
+			mSenders[senderConfig.Name] = old.item
+			continue
+		}
+
+		l.Info().Str("name", senderConfig.Name).Msg("Initializing sender")
+		sender, err := senderConfig.GetSender(runtime.SimpleClient)
 		if err != nil {
 			l.Error().
 				Err(err).
 				Str("name", senderConfig.Name).
 				Msg("Unable to configure sender")
 		}
+		mSenders[senderConfig.Name] = sender
+		configuredSenders = append(configuredSenders, configured[messaging.Sender]{name: senderConfig.Name, config: key, item: sender})
 	}
+	runtime.Senders = mSenders
+
+	// Releasing senders removed from the configuration
+	for _, old := range olds {
+		l.Info().Str("name", old.name).Msg("Removing sender")
+		if releasable, ok := old.item.(core.Releasable); ok {
+			releasable.Release()
+		}
+	}
+}
+
+// newRuntime builds the dependencies bound to every trigger, checker and cron
+// Senders are set by initSenderConfigs.
+func newRuntime(config *config.Gotomation) *core.Runtime {
+	runtime := &core.Runtime{
+		SimpleClient: httpclient.GetSimpleClient(),
+		Instances:    mInstances,
+		Checkers:     GetCheckersByType,
+		Automates:    GetAutomatesByName,
+	}
+
+	if hass, _ := config.GetHomeAssistant(""); hass.Enabled {
+		runtime.WebSocketClient = httpclient.GetWebSocketClient()
+	}
+	runtime.Coordinates = core.Coords()
+
+	if config.Google.CredentialsFile != "" {
+		runtime.GoogleConfig = thirdparty.GetGoogleConfig()
+	}
+
+	return runtime
 }
 
-func initTriggers(config *config.Gotomation) {
+func initTriggers(config *config.Gotomation, runtime *core.Runtime) {
 	l := logging.NewLogger("initTriggers")
+	olds := configuredTriggers
+	configuredTriggers = make([]configured[core.Triggerable], 0)
 	mTriggers = make(map[string][]core.Triggerable, 0)
+	toInitialize := make([]core.Triggerable, 0)
 
 	for _, trigger := range config.Triggers {
 		for tn, triggerConfig := range trigger {
@@ -209,6 +347,15 @@ func initTriggers(config *config.Gotomation) {
 			// we force it lower cased. Consequently, if the bug is one day fixed, this will continue to work as expected
 			// See: https://github.com/spf13/viper/issues/1014
 			triggerName := strings.ToLower(tn)
+
+			// Keeping the running trigger if its configuration did not change
+			if old, ok := reuse(&olds, triggerName, triggerConfig); ok {
+				old.item.GetActionable().Bind(runtime)
+				configuredTriggers = append(configuredTriggers, old)
+				mTriggers[triggerName] = append(mTriggers[triggerName], old.item)
+				continue
+			}
+
 			trigger := new(core.Trigger)
 
 			// Getting the action from the existing list
@@ -220,6 +367,7 @@ func initTriggers(config *config.Gotomation) {
 				continue
 			}
 			action := ftrig()
+			action.Bind(runtime)
 
 			if err := trigger.Configure(triggerConfig, action); err != nil {
 				l.Error().Err(err).
@@ -233,37 +381,56 @@ func initTriggers(config *config.Gotomation) {
 				Bool("enabled", trigger.Action.IsEnabled()).
 				Msg("Initializing trigger")
 
-			if mTriggers[triggerName] == nil {
-				mTriggers[triggerName] = make([]core.Triggerable, 0)
-			}
+			configuredTriggers = append(configuredTriggers, configured[core.Triggerable]{name: triggerName, config: triggerConfig, item: trigger})
 			mTriggers[triggerName] = append(mTriggers[triggerName], trigger)
+			toInitialize = append(toInitialize, trigger)
 		}
 	}
 
-	httpservice.HTTPServer().AddExtraHandlers(httpservice.GinConfigHandlers{
-		Path:     "/trigger/:name",
-		Handlers: []gin.HandlerFunc{triggerGinHandler},
-	})
+	// Releasing triggers removed from the configuration
+	for _, old := range olds {
+		l.Info().
+			Str("trigger", old.name).
+			Msg("Removing trigger")
+		if eventPool != nil {
+			eventPool.Remove(old.item)
+		}
+		old.item.Release()
+	}
 
-	// Call all triggers that needs an initialization with a dummy event
-	for _, triggers := range mTriggers {
-		for _, trig := range triggers {
-			if trig.GetActionable().NeedsInitialization() {
-				evt := model.DummyEvent // make a copy before passing a pointer
-				trig.GetActionable().Trigger(&evt)
-			}
+	routed := make([]core.Triggerable, 0, len(configuredTriggers))
+	for _, trigger := range configuredTriggers {
+		routed = append(routed, trigger.item)
+	}
+	mRouting = core.NewRoutingIndex(routed)
+
+	// Call all new triggers that needs an initialization with a dummy event
+	for _, trig := range toInitialize {
+		if trig.GetActionable().NeedsInitialization() {
+			evt := model.DummyEvent // make a copy before passing a pointer
+			trig.GetActionable().Trigger(&evt)
 		}
 	}
 }
 
-func initCheckers(config *config.Gotomation) {
+func initCheckers(config *config.Gotomation, runtime *core.Runtime) {
 	l := logging.NewLogger("initCheckers")
 
 	// (Re)init checkers map
+	olds := configuredCheckers
+	configuredCheckers = make([]configured[core.Checkable], 0)
 	mCheckers = make(map[string][]core.Checkable, 0)
 
 	for _, module := range config.Modules {
 		for moduleName, moduleConfig := range module {
+			// Keeping the running checker if its configuration did not change
+			if old, ok := reuse(&olds, moduleName, moduleConfig); ok {
+				old.item.GetModular().Bind(runtime)
+				configuredCheckers = append(configuredCheckers, old)
+				mCheckers[moduleName] = append(mCheckers[moduleName], old.item)
+				continue
+			}
+
 			checker := new(core.Checker)
 
 			// Getting the module from the existing list
@@ -275,6 +442,7 @@ func initCheckers(config *config.Gotomation) {
 				continue
 			}
 			module := fmod()
+			module.Bind(runtime)
 
 			if err := checker.Configure(moduleConfig, module); err != nil {
 				l.Error().Err(err).
@@ -288,42 +456,67 @@ func initCheckers(config *config.Gotomation) {
 				Bool("enabled", checker.Module.IsEnabled()).
 				Msg("Initializing checker")
 
-			if mCheckers[moduleName] == nil {
-				mCheckers[moduleName] = make([]core.Checkable, 0)
-			}
+			configuredCheckers = append(configuredCheckers, configured[core.Checkable]{name: moduleName, config: moduleConfig, item: checker})
 			mCheckers[moduleName] = append(mCheckers[moduleName], checker)
 			routines.AddRunnable(checker)
 		}
 	}
 
-	httpservice.HTTPServer().AddExtraHandlers(httpservice.GinConfigHandlers{
-		Path:     "/checker/:name",
-		Handlers: []gin.HandlerFunc{checkerGinHandler},
-	})
+	// Stopping checkers removed from the configuration
+	for _, old := range olds {
+		l.Info().
+			Str("module", old.name).
+			Msg("Removing checker")
+		old.item.Stop()
+		routines.RemoveRunnable(old.item)
+	}
 }
 
-func initCrons(config *config.Gotomation) {
+func initCrons(config *config.Gotomation, runtime *core.Runtime) {
 	l := logging.NewLogger("initCrons")
-	if crontab != nil {
-		crontab.Stop()
+	if crontab == nil {
+		crontab = core.NewCrontab()
+		routines.AddRunnable(crontab)
 	}
 
-	crontab := core.NewCrontab()
-	routines.AddRunnable(crontab)
-
 	l.Info().Msg("Initializing all crons")
+	olds := configuredCrons
+	configuredCrons = make([]configured[*core.CronEntry], 0, len(config.Crons))
 	for _, cronConfig := range config.Crons {
+		// Keeping the running cron if its configuration did not change
+		if old, ok := reuse(&olds, "", cronConfig); ok {
+			old.item.Bind(runtime)
+			configuredCrons = append(configuredCrons, old)
+			continue
+		}
+
 		ce := new(core.CronEntry)
 		if err := ce.Configure(cronConfig, nil); err != nil {
 			l.Error().Err(err).Msg("Unable to decode configuration for cron")
 			continue
 		}
 
-		if err := crontab.AddFunc(ce.Expr, ce.GetActionFunc()); err != nil {
+		schedule, err := core.ParseSchedule(ce.Expr, runtime.Coordinates)
+		if err != nil {
 			l.Error().Err(err).
 				Str("expr", ce.Expr).
 				Msg("Unable to add func for cron")
+			continue
 		}
+		if _, ok := schedule.(*core.SunSchedule); ok && runtime.Coordinates == nil {
+			l.Error().
+				Str("expr", ce.Expr).
+				Msg("Coordinates are not available, cron relative to the sun will never run")
+		}
+		ce.Bind(runtime)
+		ce.EntryID = crontab.AddSchedule(schedule, ce.GetActionFunc())
+		configuredCrons = append(configuredCrons, configured[*core.CronEntry]{config: cronConfig, item: ce})
+	}
+
+	// Removing crons removed from the configuration
+	for _, old := range olds {
+		crontab.Remove(old.item.EntryID)
+		old.item.Release()
 	}
 }
 
@@ -331,43 +524,91 @@ func initOMGConfig(config *config.Gotomation) {
 	mOMGConfig = &config.OpenMQTTGateway
 }
 
-func initZone(config *config.Gotomation) error {
-	l := logging.NewLogger("initZone")
+// initCoordinates sets the home's coordinates from the location configuration
+// or from the default Home Assistant instance's home zone
+func initCoordinates(config *config.Gotomation) error {
+	l := logging.NewLogger("initCoordinates")
 
-	if !config.HomeAssistant.Enabled {
-		l.Warn().Msg("Home Assistant is disabled, skipping.")
-		return nil
-	}
-
-	var err error
-	err = core.InitCoordinates(config.HomeAssistant.HomeZoneName)
+	coords, err := resolveCoordinates(config)
 	if err != nil {
 		return err
 	}
-	routines.AddRunnable(core.Coords())
+	if coords == nil {
+		l.Warn().Msg("No location configured and Home Assistant is disabled, sun related features are not available")
+		core.SetCoords(nil)
+		return nil
+	}
+
+	core.SetCoords(coords)
+	routines.AddRunnable(coords)
 
 	l.Debug().
-		Float64("latitude", core.Coords().GetLatitude()).
-		Float64("longitude", core.Coords().GetLongitude()).
+		Float64("latitude", coords.GetLatitude()).
+		Float64("longitude", coords.GetLongitude()).
+		Str("timezone", coords.GetLocation().String()).
 		Msg("GPS coordinates retrieved")
 
 	return nil
 }
 
+// resolveCoordinates returns the home's coordinates from the location configuration
+// or from the default Home Assistant instance's home zone, nil if none of them is available
+func resolveCoordinates(config *config.Gotomation) (core.Coordinates, error) {
+	hass, _ := config.GetHomeAssistant("")
+	switch {
+	case config.Location.IsSet():
+		if err := config.Location.Validate(); err != nil {
+			return nil, err
+		}
+		loc, _ := config.Location.GetLocation()
+		return core.NewCoordinatesInLocation(*config.Location.Latitude, *config.Location.Longitude, loc), nil
+	case hass.Enabled:
+		coords, err := core.ZoneCoordinates(httpclient.GetSimpleClient(), hass.HomeZoneName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get coordinates from zone %s: %w", hass.HomeZoneName, err)
+		}
+		return coords, nil
+	default:
+		return nil, nil
+	}
+}
+
 func initHTTPServer(config *config.Gotomation) {
 	//l := logging.NewLogger("initHTTPServer")
 
-	httpservice.InitHTTPServer("0.0.0.0", httpservice.DefaultHTTPPort)
+	httpservice.InitHTTPServer("0.0.0.0", httpservice.DefaultHTTPPort,
+		httpservice.GinConfigHandlers{
+			Path:     "/trigger/:name",
+			Handlers: []gin.HandlerFunc{httpservice.RedactSecrets, triggerGinHandler},
+		},
+		httpservice.GinConfigHandlers{
+			Path:     "/checker/:name",
+			Handlers: []gin.HandlerFunc{httpservice.RedactSecrets, checkerGinHandler},
+		},
+		httpservice.GinConfigHandlers{
+			Path:     "/dispatcher",
+			Handlers: []gin.HandlerFunc{dispatcherGinHandler},
+		})
 	routines.AddRunnable(httpservice.HTTPServer())
 }
 
+// instanceEventCallback returns the callback to call when a listen event occurs on the Home Assistant instance called name
+func instanceEventCallback(name string) func(msg model.HassAPIObject) {
+	return func(msg model.HassAPIObject) {
+		msg.(*model.HassEvent).Instance = name
+		EventCallback(msg)
+	}
+}
+
 // EventCallback is called when a listen event occurs
+// Matching triggers are queued to the event pool so that they do not run while holding the lock on the configuration,
+// checkers watching the event are asked to check right away.
 func EventCallback(msg model.HassAPIObject) {
 	l := logging.NewLogger("EventCallback")
 	mutex.RLock()
 	defer mutex.RUnlock()
 
-	if mTriggers == nil || len(mTriggers) == 0 {
+	if eventPool == nil {
 		return
 	}
 
@@ -377,55 +618,84 @@ func EventCallback(msg model.HassAPIObject) {
 		EmbedObject(event).
 		Msg("Event received by the callback func")
 
-	// Look for the entity
-	for _, triggers := range mTriggers {
-		for _, t := range triggers {
-			if !t.GetActionable().IsEnabled() {
-				continue
-			}
-
-			// Checking event types if defined
-			toTriggerEvents := core.StringInSliceP(event.Event.EventType, t.GetActionable().GetEventTypesForTrigger())
-
-			eventEntity := model.NewHassEntity(event.Event.Data.EntityID)
-			toTriggerEntities := eventEntity.IsContained(t.GetActionable().GetEntitiesForTrigger())
+	for _, t := range mRouting.Match(event) {
+		if !t.GetActionable().IsEnabled() {
+			continue
+		}
+		// Object's trigger func is called by a worker, debounced, throttled or delayed if configured so
+		eventPool.Enqueue(t, event)
+	}
 
-			if toTriggerEvents || toTriggerEntities {
-				// Call object's trigger func
-				t.GetActionable().Trigger(event)
+	for _, checkables := range mCheckers {
+		for _, ch := range checkables {
+			watcher, ok := ch.GetModular().(core.EventWatcher)
+			if ok && ch.GetModular().IsEnabled() && watcher.Watches(event) {
+				l.Debug().Str("checker", ch.GetName()).Msg("Event watched by checker, checking now")
+				ch.CheckNow()
 			}
 		}
 	}
 }
 
+func dispatcherGinHandler(c *gin.Context) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if eventPool == nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, model.NewAPIError(fmt.Errorf("Dispatcher is not running")))
+		return
+	}
+	eventPool.GinHandler(c)
+}
+
 func checkerGinHandler(c *gin.Context) {
 	name := c.Params.ByName("name")
 
+	// The handler is called without holding the lock, checks can take a while
+	if modular := findChecker(name); modular != nil {
+		modular.GinHandler(c)
+		return
+	}
+
+	c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(fmt.Errorf("Unable to find checker %s", name)))
+}
+
+// findChecker returns the module of the checker called name, nil if not found
+func findChecker(name string) core.Modular {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	for _, checkables := range mCheckers {
 		for _, ch := range checkables {
 			if path.Base(ch.GetName()) == name { // Removing any */ in the name
-				ch.GetModular().GinHandler(c)
-				return
+				return ch.GetModular()
 			}
 		}
 	}
-
-	c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(fmt.Errorf("Unable to find checker %s", name)))
+	return nil
 }
 
 func triggerGinHandler(c *gin.Context) {
 	name := c.Params.ByName("name")
 
+	if actionable := findTrigger(name); actionable != nil {
+		actionable.GinHandler(c)
+		return
+	}
+
+	c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(fmt.Errorf("Unable to find trigger %s", name)))
+}
+
+// findTrigger returns the action of the trigger called name, nil if not found
+func findTrigger(name string) core.Actionable {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	for _, triggers := range mTriggers {
 		for _, tr := range triggers {
 			if path.Base(tr.GetName()) == name { // Removing any */ in the name
-				tr.GetActionable().GinHandler(c)
-				return
+				return tr.GetActionable()
 			}
 		}
 	}
-
-	c.AbortWithStatusJSON(http.StatusNotFound, model.NewAPIError(fmt.Errorf("Unable to find trigger %s", name)))
+	return nil
 }
 
 // GetCheckersByType returns all checkers corresponding to a given name
@@ -437,15 +707,32 @@ func GetCheckersByType(name string) []core.Checkable {
 	return mCheckers[name]
 }
 
-func GetSender(name string) messaging.Sender {
+// GetAutomatesByName returns the checkers' modules and the triggers' actions called name
+// name is either the name given in the configuration or a module or trigger type such as heaterchecker
+func GetAutomatesByName(name string) []core.Automate {
+	if name == "" {
+		return nil
+	}
+
 	mutex.RLock()
 	defer mutex.RUnlock()
-	sender, ok := mSenders[name]
-	if !ok {
-		return nil
-	} else {
-		return sender
+
+	automates := make([]core.Automate, 0)
+	for moduleName, checkables := range mCheckers {
+		for _, ch := range checkables {
+			if strings.EqualFold(moduleName, name) || ch.GetModular().GetName() == name {
+				automates = append(automates, ch.GetModular())
+			}
+		}
+	}
+	for triggerName, triggerables := range mTriggers {
+		for _, tr := range triggerables {
+			if strings.EqualFold(triggerName, name) || tr.GetActionable().GetName() == name {
+				automates = append(automates, tr.GetActionable())
+			}
+		}
 	}
+	return automates
 }
 
 func GetOMGConfig() config.OpenMQTTGatewayConfig {
@@ -0,0 +1,56 @@
+package smarthome
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/httpservice"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/rs/zerolog"
+)
+
+func TestCheckerGinHandler_redactSecrets(t *testing.T) {
+	const secret = "ping-s3cr3t.example.com"
+	t.Setenv("GOTOMATION_TEST_PING_HOST", secret)
+	t.Cleanup(StopAndWait)
+
+	initCheckers(&config.Gotomation{
+		Modules: []map[string]any{{
+			ModuleInternetChecker: map[string]any{
+				"name":      "internet",
+				"ping_host": "${env:GOTOMATION_TEST_PING_HOST}",
+			},
+		}},
+	}, new(core.Runtime))
+
+	checker := mCheckers[ModuleInternetChecker][0].GetModular().(*InternetChecker)
+	if checker.PingHost != secret {
+		t.Fatalf("PingHost = %q, want the resolved secret %q", checker.PingHost, secret)
+	}
+
+	var logs bytes.Buffer
+	l := zerolog.New(logging.NewRedactWriter(&logs))
+	l.Info().Str("ping_host", checker.PingHost).Msg("Pinging")
+	if strings.Contains(logs.String(), secret) {
+		t.Errorf("secret written to the log: %s", logs.String())
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/checker/:name", httpservice.RedactSecrets, checkerGinHandler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checker/internet", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /checker/internet status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if body := rec.Body.String(); strings.Contains(body, secret) || !strings.Contains(body, logging.RedactedValue) {
+		t.Errorf("GET /checker/internet = %s, want the secret redacted", body)
+	}
+}
@@ -0,0 +1,104 @@
+package smarthome
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+func TestCronEntry_GetActionFunc(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       map[string]any
+		want         []hasstest.ServiceCall
+		wantDisabled bool
+		wantMessages []string
+	}{
+		{
+			name: "action",
+			config: map[string]any{
+				"expr":     "0 1 * * *",
+				"action":   "turn_off",
+				"entities": []any{"light.living", "switch.fan"},
+			},
+			want: []hasstest.ServiceCall{
+				{Domain: "light", Service: "turn_off", EntityID: []string{"light.living"}, Data: map[string]any{}},
+				{Domain: "switch", Service: "turn_off", EntityID: []string{"switch.fan"}, Data: map[string]any{}},
+			},
+		},
+		{
+			name: "action_service_data",
+			config: map[string]any{
+				"expr":         "0 1 * * *",
+				"action":       "turn_on",
+				"entities":     []any{"light.living"},
+				"service_data": map[string]any{"brightness": 20},
+			},
+			want: []hasstest.ServiceCall{
+				{Domain: "light", Service: "turn_on", EntityID: []string{"light.living"}, Data: map[string]any{"brightness": float64(20)}},
+			},
+		},
+		{
+			name: "steps",
+			config: map[string]any{
+				"expr": "0 22 * * *",
+				"steps": []any{
+					map[string]any{"entity": "climate.living", "service": "set_temperature", "data": map[string]any{"temperature": 17}},
+					map[string]any{"disable": []any{"heaterchecker"}, "sender": "test", "message": "Heaters disabled", "delay": "10ms"},
+					map[string]any{"entity": "light.living", "service": "turn_off"},
+				},
+			},
+			want: []hasstest.ServiceCall{
+				{Domain: "climate", Service: "set_temperature", EntityID: []string{"climate.living"}, Data: map[string]any{"temperature": float64(17)}},
+				{Domain: "light", Service: "turn_off", EntityID: []string{"light.living"}, Data: map[string]any{}},
+			},
+			wantDisabled: true,
+			wantMessages: []string{"Heaters disabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeHass(t,
+				model.HassState{EntityID: "light.living", State: model.StateON},
+				model.HassState{EntityID: "switch.fan", State: model.StateON},
+				model.HassState{EntityID: "climate.living", State: "heat"},
+			)
+
+			heater := new(HeaterChecker)
+			sender := &recordingSender{}
+			runtime := newFakeHassRuntime(srv)
+			runtime.Senders = map[string]messaging.Sender{"test": sender}
+			runtime.Automates = func(name string) []core.Automate {
+				if name == ModuleHeaterChecker {
+					return []core.Automate{heater}
+				}
+				return nil
+			}
+
+			ce := new(core.CronEntry)
+			if err := config.NewStrictMapstructureDecoder(ce).Decode(tt.config); err != nil {
+				t.Fatalf("unable to decode cron entry, err=%v", err)
+			}
+			t.Cleanup(ce.Release)
+			ce.Bind(runtime)
+			ce.GetActionFunc()()
+
+			if got := srv.ServiceCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServiceCalls() = %+v, want %+v", got, tt.want)
+			}
+			if got := !heater.IsEnabled(); got != tt.wantDisabled {
+				t.Errorf("heater disabled = %v, want %v", got, tt.wantDisabled)
+			}
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if !reflect.DeepEqual(sender.messages, tt.wantMessages) {
+				t.Errorf("messages = %v, want %v", sender.messages, tt.wantMessages)
+			}
+		})
+	}
+}
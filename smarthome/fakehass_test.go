@@ -0,0 +1,107 @@
+package smarthome
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/httpclient"
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+)
+
+// newFakeHass starts a fake Home Assistant server knowing states
+func newFakeHass(t *testing.T, states ...model.HassState) *hasstest.Server {
+	t.Helper()
+	srv := hasstest.NewServer(states...)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newFakeHassClient returns a SimpleClient talking to srv
+func newFakeHassClient(srv *hasstest.Server) httpclient.SimpleClient {
+	return httpclient.NewSimpleClient(model.HassConfig{
+		URL:   url.URL{Scheme: "http", Host: srv.Host(), Path: "api"},
+		Token: srv.Token,
+	})
+}
+
+// newFakeHassRuntime returns a Runtime whose SimpleClient talks to srv
+func newFakeHassRuntime(srv *hasstest.Server) *core.Runtime {
+	return &core.Runtime{
+		SimpleClient: newFakeHassClient(srv),
+		Instances:    make(map[string]core.HassInstance),
+	}
+}
+
+// listenFakeHass starts a WebSocketClient on srv, adds it to the Runtime bound to action
+// and forwards state_changed events to action like EventCallback does.
+// The returned channel receives a value each time action's Trigger returns.
+func listenFakeHass(t *testing.T, srv *hasstest.Server, action core.Actionable) <-chan struct{} {
+	t.Helper()
+	return listenFakeHassInstance(t, srv, "", action)
+}
+
+// listenFakeHassInstance is like listenFakeHass with srv being the Home Assistant instance called instance
+// The Runtime bound to action gets this instance's clients.
+func listenFakeHassInstance(t *testing.T, srv *hasstest.Server, instance string, action core.Actionable) <-chan struct{} {
+	t.Helper()
+	triggered := make(chan struct{}, 16)
+
+	wsc := httpclient.NewWebSocketClient(model.HassConfig{
+		URL:   url.URL{Scheme: "ws", Host: srv.Host(), Path: "api/websocket"},
+		Token: srv.Token,
+	})
+	runtime := action.GetRuntime()
+	if instance == "" {
+		runtime.WebSocketClient = wsc
+		runtime.SimpleClient = httpclient.NewWebSocketFirstClient(newFakeHassClient(srv), wsc)
+	} else {
+		runtime.Instances[instance] = core.HassInstance{
+			SimpleClient:    httpclient.NewInstanceClient(instance, httpclient.NewWebSocketFirstClient(newFakeHassClient(srv), wsc)),
+			WebSocketClient: wsc,
+		}
+	}
+	wsc.RegisterCallback("event", func(msg model.HassAPIObject) {
+		event := msg.(*model.HassEvent)
+		event.Instance = instance
+		if event.GetEntity().IsContained(action.GetEntitiesForTrigger()) {
+			action.Trigger(event)
+			triggered <- struct{}{}
+		}
+	}, model.HassEvent{})
+	wsc.SubscribeEvents("state_changed")
+	if err := wsc.Start(); err != nil {
+		t.Fatalf("unable to start WebSocketClient, err=%v", err)
+	}
+	t.Cleanup(wsc.Stop)
+
+	// Once states are synced, the subscription sent before has been handled by the server
+	deadline := time.Now().Add(5 * time.Second)
+	for wsc.ConnectionState() != httpclient.ConnectionReady || wsc.States().IsStale() {
+		if time.Now().After(deadline) {
+			t.Fatalf("WebSocketClient is not ready, state=%s", wsc.ConnectionState())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return triggered
+}
+
+// waitTriggered waits for action's Trigger to return
+func waitTriggered(t *testing.T, triggered <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-triggered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("action has not been triggered")
+	}
+}
+
+// configureAction decodes data into action like initTriggers does
+func configureAction(t *testing.T, data map[string]any, action core.Actionable) {
+	t.Helper()
+	if err := new(core.Trigger).Configure(data, action); err != nil {
+		t.Fatalf("unable to configure action, err=%v", err)
+	}
+}
@@ -12,6 +12,13 @@ var (
 
 type StatusLedSender struct {
 	Entity model.HassEntity `mapstructure:"entity" json:"entity"`
+
+	client httpclient.SimpleClient
+}
+
+// SetClient sets the client used to drive the LED
+func (t *StatusLedSender) SetClient(client httpclient.SimpleClient) {
+	t.client = client
 }
 
 // Send sends a message via a LED
@@ -24,5 +31,8 @@ func (t *StatusLedSender) Send(_ Message, event *model.HassEvent) error {
 	if event.Event.Data.NewState.State == model.StateON {
 		action = "turn_on"
 	}
-	return httpclient.GetSimpleClient().CallService(t.Entity, action, nil)
+	if t.client == nil {
+		return fmt.Errorf("no client set to drive %s", t.Entity.GetEntityIDFullName())
+	}
+	return t.client.CallService(t.Entity, action, nil)
 }
@@ -0,0 +1,86 @@
+package smarthome
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+// configured is an object built from a configuration entry
+// It is kept across reloads as long as its name and configuration do not change
+type configured[T any] struct {
+	name   string
+	config any
+	item   T
+}
+
+var (
+	// running is the configuration currently running, nil if nothing is running
+	running *config.Gotomation
+	// configured objects by the running configuration
+	configuredTriggers []configured[core.Triggerable]
+	configuredCheckers []configured[core.Checkable]
+	configuredCrons    []configured[*core.CronEntry]
+	configuredSenders  []configured[messaging.Sender]
+)
+
+// reuse looks for an object of olds built from the same name and configuration
+// The object is removed from olds so that it cannot be reused twice
+func reuse[T any](olds *[]configured[T], name string, config any) (configured[T], bool) {
+	for i, old := range *olds {
+		if old.name == name && reflect.DeepEqual(old.config, config) {
+			*olds = append((*olds)[:i:i], (*olds)[i+1:]...)
+			return old, true
+		}
+	}
+	return configured[T]{}, false
+}
+
+// needsFullReload returns true if everything has to be restarted to run config
+// It is the case when nothing is running yet, when Home Assistant's, the location's or the dispatcher's
+// configuration changed or when the home's coordinates changed, such as the home zone being moved.
+// The current coordinates are kept if they cannot be resolved again.
+func needsFullReload(config *config.Gotomation) bool {
+	l := logging.NewLogger("needsFullReload")
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if running == nil ||
+		!reflect.DeepEqual(running.HomeAssistant, config.HomeAssistant) ||
+		!reflect.DeepEqual(running.Location, config.Location) ||
+		running.Dispatcher != config.Dispatcher {
+		return true
+	}
+
+	coords, err := resolveCoordinates(config)
+	if err != nil {
+		l.Warn().Err(err).Msg("Unable to get coordinates, keeping the current ones")
+		return false
+	}
+	if !sameCoordinates(core.Coords(), coords) {
+		l.Info().Msg("Coordinates changed, restarting everything")
+		return true
+	}
+	return false
+}
+
+// sameCoordinates returns true if a and b are the same place in the same time zone, or both unknown
+func sameCoordinates(a, b core.Coordinates) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.GetLatitude() == b.GetLatitude() &&
+		a.GetLongitude() == b.GetLongitude() &&
+		a.GetLocation().String() == b.GetLocation().String()
+}
+
+// senderConfigKey returns a comparable representation of a sender's configuration
+// Senders' configurations hold pointers which are modified once the sender is built
+// so only their exported fields are compared
+func senderConfigKey(senderConfig config.SenderConfig) any {
+	data, _ := json.Marshal(senderConfig) // only made of strings and numbers
+	return string(data)
+}
@@ -0,0 +1,274 @@
+package smarthome
+
+import (
+	"testing"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+func TestInit_reload(t *testing.T) {
+	dehumidifier := func(thresholdMax int) map[string]any {
+		return map[string]any{
+			TriggerDehumidifier: map[string]any{
+				"name":             "dehumidifier",
+				"trigger_entities": []any{"sensor.humidity"},
+				"switch_entity":    "switch.dehumidifier",
+				"threshold_max":    thresholdMax,
+			},
+		}
+	}
+	internet := func(name string) map[string]any {
+		return map[string]any{
+			ModuleInternetChecker: map[string]any{"name": name},
+		}
+	}
+	cron := func(expr string) any {
+		return map[string]any{"expr": expr, "action": "turn_off", "entities": []any{"light.living"}}
+	}
+	first := config.Gotomation{
+		Triggers: []map[string]any{dehumidifier(60)},
+		Modules:  []map[string]any{internet("internet"), internet("internet2")},
+		Crons:    []any{cron("0 1 * * *")},
+	}
+
+	tests := []struct {
+		name         string
+		config       config.Gotomation
+		keptTriggers int
+		keptCheckers int
+		keptCrons    int
+	}{
+		{
+			name:         "unchanged",
+			config:       first,
+			keptTriggers: 1,
+			keptCheckers: 2,
+			keptCrons:    1,
+		},
+		{
+			name: "trigger_changed",
+			config: config.Gotomation{
+				Triggers: []map[string]any{dehumidifier(70)},
+				Modules:  first.Modules,
+				Crons:    first.Crons,
+			},
+			keptCheckers: 2,
+			keptCrons:    1,
+		},
+		{
+			name: "checker_removed_cron_added",
+			config: config.Gotomation{
+				Triggers: first.Triggers,
+				Modules:  []map[string]any{internet("internet")},
+				Crons:    []any{cron("0 2 * * *"), cron("0 1 * * *")},
+			},
+			keptTriggers: 1,
+			keptCheckers: 1,
+			keptCrons:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(StopAndWait)
+
+			initAll := func(config *config.Gotomation) *core.Runtime {
+				runtime := new(core.Runtime)
+				initTriggers(config, runtime)
+				initCheckers(config, runtime)
+				initCrons(config, runtime)
+				return runtime
+			}
+
+			initAll(&first)
+			oldTriggers, oldCheckers, oldCrons := configuredTriggers, configuredCheckers, configuredCrons
+			runtime := initAll(&tt.config)
+
+			if got := countKept(oldTriggers, configuredTriggers); got != tt.keptTriggers {
+				t.Errorf("kept triggers = %d, want %d", got, tt.keptTriggers)
+			}
+			if got := countKept(oldCheckers, configuredCheckers); got != tt.keptCheckers {
+				t.Errorf("kept checkers = %d, want %d", got, tt.keptCheckers)
+			}
+			if got := countKept(oldCrons, configuredCrons); got != tt.keptCrons {
+				t.Errorf("kept crons = %d, want %d", got, tt.keptCrons)
+			}
+			if got, want := len(configuredCrons), len(tt.config.Crons); got != want {
+				t.Errorf("crons = %d, want %d", got, want)
+			}
+			// Kept crons run with the new senders and clients
+			for _, ce := range configuredCrons {
+				if ce.item.GetRuntime() != runtime {
+					t.Errorf("cron %v is not bound to the new runtime", ce.config)
+				}
+			}
+			if got, want := len(mCheckers[ModuleInternetChecker]), len(tt.config.Modules); got != want {
+				t.Errorf("checkers = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestInit_reloadCoordinates(t *testing.T) {
+	zone := func(latitude float64) model.HassState {
+		return model.HassState{
+			EntityID:   "zone.home",
+			State:      "0",
+			Attributes: map[string]any{"latitude": latitude, "longitude": 2.3522},
+		}
+	}
+	srv := newFakeHass(t, zone(48.8566))
+	t.Cleanup(func() { core.SetCoords(nil) })
+	t.Cleanup(StopAndWait)
+
+	cfg := config.Gotomation{
+		HomeAssistant: []config.HomeAssistantConfig{{Enabled: true, Host: srv.Host(), Token: srv.Token, HomeZoneName: "home"}},
+		Modules:       []map[string]any{{ModuleInternetChecker: map[string]any{"name": "internet"}}},
+	}
+	initConfig := func() {
+		t.Helper()
+		if err := Init(cfg); err != nil {
+			t.Fatalf("Init() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		latitude     float64
+		keptCheckers int
+	}{
+		{
+			name:         "zone_unchanged",
+			latitude:     48.8566,
+			keptCheckers: 1,
+		},
+		{
+			name:     "zone_moved",
+			latitude: 45.764,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.SetState(zone(48.8566))
+			initConfig()
+			oldCheckers, oldCoords := configuredCheckers, core.Coords()
+
+			srv.SetState(zone(tt.latitude))
+			initConfig()
+
+			if got := countKept(oldCheckers, configuredCheckers); got != tt.keptCheckers {
+				t.Errorf("kept checkers = %d, want %d", got, tt.keptCheckers)
+			}
+			coords := core.Coords()
+			if coords == nil || coords.GetLatitude() != tt.latitude {
+				t.Fatalf("Coords() = %v, want latitude %v", coords, tt.latitude)
+			}
+			if kept := coords == oldCoords; kept != (tt.keptCheckers > 0) {
+				t.Errorf("coordinates kept = %v, want %v", kept, tt.keptCheckers > 0)
+			}
+		})
+	}
+}
+
+// countKept returns the number of objects of olds still present in news
+func countKept[T comparable](olds, news []configured[T]) int {
+	kept := 0
+	for _, n := range news {
+		for _, o := range olds {
+			if n.item == o.item {
+				kept++
+			}
+		}
+	}
+	return kept
+}
+
+func TestInitCoordinates(t *testing.T) {
+	latitude, longitude := 48.8566, 2.3522
+
+	tests := []struct {
+		name         string
+		config       config.Gotomation
+		wantLocation string
+		wantCoords   bool
+		wantErr      bool
+	}{
+		{
+			name: "location",
+			config: config.Gotomation{
+				Location: config.LocationConfig{Latitude: &latitude, Longitude: &longitude, Timezone: "Europe/Paris"},
+			},
+			wantLocation: "Europe/Paris",
+			wantCoords:   true,
+		},
+		{
+			name: "location_not_valid",
+			config: config.Gotomation{
+				Location: config.LocationConfig{Latitude: &latitude},
+			},
+			wantErr: true,
+		},
+		{
+			name:   "no_location_without_home_assistant",
+			config: config.Gotomation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { core.SetCoords(nil) })
+			core.SetCoords(core.NewCoordinates(0, 0))
+
+			err := initCoordinates(&tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initCoordinates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			coords := core.Coords()
+			if (coords != nil) != tt.wantCoords {
+				t.Fatalf("Coords() = %v, want coordinates %v", coords, tt.wantCoords)
+			}
+			if coords == nil {
+				return
+			}
+			if coords.GetLatitude() != latitude || coords.GetLongitude() != longitude {
+				t.Errorf("Coords() = %v,%v, want %v,%v", coords.GetLatitude(), coords.GetLongitude(), latitude, longitude)
+			}
+			if got := coords.GetLocation().String(); got != tt.wantLocation {
+				t.Errorf("GetLocation() = %s, want %s", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestInitSenderConfigs(t *testing.T) {
+	telegram := func(name string, chatID int64) config.SenderConfig {
+		return config.SenderConfig{Name: name, Telegram: &messaging.TelegramSender{Token: "token", ChatID: chatID}}
+	}
+	t.Cleanup(func() {
+		configuredSenders = nil
+		mSenders = nil
+	})
+
+	first := new(core.Runtime)
+	initSenderConfigs(&config.Gotomation{Senders: []config.SenderConfig{telegram("kept", 1), telegram("changed", 2)}}, first)
+	oldSenders := configuredSenders
+
+	runtime := new(core.Runtime)
+	initSenderConfigs(&config.Gotomation{Senders: []config.SenderConfig{telegram("kept", 1), telegram("changed", 3), telegram("added", 4)}}, runtime)
+
+	if got := countKept(oldSenders, configuredSenders); got != 1 {
+		t.Errorf("kept senders = %d, want 1", got)
+	}
+	for _, name := range []string{"kept", "changed", "added"} {
+		if runtime.GetSender(name) == nil {
+			t.Errorf("sender %s is not set to the new runtime", name)
+		}
+	}
+	if got := len(first.Senders); got != 2 {
+		t.Errorf("previous runtime's senders = %d, want 2", got)
+	}
+}
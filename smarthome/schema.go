@@ -0,0 +1,111 @@
+package smarthome
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/model/config"
+)
+
+// ConfigSchema returns the JSON Schema of gotomation's configuration file
+// Modules and triggers are described from the checkers and triggers registries.
+func ConfigSchema() *config.Schema {
+	r := newSchemaReflector()
+	schema := r.Reflect(reflect.TypeOf(config.Gotomation{}))
+	schema.Schema = config.SchemaDraft
+	schema.Title = "gotomation configuration"
+
+	// A single instance can be given instead of a list
+	hass := schema.Properties["home_assistant"]
+	schema.Properties["home_assistant"] = &config.Schema{
+		AnyOf: []*config.Schema{hass.Items, hass},
+	}
+
+	setAutomatesSchemas(r, schema)
+
+	return schema
+}
+
+// FragmentSchema returns the JSON Schema of the files included by the configuration file
+func FragmentSchema() *config.Schema {
+	r := newSchemaReflector()
+	schema := r.Reflect(reflect.TypeOf(config.Fragment{}))
+	schema.Schema = config.SchemaDraft
+	schema.Title = "gotomation configuration fragment"
+	setAutomatesSchemas(r, schema)
+	return schema
+}
+
+// setAutomatesSchemas describes the modules, triggers and crons of schema
+func setAutomatesSchemas(r *config.SchemaReflector, schema *config.Schema) {
+	modules := make(map[string]reflect.Type, len(checkers))
+	for name, fmod := range checkers {
+		modules[name] = reflect.TypeOf(fmod())
+	}
+	schema.Properties["modules"] = registrySchema(r, modules)
+
+	trigs := make(map[string]reflect.Type, len(triggers))
+	for name, ftrig := range triggers {
+		trigs[name] = reflect.TypeOf(ftrig())
+	}
+	schema.Properties["triggers"] = registrySchema(r, trigs)
+
+	schema.Properties["crons"] = &config.Schema{
+		Type:  "array",
+		Items: r.Reflect(reflect.TypeOf(core.CronEntry{})),
+	}
+}
+
+// HeaterSchedulesSchema returns the JSON Schema of heaters' schedules files
+func HeaterSchedulesSchema() *config.Schema {
+	schema := newSchemaReflector().Reflect(reflect.TypeOf(core.HeaterSchedules{}))
+	schema.Schema = config.SchemaDraft
+	schema.Title = "gotomation heater schedules"
+	return schema
+}
+
+// newSchemaReflector returns a reflector aware of core's types
+func newSchemaReflector() *config.SchemaReflector {
+	r := config.NewSchemaReflector()
+	days := make([]string, 0, len(core.Days()))
+	for _, day := range core.Days() {
+		days = append(days, config.CaseInsensitivePattern(day))
+	}
+	day := strings.Join(days, "|")
+	r.Override(reflect.TypeOf(core.SchedulesDays("")), &config.Schema{
+		Type:        "string",
+		Pattern:     `^ *(` + day + `) *(, *(` + day + `) *)*$`,
+		Description: "Comma separated list of days, week or weekend",
+	})
+	return r
+}
+
+// registrySchema returns the schema of a list of automates
+// Each item is a single key object, the key being the automate's name in the registry (case insensitive).
+func registrySchema(r *config.SchemaReflector, automates map[string]reflect.Type) *config.Schema {
+	names := make([]string, 0, len(automates))
+	for name := range automates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	one := 1
+	item := &config.Schema{
+		Type:                 "object",
+		PatternProperties:    make(map[string]*config.Schema, len(automates)),
+		AdditionalProperties: false,
+		MinProperties:        &one,
+		MaxProperties:        &one,
+		Description:          "One of " + strings.Join(names, ", "),
+	}
+	for name, t := range automates {
+		item.PatternProperties["^"+config.CaseInsensitivePattern(name)+"$"] = r.Reflect(t)
+	}
+
+	return &config.Schema{
+		Type:  "array",
+		Items: item,
+	}
+}
@@ -0,0 +1,102 @@
+package smarthome
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/nmaupu/gotomation/model/config"
+)
+
+func TestConfigSchema(t *testing.T) {
+	schema := ConfigSchema()
+	modules := schema.Properties["modules"].Items.PatternProperties
+	trigs := schema.Properties["triggers"].Items.PatternProperties
+
+	tests := []struct {
+		name     string
+		patterns map[string]*config.Schema
+		key      string
+		property string
+	}{
+		{
+			name:     "module",
+			patterns: modules,
+			key:      "heaterChecker",
+			property: "schedules_file",
+		},
+		{
+			name:     "module_squashed_field",
+			patterns: modules,
+			key:      "internetchecker",
+			property: "interval",
+		},
+		{
+			name:     "trigger",
+			patterns: trigs,
+			key:      "Dehumidifier",
+			property: "threshold_max",
+		},
+		{
+			name:     "trigger_squashed_field",
+			patterns: trigs,
+			key:      "alert",
+			property: "trigger_entities",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found *config.Schema
+			for pattern, s := range tt.patterns {
+				if regexp.MustCompile(pattern).MatchString(tt.key) {
+					found = s
+				}
+			}
+			if found == nil {
+				t.Fatalf("no schema matching %s", tt.key)
+			}
+			if _, ok := found.Properties[tt.property]; !ok {
+				t.Errorf("%s has no property %s", tt.key, tt.property)
+			}
+		})
+	}
+
+	if got, want := len(modules), len(checkers); got != want {
+		t.Errorf("modules = %d, want %d", got, want)
+	}
+	if got, want := len(trigs), len(triggers); got != want {
+		t.Errorf("triggers = %d, want %d", got, want)
+	}
+}
+
+func TestHeaterSchedulesSchema(t *testing.T) {
+	days := regexp.MustCompile(HeaterSchedulesSchema().Properties["schedules"].PropertyNames.Pattern)
+
+	tests := []struct {
+		name string
+		days string
+		want bool
+	}{
+		{
+			name: "single_day",
+			days: "monday",
+			want: true,
+		},
+		{
+			name: "list",
+			days: "weekEnd, monday,tuesday",
+			want: true,
+		},
+		{
+			name: "unknown_day",
+			days: "week,someday",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := days.MatchString(tt.days); got != tt.want {
+				t.Errorf("match(%q) = %v, want %v", tt.days, got, tt.want)
+			}
+		})
+	}
+}
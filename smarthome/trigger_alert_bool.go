@@ -1,26 +1,29 @@
 package smarthome
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/smarthome/messaging"
+	"github.com/nmaupu/gotomation/templating"
 	"net/http"
-	"strings"
-	"text/template"
 )
 
 var (
-	_ core.Actionable = (*AlertTriggerBool)(nil)
+	_ core.Actionable  = (*AlertTriggerBool)(nil)
+	_ core.Validatable = (*AlertTriggerBool)(nil)
 )
 
 const (
 	DefaultAlertTriggerBoolTemplateString = "{{ .Event.EntityID }} has been changed to {{ .Event.NewState.State }}"
 )
 
+var (
+	defaultAlertTriggerBoolTemplate = templating.MustParse(DefaultAlertTriggerBoolTemplateString)
+)
+
 // AlertTriggerBool sends alert to a Sender when a specific boolean has its state changed
 type AlertTriggerBool struct {
 	core.Action `mapstructure:",squash"`
@@ -28,7 +31,7 @@ type AlertTriggerBool struct {
 	// Templates are the template to use to send notification message
 	Templates map[string]struct {
 		// MsgTemplate is used to format the message sent
-		MsgTemplate string `mapstructure:"msg_template"`
+		MsgTemplate templating.Template `mapstructure:"msg_template"`
 	} `mapstructure:"templates"`
 }
 
@@ -52,7 +55,7 @@ func (a *AlertTriggerBool) Trigger(event *model.HassEvent) {
 	l.Debug().Msg("Trigger receiver")
 
 	// Retrieve sender and send message with it
-	sender := GetSender(a.Sender)
+	sender := a.GetRuntime().GetSender(a.Sender)
 	if sender == nil {
 		l.Error().Msg("sender does not exist")
 		return
@@ -61,10 +64,10 @@ func (a *AlertTriggerBool) Trigger(event *model.HassEvent) {
 	entity := event.Event.Data.EntityID
 
 	// Getting template if set
-	tplString := DefaultAlertTriggerBoolTemplateString
+	tmpl := defaultAlertTriggerBoolTemplate
 	t, ok := a.Templates[entity]
-	if ok && t.MsgTemplate != "" {
-		tplString = t.MsgTemplate
+	if ok && !t.MsgTemplate.IsZero() {
+		tmpl = t.MsgTemplate
 	}
 
 	l.Debug().
@@ -75,38 +78,7 @@ func (a *AlertTriggerBool) Trigger(event *model.HassEvent) {
 		Any("new_attrs", event.Event.Data.NewState.Attributes).
 		Msg("Entity state")
 
-	tmpl, err := template.New("messageSender").
-		Funcs(template.FuncMap{
-			"IsStateChanged": func(d model.HassEventData) bool {
-				if d.NewState.IsUnknownOrUnavailable() {
-					// device status is unknown or unavailable, ignoring
-					return false
-				}
-				// New state is on or off but the old state is unknown or unavailable
-				if d.OldState.IsUnknownOrUnavailable() {
-					return d.NewState.IsON() // act like old state is 'off' and trigger msg
-				}
-
-				return !strings.EqualFold(d.OldState.State, d.NewState.State)
-			},
-			"IsWet": func(d model.HassEventData) bool {
-				return d.NewState.IsON()
-			},
-			"IsDry": func(d model.HassEventData) bool {
-				return d.NewState.IsOFF()
-			},
-		}).
-		Parse(tplString)
-	if err != nil {
-		l.Error().
-			Err(err).
-			Str("template", tplString).
-			Msg("an error occurred compiling template")
-		sender.Send(a.getErrorMessage(event, err), event)
-		return
-	}
-	buf := bytes.NewBufferString("")
-	err = tmpl.Execute(buf, struct {
+	msg, err := tmpl.Execute(a.GetRuntime(), struct {
 		Event model.HassEventData
 	}{
 		Event: event.Event.Data,
@@ -114,13 +86,12 @@ func (a *AlertTriggerBool) Trigger(event *model.HassEvent) {
 	if err != nil {
 		l.Error().
 			Err(err).
-			Str("template", tplString).
+			Stringer("template", tmpl).
 			Msg("an error occurred executing template")
 		sender.Send(a.getErrorMessage(event, err), event)
 		return
 	}
 
-	msg := strings.Trim(buf.String(), " ")
 	l.Debug().
 		Str("msg", msg).
 		Str("sender", a.Sender).
@@ -143,7 +114,16 @@ func (a *AlertTriggerBool) getErrorMessage(event *model.HassEvent, err error) me
 	}
 }
 
+// Validate checks that the sender exists, templates are compiled when decoded
+func (a *AlertTriggerBool) Validate() []error {
+	var errs []error
+	if a.GetRuntime().GetSender(a.Sender) == nil {
+		errs = append(errs, fmt.Errorf("sender: %q is not configured", a.Sender))
+	}
+	return errs
+}
+
 // GinHandler godoc
 func (a *AlertTriggerBool) GinHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, *a)
+	c.JSON(http.StatusOK, a)
 }
@@ -0,0 +1,112 @@
+package smarthome
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+// recordingSender records messages instead of sending them
+type recordingSender struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (s *recordingSender) Send(m messaging.Message, event *model.HassEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, m.Content)
+	return nil
+}
+
+func TestAlertTriggerBool_Trigger(t *testing.T) {
+	const leakTemplate = `{{ if IsStateChanged .Event }}{{ .Event.EntityID }} is {{ if IsWet .Event }}wet{{ else }}dry{{ end }}{{ end }}`
+
+	tests := []struct {
+		name     string
+		sender   string
+		template string
+		oldState string
+		newState string
+		want     []string
+	}{
+		{
+			name:     "default_template",
+			sender:   "test",
+			oldState: model.StateOFF,
+			newState: model.StateON,
+			want:     []string{"binary_sensor.leak has been changed to on"},
+		},
+		{
+			name:     "template_wet",
+			sender:   "test",
+			template: leakTemplate,
+			oldState: model.StateOFF,
+			newState: model.StateON,
+			want:     []string{"binary_sensor.leak is wet"},
+		},
+		{
+			name:     "template_dry",
+			sender:   "test",
+			template: leakTemplate,
+			oldState: model.StateON,
+			newState: model.StateOFF,
+			want:     []string{"binary_sensor.leak is dry"},
+		},
+		{
+			name:     "template_was_unavailable",
+			sender:   "test",
+			template: leakTemplate,
+			oldState: model.StateUnavailable,
+			newState: model.StateON,
+			want:     []string{"binary_sensor.leak is wet"},
+		},
+		{
+			name:     "template_state_unchanged",
+			sender:   "test",
+			template: leakTemplate,
+			oldState: model.StateON,
+			newState: model.StateON,
+		},
+		{
+			name:     "unknown_sender",
+			sender:   "unknown",
+			oldState: model.StateOFF,
+			newState: model.StateON,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := new(recordingSender)
+			srv := newFakeHass(t, model.HassState{EntityID: "binary_sensor.leak", State: tt.oldState})
+			runtime := newFakeHassRuntime(srv)
+			runtime.Senders = map[string]messaging.Sender{"test": sender}
+
+			a := new(AlertTriggerBool)
+			a.Bind(runtime)
+			data := map[string]any{
+				"trigger_entities": []string{"binary_sensor.leak"},
+				"sender":           tt.sender,
+			}
+			if tt.template != "" {
+				data["templates"] = map[string]any{
+					"binary_sensor.leak": map[string]any{"msg_template": tt.template},
+				}
+			}
+			configureAction(t, data, a)
+			triggered := listenFakeHass(t, srv, a)
+
+			srv.SetState(model.HassState{EntityID: "binary_sensor.leak", State: tt.newState})
+			waitTriggered(t, triggered)
+
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if !reflect.DeepEqual(sender.messages, tt.want) {
+				t.Errorf("messages = %q, want %q", sender.messages, tt.want)
+			}
+		})
+	}
+}
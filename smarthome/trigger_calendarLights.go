@@ -8,7 +8,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 )
@@ -50,16 +49,17 @@ func (c *CalendarLightsTrigger) Trigger(event *model.HassEvent) {
 	}
 
 	// Looking for real light entity
-	eventEntity := model.NewHassEntity(event.Event.Data.EntityID) // Should get calendar.light_xxx
+	eventEntity := event.GetEntity() // Should get calendar.light_xxx
 	lightEntity := model.NewHassEntity(strings.Replace(eventEntity.EntityID, "_", ".", 1))
-	entity, err := httpclient.GetSimpleClient().GetEntity(lightEntity.Domain, fmt.Sprintf("%s.*", lightEntity.EntityID))
+	lightEntity.Instance = eventEntity.Instance
+	entity, err := c.GetRuntime().Client(lightEntity).GetEntity(lightEntity.Domain, fmt.Sprintf("%s.*", lightEntity.EntityID))
 	if err != nil {
 		l.Error().Err(err).EmbedObject(lightEntity).Msg("Unable to get entity")
 		return
 	}
 
 	// Switching entity on or off
-	err = httpclient.GetSimpleClient().CallService(entity, service, extraParams)
+	err = c.GetRuntime().Client(entity).CallService(entity, service, extraParams)
 	if err != nil {
 		l.Error().Err(err).EmbedObject(entity).Msgf("Cannot call service %s on entity", service)
 	}
@@ -67,5 +67,5 @@ func (c *CalendarLightsTrigger) Trigger(event *model.HassEvent) {
 
 // GinHandler godoc
 func (c *CalendarLightsTrigger) GinHandler(ctx *gin.Context) {
-	ctx.JSON(http.StatusOK, *c)
+	ctx.JSON(http.StatusOK, c)
 }
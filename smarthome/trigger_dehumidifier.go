@@ -7,7 +7,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 )
@@ -58,7 +57,7 @@ func (d *DehumidifierTrigger) Trigger(event *model.HassEvent) {
 			EmbedObject(event).
 			Msg("Event received")
 
-		switchState, err := httpclient.GetSimpleClient().GetEntity(d.SwitchEntity.Domain, d.SwitchEntity.EntityID)
+		switchState, err := d.GetRuntime().Client(d.SwitchEntity).GetEntity(d.SwitchEntity.Domain, d.SwitchEntity.EntityID)
 		if err != nil {
 			l.Error().Err(err).
 				Str("device", d.SwitchEntity.GetEntityIDFullName()).
@@ -84,7 +83,7 @@ func (d *DehumidifierTrigger) Trigger(event *model.HassEvent) {
 					Float64("threshold_min", d.ThresholdMin).
 					Float64("threshold_max", d.ThresholdMax).
 					Msg("current >= threshold_max, switching on")
-				httpclient.GetSimpleClient().CallService(d.SwitchEntity, "turn_on", nil)
+				d.GetRuntime().Client(d.SwitchEntity).CallService(d.SwitchEntity, "turn_on", nil)
 			} else {
 				l.Debug().
 					Float64("current", currentHum).
@@ -100,7 +99,7 @@ func (d *DehumidifierTrigger) Trigger(event *model.HassEvent) {
 					Float64("threshold_min", d.ThresholdMin).
 					Float64("threshold_max", d.ThresholdMax).
 					Msg("current <= threshold_min, switching off")
-				httpclient.GetSimpleClient().CallService(d.SwitchEntity, "turn_off", nil)
+				d.GetRuntime().Client(d.SwitchEntity).CallService(d.SwitchEntity, "turn_off", nil)
 			} else {
 				l.Debug().
 					Float64("current", currentHum).
@@ -0,0 +1,163 @@
+package smarthome
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+)
+
+func TestDehumidifierTrigger_Trigger(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeEnd  string
+		switchOn bool
+		humidity string
+		want     []hasstest.ServiceCall
+	}{
+		{
+			name:     "humidity_high_switch_off",
+			timeEnd:  "23:59:59",
+			humidity: "65",
+			want: []hasstest.ServiceCall{
+				{Domain: "switch", Service: "turn_on", EntityID: []string{"switch.dehumidifier"}, Data: map[string]any{}},
+			},
+		},
+		{
+			name:     "humidity_low_switch_on",
+			timeEnd:  "23:59:59",
+			switchOn: true,
+			humidity: "45",
+			want: []hasstest.ServiceCall{
+				{Domain: "switch", Service: "turn_off", EntityID: []string{"switch.dehumidifier"}, Data: map[string]any{}},
+			},
+		},
+		{
+			name:     "humidity_high_switch_on",
+			timeEnd:  "23:59:59",
+			switchOn: true,
+			humidity: "65",
+		},
+		{
+			name:     "humidity_between_thresholds",
+			timeEnd:  "23:59:59",
+			humidity: "55",
+		},
+		{
+			name:     "out_of_time_range",
+			timeEnd:  "00:00:00",
+			humidity: "65",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switchState := model.StateOFF
+			if tt.switchOn {
+				switchState = model.StateON
+			}
+			srv := newFakeHass(t,
+				model.HassState{EntityID: "sensor.humidity", State: "50"},
+				model.HassState{EntityID: "switch.dehumidifier", State: switchState},
+			)
+
+			d := new(DehumidifierTrigger)
+			d.Bind(newFakeHassRuntime(srv))
+			configureAction(t, map[string]any{
+				"trigger_entities": []string{"sensor.humidity"},
+				"switch_entity":    "switch.dehumidifier",
+				"time_beg":         "00:00:00",
+				"time_end":         tt.timeEnd,
+				"threshold_min":    50,
+				"threshold_max":    60,
+			}, d)
+			triggered := listenFakeHass(t, srv, d)
+
+			srv.SetState(model.HassState{EntityID: "sensor.humidity", State: tt.humidity})
+			waitTriggered(t, triggered)
+
+			if got := srv.ServiceCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServiceCalls() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDehumidifierTrigger_Trigger_instances(t *testing.T) {
+	turnOn := hasstest.ServiceCall{Domain: "switch", Service: "turn_on", EntityID: []string{"switch.dehumidifier"}, Data: map[string]any{}}
+
+	tests := []struct {
+		name           string
+		sensorInstance string
+		switchInstance string
+		wantHouse      []hasstest.ServiceCall
+		wantGarage     []hasstest.ServiceCall
+	}{
+		{
+			name:           "sensor_on_garage_switch_on_house",
+			sensorInstance: "garage",
+			wantHouse:      []hasstest.ServiceCall{turnOn},
+		},
+		{
+			name:           "sensor_on_house_switch_on_garage",
+			switchInstance: "garage",
+			wantGarage:     []hasstest.ServiceCall{turnOn},
+		},
+		{
+			name:           "sensor_and_switch_on_garage",
+			sensorInstance: "garage",
+			switchInstance: "garage",
+			wantGarage:     []hasstest.ServiceCall{turnOn},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Both instances know both entities so that only instance resolution matters
+			states := []model.HassState{
+				{EntityID: "sensor.humidity", State: "50"},
+				{EntityID: "switch.dehumidifier", State: model.StateOFF},
+			}
+			house := newFakeHass(t, states...)
+			garage := newFakeHass(t, states...)
+			servers := map[string]*hasstest.Server{"": house, "garage": garage}
+
+			prefix := func(instance string) string {
+				if instance == "" {
+					return ""
+				}
+				return instance + model.InstanceSeparator
+			}
+
+			d := new(DehumidifierTrigger)
+			d.Bind(newFakeHassRuntime(house))
+			configureAction(t, map[string]any{
+				"trigger_entities": []string{prefix(tt.sensorInstance) + "sensor.humidity"},
+				"switch_entity":    prefix(tt.switchInstance) + "switch.dehumidifier",
+				"time_beg":         "00:00:00",
+				"time_end":         "23:59:59",
+				"threshold_min":    50,
+				"threshold_max":    60,
+			}, d)
+			triggered := listenFakeHassInstance(t, servers[tt.sensorInstance], tt.sensorInstance, d)
+			if tt.switchInstance != tt.sensorInstance {
+				listenFakeHassInstance(t, servers[tt.switchInstance], tt.switchInstance, d)
+			}
+
+			// The same entity changing on the other instance must not trigger anything
+			other := house
+			if tt.sensorInstance == "" {
+				other = garage
+			}
+			other.SetState(model.HassState{EntityID: "sensor.humidity", State: "65"})
+			servers[tt.sensorInstance].SetState(model.HassState{EntityID: "sensor.humidity", State: "65"})
+			waitTriggered(t, triggered)
+
+			if got := house.ServiceCalls(); !reflect.DeepEqual(got, tt.wantHouse) {
+				t.Errorf("house ServiceCalls() = %+v, want %+v", got, tt.wantHouse)
+			}
+			if got := garage.ServiceCalls(); !reflect.DeepEqual(got, tt.wantGarage) {
+				t.Errorf("garage ServiceCalls() = %+v, want %+v", got, tt.wantGarage)
+			}
+		})
+	}
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 )
@@ -15,11 +14,6 @@ var (
 	_ core.Actionable = (*HarmonyTrigger)(nil)
 )
 
-const (
-	offsetDawn = 15 * time.Minute
-	offsetDusk = -15 * time.Minute
-)
-
 // HarmonyTrigger checks for harmony remote button press and takes action accordingly
 type HarmonyTrigger struct {
 	core.Action `mapstructure:",squash"`
@@ -31,6 +25,8 @@ type workAction struct {
 	Key string `mapstructure:"key"`
 	// OnlyDark triggers this workAction only when it's dark outside
 	OnlyDark bool `mapstructure:"only_dark"`
+	// DarkElevation is the sun's elevation in degrees below which it's dark, core.DarkElevation if not set
+	DarkElevation *float64 `mapstructure:"dark_elevation"`
 	// Commands are all the commands being executed
 	Commands []command `mapstructure:"commands"`
 }
@@ -70,7 +66,7 @@ func (h *HarmonyTrigger) Trigger(event *model.HassEvent) {
 		return
 	}
 
-	if !wa.OnlyDark || (wa.OnlyDark && core.Coords().IsDarkNow(offsetDawn, offsetDusk)) {
+	if !wa.OnlyDark || h.GetRuntime().IsDark(wa.getDarkElevation()) {
 		for _, cmd := range wa.Commands {
 			cmdLogger := l.With().
 				Str("cmd_entity", cmd.Entity.GetEntityIDFullName()).
@@ -85,7 +81,7 @@ func (h *HarmonyTrigger) Trigger(event *model.HassEvent) {
 				if cmd.Brightness > 0 {
 					extra["brightness"] = cmd.Brightness
 				}
-				err := httpclient.GetSimpleClient().CallService(cmd.Entity, cmd.Service, extra)
+				err := h.GetRuntime().Client(cmd.Entity).CallService(cmd.Entity, cmd.Service, extra)
 				if err != nil {
 					cmdLogger.Error().Err(err).Msg("An error occurred calling service")
 				}
@@ -97,6 +93,14 @@ func (h *HarmonyTrigger) Trigger(event *model.HassEvent) {
 	}
 }
 
+// getDarkElevation returns the sun's elevation below which it's dark for this workAction
+func (wa workAction) getDarkElevation() float64 {
+	if wa.DarkElevation == nil {
+		return core.DarkElevation
+	}
+	return *wa.DarkElevation
+}
+
 func (h *HarmonyTrigger) getWorkAction(key string) *workAction {
 	for _, wa := range h.WorkActions {
 		if key == wa.Key {
@@ -109,5 +113,5 @@ func (h *HarmonyTrigger) getWorkAction(key string) *workAction {
 
 // GinHandler godoc
 func (h *HarmonyTrigger) GinHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, *h)
+	c.JSON(http.StatusOK, h)
 }
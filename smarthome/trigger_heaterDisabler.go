@@ -2,7 +2,6 @@ package smarthome
 
 import (
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 )
@@ -25,7 +24,7 @@ func (d *HeaterCheckersDisablerTrigger) Trigger(event *model.HassEvent) {
 func (d *HeaterCheckersDisablerTrigger) setAllCheckers(overrideState bool) {
 	l := logging.NewLogger("HeaterCheckersDisablerTrigger.setAllCheckers").With().Bool("overrideState", overrideState).Logger()
 
-	for _, checker := range GetCheckersByType(ModuleHeaterChecker) {
+	for _, checker := range d.GetRuntime().GetCheckers(ModuleHeaterChecker) {
 		m := checker.GetModular()
 		heaterChecker, ok := m.(*HeaterChecker)
 		if !ok {
@@ -40,7 +39,7 @@ func (d *HeaterCheckersDisablerTrigger) setAllCheckers(overrideState bool) {
 					Msg("Unable to get manual override entity")
 			} else {
 				// Turn off manual override for this heater
-				err := httpclient.GetSimpleClient().CallService(manualOverrideEntity, "turn_off", map[string]interface{}{})
+				err := d.GetRuntime().Client(manualOverrideEntity).CallService(manualOverrideEntity, "turn_off", map[string]interface{}{})
 				if err != nil {
 					l.Error().Err(err).
 						Object("entity", manualOverrideEntity).
@@ -63,7 +62,7 @@ func (d *HeaterCheckersDisablerTrigger) setAllCheckers(overrideState bool) {
 					Object("entity", climateEntity).
 					Msg("Unable to get climate entity")
 			} else {
-				err := httpclient.GetSimpleClient().CallService(climateEntity, "set_temperature", map[string]interface{}{
+				err := d.GetRuntime().Client(climateEntity).CallService(climateEntity, "set_temperature", map[string]interface{}{
 					"temperature": temp,
 				})
 				if err != nil {
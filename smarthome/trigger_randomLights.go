@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/nmaupu/gotomation/core"
-	"github.com/nmaupu/gotomation/httpclient"
 	"github.com/nmaupu/gotomation/logging"
 	"github.com/nmaupu/gotomation/model"
 	"github.com/nmaupu/gotomation/routines"
@@ -14,6 +13,7 @@ import (
 
 var (
 	_ core.Actionable = (*RandomLightsTrigger)(nil)
+	_ core.Releasable = (*RandomLightsTrigger)(nil)
 )
 
 const (
@@ -56,7 +56,7 @@ func (d *RandomLightsTrigger) Trigger(event *model.HassEvent) {
 	}
 
 	// Initialization depending on input_boolean status
-	triggerEntity, err := httpclient.GetSimpleClient().GetEntity(d.Entities[0].Domain, d.Entities[0].EntityID)
+	triggerEntity, err := d.GetRuntime().Client(d.Entities[0]).GetEntity(d.Entities[0].Domain, d.Entities[0].EntityID)
 	if err != nil {
 		l.Error().Err(err).EmbedObject(triggerEntity).Msg("unable to get entity's state")
 		return
@@ -84,9 +84,9 @@ func (d *RandomLightsTrigger) init() error {
 	var err error
 
 	if d.TimeBegin.IsZero() {
-		_, sunset, err := core.Coords().GetSunriseSunset()
+		_, sunset, err := d.GetRuntime().GetSunriseSunset()
 		if err != nil {
-			return err
+			return fmt.Errorf("time_begin is not specified and sunset is not known: %w", err)
 		}
 		d.TimeBegin = sunset.Add(offsetDefaultTimeBeginFromSunset)
 	}
@@ -108,6 +108,7 @@ func (d *RandomLightsTrigger) init() error {
 	}
 
 	d.randomLightsRoutine, err = core.NewRandomLightsRoutine(
+		d.GetRuntime(),
 		d.Name,
 		d.NbSlots,
 		d.Lights,
@@ -119,6 +120,15 @@ func (d *RandomLightsTrigger) init() error {
 	return err
 }
 
+// Release stops the randomLightsRoutine when this trigger is removed from the configuration
+func (d *RandomLightsTrigger) Release() {
+	if d.randomLightsRoutine == nil {
+		return
+	}
+	d.randomLightsRoutine.Stop()
+	routines.RemoveRunnable(d.randomLightsRoutine)
+}
+
 // GinHandler godoc
 func (d *RandomLightsTrigger) GinHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, d)
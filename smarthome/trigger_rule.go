@@ -0,0 +1,411 @@
+package smarthome
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nmaupu/gotomation/app"
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/logging"
+	"github.com/nmaupu/gotomation/model"
+)
+
+const (
+	// RuleModeQueued runs the actions in background, a new event waits for the running actions to be done
+	RuleModeQueued = "queued"
+	// RuleModeRestart cancels the running actions and runs them again when a new event triggers the rule
+	RuleModeRestart = "restart"
+)
+
+var (
+	_ core.Actionable  = (*RuleTrigger)(nil)
+	_ core.Validatable = (*RuleTrigger)(nil)
+	_ core.Releasable  = (*RuleTrigger)(nil)
+)
+
+// RuleTrigger runs actions when one of its when clauses matches an event and all its conditions are met
+type RuleTrigger struct {
+	core.Action `mapstructure:",squash"`
+	// When are the clauses triggering the rule, only one has to match
+	When []ruleWhen `mapstructure:"when"`
+	// Conditions have to be all met for the actions to run
+	Conditions []ruleCondition `mapstructure:"conditions"`
+	// Actions are run one after the other
+	Actions []core.Step `mapstructure:"actions"`
+	// Mode is RuleModeQueued (default) or RuleModeRestart
+	Mode string `mapstructure:"mode"`
+
+	// cancel is closed to stop the running actions when the rule is released
+	cancel   chan struct{}
+	released bool
+	// queue are the events waiting for the running actions to be done in queued mode
+	// serving is true while a go routine runs the actions of the queued events
+	queue   []model.HassEvent
+	serving bool
+	// stop is closed to cancel the actions running in restart mode, done is closed once they returned
+	stop        chan struct{}
+	done        chan struct{}
+	mutexCancel sync.Mutex
+}
+
+// ruleWhen matches an entity's state or attribute change or an event type
+type ruleWhen struct {
+	// Entity whose state (or attribute) changes
+	Entity model.HassEntity `mapstructure:"entity"`
+	// Attribute of Entity to watch instead of its state
+	Attribute string `mapstructure:"attribute"`
+	// EventType is a regexp pattern matching the event's type
+	EventType string `mapstructure:"event_type"`
+	// From is the value before the change
+	From string `mapstructure:"from"`
+	// To is the value after the change
+	To string `mapstructure:"to"`
+	// Above matches when the value goes above this threshold
+	Above *float64 `mapstructure:"above"`
+	// Below matches when the value goes below this threshold
+	Below *float64 `mapstructure:"below"`
+}
+
+// ruleCondition is met when all its fields are
+type ruleCondition struct {
+	// TimeBeg and TimeEnd is the time window when the condition is met, it can span over midnight
+	TimeBeg time.Time `mapstructure:"time_beg"`
+	TimeEnd time.Time `mapstructure:"time_end"`
+	// Dark is met when it's dark outside if true, when it's not if false
+	// It's dark when the sun is below core.DarkElevation.
+	Dark *bool `mapstructure:"dark"`
+	// SunElevationAbove and SunElevationBelow are the thresholds of the sun's elevation in degrees
+	SunElevationAbove *float64 `mapstructure:"sun_elevation_above"`
+	SunElevationBelow *float64 `mapstructure:"sun_elevation_below"`
+	// Days are the days of the week when the condition is met
+	Days core.SchedulesDays `mapstructure:"days"`
+	// Entity whose state (or attribute) is checked
+	Entity model.HassEntity `mapstructure:"entity"`
+	// Attribute of Entity to check instead of its state
+	Attribute string `mapstructure:"attribute"`
+	// State is the expected value of Entity
+	State string `mapstructure:"state"`
+	// Above and Below are the thresholds of Entity's value
+	Above *float64 `mapstructure:"above"`
+	Below *float64 `mapstructure:"below"`
+}
+
+// GetEntitiesForTrigger returns the trigger entities and the when clauses' ones
+func (r *RuleTrigger) GetEntitiesForTrigger() []model.HassEntity {
+	entities := append([]model.HassEntity(nil), r.Action.GetEntitiesForTrigger()...)
+	for _, when := range r.When {
+		if when.Entity.EntityID != "" {
+			entities = append(entities, when.Entity)
+		}
+	}
+	return entities
+}
+
+// GetEventTypesForTrigger returns the trigger events and the when clauses' ones
+func (r *RuleTrigger) GetEventTypesForTrigger() []string {
+	eventTypes := append([]string(nil), r.Action.GetEventTypesForTrigger()...)
+	for _, when := range r.When {
+		if when.EventType != "" {
+			eventTypes = append(eventTypes, when.EventType)
+		}
+	}
+	return eventTypes
+}
+
+// Trigger godoc
+func (r *RuleTrigger) Trigger(event *model.HassEvent) {
+	l := logging.NewLogger("RuleTrigger.Trigger").With().Str("name", r.Name).Logger()
+
+	if event == nil {
+		l.Warn().Msg("Event received is nil")
+		return
+	}
+
+	if !r.matches(event) {
+		l.Trace().EmbedObject(event).Msg("No when clause matches the event")
+		return
+	}
+	for i, condition := range r.Conditions {
+		if !condition.isMet(r.GetRuntime(), time.Now()) {
+			l.Debug().Int("condition", i).Msg("Condition is not met, doing nothing")
+			return
+		}
+	}
+
+	// Actions run in background so that their delays do not hold the dispatcher's worker
+	if r.Mode == RuleModeRestart {
+		r.restart(event)
+		return
+	}
+	r.enqueue(event)
+}
+
+// matches returns true if one of the when clauses matches event
+// A rule without when clause matches all the events it receives
+func (r *RuleTrigger) matches(event *model.HassEvent) bool {
+	if len(r.When) == 0 {
+		return true
+	}
+	for _, when := range r.When {
+		if when.matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs all the actions until they are done or cancel is closed
+func (r *RuleTrigger) run(event *model.HassEvent, cancel <-chan struct{}) {
+	l := logging.NewLogger("RuleTrigger.run").With().Str("name", r.Name).Logger()
+
+	if !core.RunSteps(r.GetRuntime(), r.Actions, event, cancel) {
+		l.Debug().Msg("Rule released, remaining actions are cancelled")
+	}
+}
+
+// restart cancels the actions still running and runs them again in background for event
+func (r *RuleTrigger) restart(event *model.HassEvent) {
+	l := logging.NewLogger("RuleTrigger.restart").With().Str("name", r.Name).Logger()
+
+	r.mutexCancel.Lock()
+	defer r.mutexCancel.Unlock()
+	if r.released {
+		l.Debug().Msg("Rule released, doing nothing")
+		return
+	}
+	if r.stop != nil {
+		l.Debug().Msg("Cancelling running actions")
+		close(r.stop)
+		r.stop = nil
+	}
+	if r.done != nil {
+		<-r.done
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	r.stop, r.done = stop, done
+	evt := *event // event is not ours once Trigger returns
+	l.Debug().EmbedObject(&evt).Msg("Running actions")
+	app.RoutinesWG.Add(1)
+	go func() {
+		defer app.RoutinesWG.Done()
+		defer close(done)
+		r.run(&evt, stop)
+	}()
+}
+
+// enqueue queues event for its actions to run once the previous events' ones are done
+func (r *RuleTrigger) enqueue(event *model.HassEvent) {
+	l := logging.NewLogger("RuleTrigger.enqueue").With().Str("name", r.Name).Logger()
+
+	r.mutexCancel.Lock()
+	defer r.mutexCancel.Unlock()
+	if r.released {
+		l.Debug().Msg("Rule released, doing nothing")
+		return
+	}
+	if len(r.queue) >= core.DefaultQueueDepth {
+		l.Warn().Int("queue_depth", core.DefaultQueueDepth).Msg("Too many events waiting for the actions, dropping event")
+		return
+	}
+
+	l.Debug().EmbedObject(event).Msg("Queuing actions")
+	r.queue = append(r.queue, *event) // event is not ours once Trigger returns
+	if r.serving {
+		return
+	}
+	r.serving = true
+	app.RoutinesWG.Add(1)
+	go func() {
+		defer app.RoutinesWG.Done()
+		r.serve()
+	}()
+}
+
+// serve runs the actions of the queued events one after the other until the queue is empty
+func (r *RuleTrigger) serve() {
+	for {
+		r.mutexCancel.Lock()
+		if r.released || len(r.queue) == 0 {
+			r.serving = false
+			r.mutexCancel.Unlock()
+			return
+		}
+		evt := r.queue[0]
+		r.queue = r.queue[1:]
+		if r.cancel == nil {
+			r.cancel = make(chan struct{})
+		}
+		cancel := r.cancel
+		r.mutexCancel.Unlock()
+
+		r.run(&evt, cancel)
+	}
+}
+
+// Release cancels the actions still running, the rule does nothing afterwards
+func (r *RuleTrigger) Release() {
+	r.mutexCancel.Lock()
+	defer r.mutexCancel.Unlock()
+	r.released = true
+	r.queue = nil
+	if r.cancel != nil {
+		close(r.cancel)
+		r.cancel = nil
+	}
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// Validate checks that when clauses, conditions and actions are usable
+func (r *RuleTrigger) Validate() []error {
+	var errs []error
+	if r.Mode != "" && r.Mode != RuleModeQueued && r.Mode != RuleModeRestart {
+		errs = append(errs, fmt.Errorf("mode: %q is not one of %s, %s", r.Mode, RuleModeQueued, RuleModeRestart))
+	}
+	for i, when := range r.When {
+		if when.Entity.EntityID == "" && when.EventType == "" {
+			errs = append(errs, fmt.Errorf("when[%d]: entity or event_type has to be set", i))
+		}
+		if when.EventType != "" && (when.From != "" || when.To != "" || when.Above != nil || when.Below != nil) && when.Entity.EntityID == "" {
+			errs = append(errs, fmt.Errorf("when[%d]: from, to, above and below require an entity", i))
+		}
+	}
+	for i, condition := range r.Conditions {
+		if condition.Entity.EntityID == "" && (condition.State != "" || condition.Attribute != "" || condition.Above != nil || condition.Below != nil) {
+			errs = append(errs, fmt.Errorf("conditions[%d]: state, attribute, above and below require an entity", i))
+		}
+	}
+	return append(errs, core.ValidateSteps(r.GetRuntime(), "actions", r.Actions)...)
+}
+
+// GinHandler godoc
+func (r *RuleTrigger) GinHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, r)
+}
+
+// matches returns true if event corresponds to this clause
+func (w ruleWhen) matches(event *model.HassEvent) bool {
+	if w.EventType != "" {
+		// Same as triggers' event types, matched anywhere in the event's type
+		re, err := model.CompilePattern(w.EventType)
+		if err != nil || !re.MatchString(event.Event.EventType) {
+			return false
+		}
+	}
+	if w.Entity.EntityID == "" {
+		return true
+	}
+	if !event.GetEntity().IsContained([]model.HassEntity{w.Entity}) {
+		return false
+	}
+
+	oldValue := stateValue(event.Event.Data.OldState, w.Attribute)
+	newValue := stateValue(event.Event.Data.NewState, w.Attribute)
+	if w.From != "" && !strings.EqualFold(oldValue, w.From) {
+		return false
+	}
+	if w.To != "" && !strings.EqualFold(newValue, w.To) {
+		return false
+	}
+	if w.Above != nil || w.Below != nil {
+		// Matching only when crossing a threshold
+		return inRange(newValue, w.Above, w.Below) && !inRange(oldValue, w.Above, w.Below)
+	}
+	if w.From == "" && w.To == "" {
+		return oldValue != newValue
+	}
+	return true
+}
+
+// isMet returns true if the condition is met at now
+func (c ruleCondition) isMet(runtime *core.Runtime, now time.Time) bool {
+	l := logging.NewLogger("ruleCondition.isMet")
+
+	if !c.TimeBeg.IsZero() || !c.TimeEnd.IsZero() {
+		if !inTimeWindow(now, c.TimeBeg, c.TimeEnd) {
+			return false
+		}
+	}
+	if c.Dark != nil {
+		if runtime.Coordinates == nil || runtime.IsDark(core.DarkElevation) != *c.Dark {
+			return false
+		}
+	}
+	if c.SunElevationAbove != nil || c.SunElevationBelow != nil {
+		elevation, _, err := runtime.GetSunPosition(now)
+		if err != nil {
+			l.Error().Err(err).Msg("Unable to get sun's elevation, condition is not met")
+			return false
+		}
+		if (c.SunElevationAbove != nil && elevation <= *c.SunElevationAbove) ||
+			(c.SunElevationBelow != nil && elevation >= *c.SunElevationBelow) {
+			return false
+		}
+	}
+	if c.Days != "" && !c.Days.IsScheduled(now) {
+		return false
+	}
+	if c.Entity.EntityID == "" {
+		return true
+	}
+
+	entity, err := runtime.Client(c.Entity).GetEntity(c.Entity.Domain, c.Entity.EntityID)
+	if err != nil {
+		l.Error().Err(err).Object("entity", c.Entity).Msg("Unable to get entity, condition is not met")
+		return false
+	}
+	value := stateValue(entity.State, c.Attribute)
+	if c.State != "" && !strings.EqualFold(value, c.State) {
+		return false
+	}
+	if (c.Above != nil || c.Below != nil) && !inRange(value, c.Above, c.Below) {
+		return false
+	}
+	return true
+}
+
+// stateValue returns the state of s, or its attribute if attribute is set
+func stateValue(s model.HassState, attribute string) string {
+	if attribute == "" {
+		return s.State
+	}
+	value, ok := s.Attributes[attribute]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// inRange returns true if value is a number strictly above above and strictly below below
+// A nil threshold is not checked
+func inRange(value string, above, below *float64) bool {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	return (above == nil || v > *above) && (below == nil || v < *below)
+}
+
+// inTimeWindow returns true if now's time of day is between beg and end
+// The window spans over midnight if end is before beg, a zero beg or end is midnight
+func inTimeWindow(now, beg, end time.Time) bool {
+	secondsOfDay := func(t time.Time) int {
+		return t.Hour()*3600 + t.Minute()*60 + t.Second()
+	}
+	n, b, e := secondsOfDay(now), secondsOfDay(beg), secondsOfDay(end)
+	if end.IsZero() {
+		e = 24 * 3600
+	}
+	if b <= e {
+		return n >= b && n < e
+	}
+	return n >= b || n < e
+}
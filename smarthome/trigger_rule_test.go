@@ -0,0 +1,305 @@
+package smarthome
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/httpclient/hasstest"
+	"github.com/nmaupu/gotomation/model"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+func TestRuleTrigger_Trigger(t *testing.T) {
+	turnOn := hasstest.ServiceCall{Domain: "light", Service: "turn_on", EntityID: []string{"light.hallway"}, Data: map[string]any{"brightness": float64(50)}}
+
+	tests := []struct {
+		name         string
+		when         map[string]any
+		conditions   []any
+		initialState string
+		newState     string
+		away         string
+		coordinates  bool
+		want         []hasstest.ServiceCall
+		wantMessages []string
+	}{
+		{
+			name:         "from_to_matches",
+			when:         map[string]any{"entity": "binary_sensor.motion", "from": "off", "to": "on"},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+			want:         []hasstest.ServiceCall{turnOn},
+			wantMessages: []string{"binary_sensor.motion is on"},
+		},
+		{
+			name:         "to_does_not_match",
+			when:         map[string]any{"entity": "binary_sensor.motion", "to": "on"},
+			initialState: model.StateON,
+			newState:     model.StateOFF,
+		},
+		{
+			name:         "above_crossed",
+			when:         map[string]any{"entity": "binary_sensor.motion", "above": 20},
+			initialState: "19.5",
+			newState:     "21",
+			want:         []hasstest.ServiceCall{turnOn},
+			wantMessages: []string{"binary_sensor.motion is 21"},
+		},
+		{
+			name:         "above_already_above",
+			when:         map[string]any{"entity": "binary_sensor.motion", "above": 20},
+			initialState: "21",
+			newState:     "22",
+		},
+		{
+			name:         "entity_condition_met",
+			when:         map[string]any{"entity": "binary_sensor.motion"},
+			conditions:   []any{map[string]any{"entity": "input_boolean.away", "state": "off"}, map[string]any{"days": "week,weekend"}},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+			away:         model.StateOFF,
+			want:         []hasstest.ServiceCall{turnOn},
+			wantMessages: []string{"binary_sensor.motion is on"},
+		},
+		{
+			name:         "entity_condition_not_met",
+			when:         map[string]any{"entity": "binary_sensor.motion"},
+			conditions:   []any{map[string]any{"entity": "input_boolean.away", "state": "off"}},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+			away:         model.StateON,
+		},
+		{
+			name:         "time_window_not_met",
+			when:         map[string]any{"entity": "binary_sensor.motion"},
+			conditions:   []any{map[string]any{"time_beg": "00:00:00", "time_end": "00:00:00"}},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+		},
+		{
+			name:         "sun_elevation_met",
+			when:         map[string]any{"entity": "binary_sensor.motion"},
+			conditions:   []any{map[string]any{"sun_elevation_above": -91, "sun_elevation_below": 91}},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+			coordinates:  true,
+			want:         []hasstest.ServiceCall{turnOn},
+			wantMessages: []string{"binary_sensor.motion is on"},
+		},
+		{
+			name:         "sun_elevation_without_coordinates",
+			when:         map[string]any{"entity": "binary_sensor.motion"},
+			conditions:   []any{map[string]any{"sun_elevation_below": 91}},
+			initialState: model.StateOFF,
+			newState:     model.StateON,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeHass(t,
+				model.HassState{EntityID: "binary_sensor.motion", State: tt.initialState},
+				model.HassState{EntityID: "input_boolean.away", State: tt.away},
+			)
+
+			sender := &recordingSender{}
+			runtime := newFakeHassRuntime(srv)
+			runtime.Senders = map[string]messaging.Sender{"test": sender}
+			if tt.coordinates {
+				runtime.Coordinates = core.NewCoordinates(48.8566, 2.3522)
+			}
+			r := new(RuleTrigger)
+			r.Bind(runtime)
+			t.Cleanup(r.Release)
+			configureAction(t, map[string]any{
+				"when":       []any{tt.when},
+				"conditions": tt.conditions,
+				"actions": []any{
+					map[string]any{"sender": "test", "message": "{{ .Event.EntityID }} is {{ .Event.NewState.State }}"},
+					map[string]any{"delay": "10ms"},
+					map[string]any{"entity": "light.hallway", "service": "turn_on", "data": map[string]any{"brightness": 50}},
+				},
+			}, r)
+			triggered := listenFakeHass(t, srv, r)
+
+			srv.SetState(model.HassState{EntityID: "binary_sensor.motion", State: tt.newState})
+			waitTriggered(t, triggered)
+			waitActionsDone(t, r)
+
+			if got := srv.ServiceCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServiceCalls() = %+v, want %+v", got, tt.want)
+			}
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if !reflect.DeepEqual(sender.messages, tt.wantMessages) {
+				t.Errorf("messages = %v, want %v", sender.messages, tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestRuleTrigger_Trigger_mode(t *testing.T) {
+	turnOn := hasstest.ServiceCall{Domain: "light", Service: "turn_on", EntityID: []string{"light.hallway"}, Data: map[string]any{}}
+
+	tests := []struct {
+		name         string
+		mode         string
+		want         []hasstest.ServiceCall
+		wantMessages []string
+	}{
+		{
+			name:         "queued",
+			mode:         RuleModeQueued,
+			want:         []hasstest.ServiceCall{turnOn, turnOn},
+			wantMessages: []string{"on", "off"},
+		},
+		{
+			name:         "default_is_queued",
+			want:         []hasstest.ServiceCall{turnOn, turnOn},
+			wantMessages: []string{"on", "off"},
+		},
+		{
+			name:         "restart",
+			mode:         RuleModeRestart,
+			want:         []hasstest.ServiceCall{turnOn},
+			wantMessages: []string{"on", "off"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeHass(t, model.HassState{EntityID: "binary_sensor.motion", State: model.StateOFF})
+
+			sender := &recordingSender{}
+			runtime := newFakeHassRuntime(srv)
+			runtime.Senders = map[string]messaging.Sender{"test": sender}
+			r := new(RuleTrigger)
+			r.Bind(runtime)
+			t.Cleanup(r.Release)
+			configureAction(t, map[string]any{
+				"mode": tt.mode,
+				"when": []any{map[string]any{"entity": "binary_sensor.motion"}},
+				"actions": []any{
+					map[string]any{"sender": "test", "message": "{{ .Event.NewState.State }}"},
+					map[string]any{"delay": "200ms"},
+					map[string]any{"entity": "light.hallway", "service": "turn_on"},
+				},
+			}, r)
+			triggered := listenFakeHass(t, srv, r)
+
+			srv.SetState(model.HassState{EntityID: "binary_sensor.motion", State: model.StateON})
+			waitTriggered(t, triggered)
+			srv.SetState(model.HassState{EntityID: "binary_sensor.motion", State: model.StateOFF})
+			waitTriggered(t, triggered)
+
+			waitActionsDone(t, r)
+
+			if got := srv.ServiceCalls(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ServiceCalls() = %+v, want %+v", got, tt.want)
+			}
+			sender.mutex.Lock()
+			defer sender.mutex.Unlock()
+			if !reflect.DeepEqual(sender.messages, tt.wantMessages) {
+				t.Errorf("messages = %v, want %v", sender.messages, tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestRuleTrigger_Release(t *testing.T) {
+	for _, mode := range []string{RuleModeQueued, RuleModeRestart} {
+		t.Run(mode, func(t *testing.T) {
+			srv := newFakeHass(t)
+			r := new(RuleTrigger)
+			r.Bind(newFakeHassRuntime(srv))
+			configureAction(t, map[string]any{
+				"mode": mode,
+				"actions": []any{
+					map[string]any{"delay": "1h"},
+					map[string]any{"entity": "light.hallway", "service": "turn_on"},
+				},
+			}, r)
+
+			r.Trigger(&model.HassEvent{})
+			r.Trigger(&model.HassEvent{})
+			time.Sleep(50 * time.Millisecond)
+			r.Release()
+			waitActionsDone(t, r)
+
+			// Triggering a released rule does nothing
+			r.Trigger(&model.HassEvent{})
+			time.Sleep(50 * time.Millisecond)
+			if got := srv.ServiceCalls(); len(got) > 0 {
+				t.Errorf("ServiceCalls() = %+v, want none", got)
+			}
+		})
+	}
+}
+
+func TestRuleTrigger_delayDoesNotBlockDispatch(t *testing.T) {
+	srv := newFakeHass(t)
+	sender := &recordingSender{}
+	runtime := newFakeHassRuntime(srv)
+	runtime.Senders = map[string]messaging.Sender{"test": sender}
+
+	newTrigger := func(actions []any) core.Triggerable {
+		r := new(RuleTrigger)
+		r.Bind(runtime)
+		t.Cleanup(r.Release)
+		trigger := new(core.Trigger)
+		if err := trigger.Configure(map[string]any{"actions": actions}, r); err != nil {
+			t.Fatalf("unable to configure action, err=%v", err)
+		}
+		return trigger
+	}
+	delayed := newTrigger([]any{map[string]any{"delay": "1h"}})
+	other := newTrigger([]any{map[string]any{"sender": "test", "message": "triggered"}})
+
+	// A single worker, busy with the delay if the rule's actions were run by it
+	pool := core.NewWorkerPool(1, 10)
+	pool.Start()
+	t.Cleanup(pool.Stop)
+	pool.Enqueue(delayed, &model.HassEvent{})
+	pool.Enqueue(other, &model.HassEvent{})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sender.mutex.Lock()
+		got := len(sender.messages)
+		sender.mutex.Unlock()
+		if got > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("second trigger has not been triggered while the first one waits for its delay")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitActionsDone waits for the actions of r running in background to be done
+func waitActionsDone(t *testing.T, r *RuleTrigger) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.mutexCancel.Lock()
+		serving, done := r.serving, r.done
+		r.mutexCancel.Unlock()
+
+		if !serving {
+			if done == nil {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Until(deadline)):
+				t.Fatalf("actions are still running")
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("actions are still running")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,144 @@
+package smarthome
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/nmaupu/gotomation/core"
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+// ValidationError is an error found in the configuration
+type ValidationError struct {
+	// Path locates the faulty configuration entry, such as modules[2].heaterChecker
+	Path string
+	Err  error
+}
+
+// Error godoc
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Validate checks a configuration without running anything
+// Modules, triggers and crons are decoded strictly: unknown keys are errors.
+// It returns all the errors found, none if the configuration is valid.
+func Validate(gotoConfig config.Gotomation) []error {
+	errs := make([]error, 0)
+	if err := gotoConfig.Validate(); err != nil {
+		errs = append(errs, ValidationError{Path: "home_assistant", Err: err})
+	}
+	if err := gotoConfig.Location.Validate(); err != nil {
+		errs = append(errs, ValidationError{Path: "location", Err: err})
+	}
+
+	senders := make(map[string]messaging.Sender, len(gotoConfig.Senders))
+	for i, senderConfig := range gotoConfig.Senders {
+		sender, err := senderConfig.GetSender(nil)
+		if err != nil {
+			errs = append(errs, ValidationError{Path: fmt.Sprintf("senders[%d]", i), Err: err})
+			continue
+		}
+		senders[senderConfig.Name] = sender
+	}
+	runtime := &core.Runtime{Senders: senders}
+
+	for i, module := range gotoConfig.Modules {
+		for moduleName, moduleConfig := range module {
+			path := fmt.Sprintf("modules[%d].%s", i, moduleName)
+			fmod, ok := checkers[moduleName]
+			if !ok {
+				errs = append(errs, ValidationError{Path: path, Err: fmt.Errorf("unknown module")})
+				continue
+			}
+			errs = append(errs, validateAutomate(path, moduleConfig, fmod(), runtime)...)
+		}
+	}
+
+	for i, trigger := range gotoConfig.Triggers {
+		for tn, triggerConfig := range trigger {
+			path := fmt.Sprintf("triggers[%d].%s", i, tn)
+			ftrig, ok := triggers[strings.ToLower(tn)]
+			if !ok {
+				errs = append(errs, ValidationError{Path: path, Err: fmt.Errorf("unknown trigger")})
+				continue
+			}
+			errs = append(errs, validateAutomate(path, triggerConfig, ftrig(), runtime)...)
+		}
+	}
+
+	for i, cronConfig := range gotoConfig.Crons {
+		path := fmt.Sprintf("crons[%d]", i)
+		ce := new(core.CronEntry)
+		if err := config.NewStrictMapstructureDecoder(ce).Decode(cronConfig); err != nil {
+			errs = append(errs, decodeErrors(path, err)...)
+			continue
+		}
+		if _, err := core.ParseSchedule(ce.Expr, nil); err != nil {
+			errs = append(errs, ValidationError{Path: path + ".expr", Err: err})
+		}
+		for _, err := range ce.Validate(runtime) {
+			errs = append(errs, ValidationError{Path: path, Err: err})
+		}
+	}
+
+	return errs
+}
+
+// validateAutomate strictly decodes data into automate and checks it if it is a core.Validatable
+func validateAutomate(path string, data any, automate core.Automate, runtime *core.Runtime) []error {
+	automate.Bind(runtime)
+	if err := config.NewStrictMapstructureDecoder(automate).Decode(data); err != nil {
+		return decodeErrors(path, err)
+	}
+
+	errs := make([]error, 0)
+	if actionable, ok := automate.(core.Actionable); ok {
+		if err := actionable.GetDispatchOptions().Validate(); err != nil {
+			errs = append(errs, ValidationError{Path: path, Err: err})
+		}
+	}
+
+	validatable, ok := automate.(core.Validatable)
+	if !ok {
+		return errs
+	}
+	for _, err := range validatable.Validate() {
+		errs = append(errs, ValidationError{Path: path, Err: err})
+	}
+	return errs
+}
+
+// decodeErrors splits a mapstructure error into one ValidationError per faulty key
+// mapstructure reports errors as "'key.sub_key' message" or as "error decoding 'key.sub_key': message"
+// when a decode hook fails, key being relative to path
+func decodeErrors(path string, err error) []error {
+	merr, ok := err.(*mapstructure.Error)
+	if !ok {
+		return []error{ValidationError{Path: path, Err: err}}
+	}
+
+	errs := make([]error, 0, len(merr.Errors))
+	for _, msg := range merr.Errors {
+		errPath := path
+		sep := "' "
+		if strings.HasPrefix(msg, "error decoding '") {
+			msg = strings.TrimPrefix(msg, "error decoding ")
+			sep = "': "
+		}
+		if strings.HasPrefix(msg, "'") {
+			if key, rest, found := strings.Cut(msg[1:], sep); found {
+				if key != "" {
+					errPath = path + "." + key
+				}
+				msg = rest
+			}
+		}
+		errs = append(errs, ValidationError{Path: errPath, Err: errors.New(msg)})
+	}
+	return errs
+}
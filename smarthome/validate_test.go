@@ -0,0 +1,164 @@
+package smarthome
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nmaupu/gotomation/model/config"
+	"github.com/nmaupu/gotomation/smarthome/messaging"
+)
+
+func TestValidate(t *testing.T) {
+	senders := []config.SenderConfig{
+		{Name: "telegram", Telegram: &messaging.TelegramSender{Token: "token", ChatID: 1}},
+	}
+	latitude := 48.8566
+
+	tests := []struct {
+		name   string
+		config config.Gotomation
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: config.Gotomation{
+				Senders: senders,
+				Modules: []map[string]any{
+					{ModuleInternetChecker: map[string]any{"name": "internet", "interval": "1m"}},
+				},
+				Triggers: []map[string]any{
+					{"Alert": map[string]any{"trigger_entities": []any{"binary_sensor.leak"}, "sender": "telegram"}},
+				},
+				Crons: []any{
+					map[string]any{"expr": "0 1 * * *", "action": "turn_off", "entities": []any{"light.living"}},
+				},
+			},
+			want: []string{},
+		},
+		{
+			name: "unknown_names",
+			config: config.Gotomation{
+				Modules:  []map[string]any{{"internetChecker2": map[string]any{}}},
+				Triggers: []map[string]any{{"alerts": map[string]any{}}},
+			},
+			want: []string{
+				"modules[0].internetChecker2: unknown module",
+				"triggers[0].alerts: unknown trigger",
+			},
+		},
+		{
+			name: "unknown_keys",
+			config: config.Gotomation{
+				Triggers: []map[string]any{
+					{TriggerHarmony: map[string]any{
+						"enabled":      true,
+						"work_actions": []any{map[string]any{"commands": []any{map[string]any{"only_dark": true}}}},
+					}},
+				},
+			},
+			want: []string{
+				"triggers[0].harmony.work_actions[0].commands[0]: has invalid keys: only_dark",
+				"triggers[0].harmony: has invalid keys: enabled",
+			},
+		},
+		{
+			name: "sender_and_template",
+			config: config.Gotomation{
+				Senders: senders,
+				Modules: []map[string]any{
+					{ModuleFreshness: map[string]any{"sender": "telegram", "template": "{{ .Foo "}},
+				},
+				Triggers: []map[string]any{
+					{TriggerAlertBool: map[string]any{"sender": "slack"}},
+				},
+			},
+			want: []string{
+				`modules[0].freshnesschecker.template: template: template:1: unclosed action`,
+				`triggers[0].alert: sender: "slack" is not configured`,
+			},
+		},
+		{
+			name: "cron",
+			config: config.Gotomation{
+				Crons: []any{
+					map[string]any{"expr": "0 25 * * *", "action": "turn_off", "entities": []any{"light.living"}},
+					map[string]any{"expr": "0 1 * * *", "actions": "turn_off"},
+				},
+			},
+			want: []string{
+				"crons[0].expr: end of range (25) above maximum (23): 25",
+				"crons[1]: has invalid keys: actions",
+			},
+		},
+		{
+			name: "cron_steps",
+			config: config.Gotomation{
+				Crons: []any{
+					map[string]any{"expr": "0 1 * * *", "action": "turn_off"},
+					map[string]any{"expr": "0 1 * * *", "steps": []any{
+						map[string]any{"service": "turn_on", "data": map[string]any{"brightness": 50}},
+						map[string]any{"disable": []any{"heaterchecker"}, "sender": "telegram", "message": "Heaters disabled"},
+						map[string]any{},
+					}},
+				},
+			},
+			want: []string{
+				"crons[0]: entities are not set",
+				"crons[1]: steps[0]: entity is not set",
+				`crons[1]: steps[1].sender: "telegram" is not configured`,
+				"crons[1]: steps[2]: service, enable, disable, sender or delay has to be set",
+			},
+		},
+		{
+			name: "dispatch_options",
+			config: config.Gotomation{
+				Triggers: []map[string]any{
+					{TriggerDehumidifier: map[string]any{"debounce": "10s", "for": "5m"}},
+				},
+			},
+			want: []string{
+				"triggers[0].dehumidifier: debounce and for cannot be used together",
+			},
+		},
+		{
+			name: "heater_preheat",
+			config: config.Gotomation{
+				Modules: []map[string]any{
+					{ModuleHeaterChecker: map[string]any{"preheat": map[string]any{"enabled": true, "max_lead": "-1h"}}},
+				},
+			},
+			want: []string{
+				"modules[0].heaterchecker: preheat: max_lead and default_rate cannot be negative",
+			},
+		},
+		{
+			name: "location",
+			config: config.Gotomation{
+				Location: config.LocationConfig{Latitude: &latitude},
+			},
+			want: []string{
+				"location: latitude and longitude have to be set together",
+			},
+		},
+		{
+			name: "sender",
+			config: config.Gotomation{
+				Senders: []config.SenderConfig{{Name: "nothing"}},
+			},
+			want: []string{
+				"senders[0]: no sender specified in configuration for nothing",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, err := range Validate(tt.config) {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package templating
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// toFloat converts a number or a string to a float64
+func toFloat(v any) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case time.Duration:
+		return n.Seconds(), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	case fmt.Stringer:
+		return strconv.ParseFloat(strings.TrimSpace(n.String()), 64)
+	}
+	return 0, fmt.Errorf("cannot convert %v (%T) to a number", v, v)
+}
+
+// toDuration converts a duration string such as 1h30m or a number of seconds to a time.Duration
+func toDuration(v any) (time.Duration, error) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, nil
+	case string:
+		if duration, err := time.ParseDuration(d); err == nil {
+			return duration, nil
+		}
+	}
+	seconds, err := toFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %v (%T) to a duration", v, v)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// round rounds f to precision decimals
+func round(f float64, precision int) float64 {
+	pow := math.Pow(10, float64(precision))
+	return math.Round(f*pow) / pow
+}
+
+// humanize returns a human readable duration, or how long ago (or in how long) a time is
+func humanize(v any) (string, error) {
+	if t, ok := v.(time.Time); ok {
+		d := time.Until(t)
+		if d < 0 {
+			return humanizeDuration(-d) + " ago", nil
+		}
+		return "in " + humanizeDuration(d), nil
+	}
+
+	d, err := toDuration(v)
+	if err != nil {
+		return "", err
+	}
+	return humanizeDuration(d), nil
+}
+
+// humanizeDuration returns d in its biggest unit, such as 3 hours
+func humanizeDuration(d time.Duration) string {
+	if d < 0 {
+		d = -d
+	}
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	for _, unit := range units {
+		if n := int(d / unit.duration); n > 0 {
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%d %ss", n, unit.name)
+		}
+	}
+	return "0 seconds"
+}
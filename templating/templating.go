@@ -0,0 +1,181 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nmaupu/gotomation/model"
+)
+
+// Env gives templates' functions access to Home Assistant
+type Env interface {
+	// GetEntity returns entity with its current state
+	GetEntity(entity model.HassEntity) (model.HassEntity, error)
+	// GetSunriseSunset returns today's sunrise and sunset
+	GetSunriseSunset() (time.Time, time.Time, error)
+	// GetSunPosition returns the sun's elevation and azimuth in degrees at t
+	GetSunPosition(t time.Time) (float64, float64, error)
+}
+
+// Template is a text template compiled with gotomation's functions
+// It is decoded from a string by StringToTemplateDecodeHookFunc so that it is compiled
+// once when the configuration is read.
+type Template struct {
+	text string
+	tmpl *template.Template
+}
+
+// Parse compiles text
+func Parse(text string) (Template, error) {
+	tmpl, err := template.New("template").Funcs(FuncMap(nil)).Parse(text)
+	if err != nil {
+		return Template{}, err
+	}
+	return Template{text: text, tmpl: tmpl}, nil
+}
+
+// MustParse is like Parse but panics if text does not compile
+func MustParse(text string) Template {
+	t, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// IsZero returns true if no template has been set
+func (t Template) IsZero() bool {
+	return t.tmpl == nil
+}
+
+// String returns the template's text
+func (t Template) String() string {
+	return t.text
+}
+
+// MarshalText returns the template's text
+func (t Template) MarshalText() ([]byte, error) {
+	return []byte(t.text), nil
+}
+
+// Execute applies the template to data and returns the result without surrounding spaces
+// env is used by the functions getting data from Home Assistant
+func (t Template) Execute(env Env, data any) (string, error) {
+	if t.IsZero() {
+		return "", nil
+	}
+
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBufferString("")
+	if err := tmpl.Funcs(FuncMap(env)).Execute(buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// StringToTemplateDecodeHookFunc returns a mapstructure decode hook func compiling strings to Template
+func StringToTemplateDecodeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(Template{}) {
+			return data, nil
+		}
+		return Parse(reflect.ValueOf(data).String())
+	}
+}
+
+// FuncMap returns the functions available to templates
+// Functions using env return an error if it is nil
+func FuncMap(env Env) template.FuncMap {
+	getEntity := func(entityID string) (model.HassEntity, error) {
+		if env == nil {
+			return model.HassEntity{}, fmt.Errorf("Home Assistant is not available")
+		}
+		return env.GetEntity(model.NewHassEntity(entityID))
+	}
+	sun := func() (time.Time, time.Time, error) {
+		if env == nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("coordinates are not available")
+		}
+		return env.GetSunriseSunset()
+	}
+	sunPosition := func() (float64, float64, error) {
+		if env == nil {
+			return 0, 0, fmt.Errorf("coordinates are not available")
+		}
+		return env.GetSunPosition(time.Now())
+	}
+
+	return template.FuncMap{
+		"JoinEntities":   model.JoinEntities,
+		"IsStateChanged": isStateChanged,
+		"IsWet": func(d model.HassEventData) bool {
+			return d.NewState.IsON()
+		},
+		"IsDry": func(d model.HassEventData) bool {
+			return d.NewState.IsOFF()
+		},
+		"states": func(entityID string) (string, error) {
+			entity, err := getEntity(entityID)
+			return entity.State.State, err
+		},
+		"attr": func(entityID, name string) (any, error) {
+			entity, err := getEntity(entityID)
+			return entity.State.Attributes[name], err
+		},
+		"now": time.Now,
+		"sunrise": func() (time.Time, error) {
+			sunrise, _, err := sun()
+			return sunrise, err
+		},
+		"sunset": func() (time.Time, error) {
+			_, sunset, err := sun()
+			return sunset, err
+		},
+		"sun_elevation": func() (float64, error) {
+			elevation, _, err := sunPosition()
+			return elevation, err
+		},
+		"sun_azimuth": func() (float64, error) {
+			_, azimuth, err := sunPosition()
+			return azimuth, err
+		},
+		"duration": toDuration,
+		"humanize": humanize,
+		"round": func(precision int, v any) (float64, error) {
+			f, err := toFloat(v)
+			if err != nil {
+				return 0, err
+			}
+			return round(f, precision), nil
+		},
+		"float": toFloat,
+		"default": func(def, v any) any {
+			if v == nil || reflect.ValueOf(v).IsZero() {
+				return def
+			}
+			return v
+		},
+	}
+}
+
+// isStateChanged returns true if an event changes an entity from a known state to another
+func isStateChanged(d model.HassEventData) bool {
+	if d.NewState.IsUnknownOrUnavailable() {
+		// device status is unknown or unavailable, ignoring
+		return false
+	}
+	// New state is on or off but the old state is unknown or unavailable
+	if d.OldState.IsUnknownOrUnavailable() {
+		return d.NewState.IsON() // act like old state is 'off' and trigger msg
+	}
+
+	return !strings.EqualFold(d.OldState.State, d.NewState.State)
+}
@@ -0,0 +1,116 @@
+package templating
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nmaupu/gotomation/model"
+)
+
+// fakeEnv serves states without Home Assistant
+type fakeEnv map[string]model.HassState
+
+func (e fakeEnv) GetEntity(entity model.HassEntity) (model.HassEntity, error) {
+	state, ok := e[entity.GetEntityIDFullName()]
+	if !ok {
+		return model.HassEntity{}, fmt.Errorf("unknown entity %s", entity.GetEntityIDFullName())
+	}
+	entity.State = state
+	return entity, nil
+}
+
+func (e fakeEnv) GetSunriseSunset() (time.Time, time.Time, error) {
+	return time.Date(2024, 6, 21, 5, 45, 0, 0, time.UTC), time.Date(2024, 6, 21, 21, 58, 0, 0, time.UTC), nil
+}
+
+func (e fakeEnv) GetSunPosition(t time.Time) (float64, float64, error) {
+	return -4.5, 271.25, nil
+}
+
+func TestTemplate_Execute(t *testing.T) {
+	env := fakeEnv{
+		"sensor.temperature": {State: "21.456"},
+		"light.living":       {State: model.StateON, Attributes: map[string]any{"brightness": float64(128)}},
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		env     Env
+		data    any
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "states_round",
+			text: `{{ states "sensor.temperature" | round 1 }}°C`,
+			env:  env,
+			want: "21.5°C",
+		},
+		{
+			name: "attr_float",
+			text: `{{ attr "light.living" "brightness" | float }}`,
+			env:  env,
+			want: "128",
+		},
+		{
+			name: "sun",
+			text: `{{ sunrise.Format "15:04" }}-{{ sunset.Format "15:04" }}`,
+			env:  env,
+			want: "05:45-21:58",
+		},
+		{
+			name: "sun_position",
+			text: `{{ sun_elevation }} {{ sun_azimuth | round 0 }}`,
+			env:  env,
+			want: "-4.5 271",
+		},
+		{
+			name: "duration_humanize",
+			text: `{{ duration "90m" | humanize }} / {{ duration 45 | humanize }}`,
+			want: "1 hour / 45 seconds",
+		},
+		{
+			name: "default",
+			text: `{{ .Missing | default "n/a" }} {{ .Set | default "n/a" }}`,
+			data: map[string]any{"Set": "ok"},
+			want: "n/a ok",
+		},
+		{
+			name: "event_functions",
+			text: `{{ if IsStateChanged .Event }}{{ if IsWet .Event }}wet{{ end }}{{ end }}`,
+			data: map[string]any{"Event": model.HassEventData{
+				OldState: model.HassState{State: model.StateOFF},
+				NewState: model.HassState{State: model.StateON},
+			}},
+			want: "wet",
+		},
+		{
+			name:    "no_env",
+			text:    `{{ states "sensor.temperature" }}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown_entity",
+			text:    `{{ states "sensor.unknown" }}`,
+			env:     env,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := tmpl.Execute(tt.env, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}